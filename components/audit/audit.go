@@ -0,0 +1,185 @@
+// Package audit records who did what: one JSON line per request with the principal, the action, the target resources,
+// the outcome and the request ID, written to a dedicated append-only sink.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/rhosocial/go-rush-common/components/logger"
+	"github.com/rhosocial/go-rush-common/components/logger/sink"
+	"github.com/rhosocial/go-rush-common/components/response"
+)
+
+// ContextResources is the key under which the resources added by AddResource are stored.
+const ContextResources = "AuditResources"
+
+// Entry is one audit record.
+type Entry struct {
+	Time      time.Time         `json:"time"`
+	RequestID logger.RequestID  `json:"request_id"`
+	Principal any               `json:"principal,omitempty"`
+	ClientIP  string            `json:"client_ip"`
+	Method    string            `json:"method"`
+	Route     string            `json:"route"`
+	Action    string            `json:"action"`
+	Resources map[string]string `json:"resources,omitempty"`
+	Status    int               `json:"status"`
+	// Code is the code of the response envelope, absent if the handler did not build one.
+	Code *uint32 `json:"code,omitempty"`
+	// Errors are the errors attached to the context.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// EnvAudit defines the audit sinks and which requests are recorded.
+type EnvAudit struct {
+	Sinks sink.EnvSinks `yaml:"Sinks"`
+	// Methods are the recorded request methods. Empty means the methods that modify state: POST, PUT, PATCH and
+	// DELETE.
+	Methods []string `yaml:"Methods,omitempty" validate:"dive,oneof=GET HEAD POST PUT PATCH DELETE OPTIONS"`
+}
+
+func (e *EnvAudit) Validate() error {
+	validate := validator.New()
+	return validate.Struct(e)
+}
+
+// DefaultMethods are the methods recorded when Config.Methods is empty.
+var DefaultMethods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// Config defines the behavior of the audit middleware.
+type Config struct {
+	// Writer receives one JSON line per entry. Each entry is written with a single call.
+	Writer io.Writer
+	// Methods are the recorded request methods. Empty means DefaultMethods.
+	Methods []string
+	// Skip excludes requests from the audit log.
+	Skip func(c *gin.Context) bool
+	// ErrorLogger reports entries that could not be written. Nil means slog.Default().
+	ErrorLogger *slog.Logger
+}
+
+// New opens the configured sinks and returns the audit middleware, and the sink to close on shutdown.
+func New(e *EnvAudit) (gin.HandlerFunc, io.Closer, error) {
+	if err := e.Validate(); err != nil {
+		return nil, nil, err
+	}
+	w, err := sink.New(&e.Sinks)
+	if err != nil {
+		return nil, nil, err
+	}
+	return MiddlewareWithConfig(Config{Writer: w, Methods: e.Methods}), w, nil
+}
+
+// Middleware returns an audit middleware writing the requests of DefaultMethods to w.
+func Middleware(w io.Writer) gin.HandlerFunc {
+	return MiddlewareWithConfig(Config{Writer: w})
+}
+
+// MiddlewareWithConfig returns a middleware that writes an Entry once the request has been handled.
+//
+// It should be registered after logger.AppendRequestID and the authentication middleware, which is expected to store
+// the principal under logger.ContextPrincipal. The path parameters are recorded as resources, along with those added
+// by AddResource. A request whose handler panics is recorded with the status 500 and the panic among the errors, and
+// the panic goes on to the recovery middleware, which should be registered before this one.
+func MiddlewareWithConfig(config Config) gin.HandlerFunc {
+	methods := config.Methods
+	if len(methods) == 0 {
+		methods = DefaultMethods
+	}
+	recorded := make(map[string]struct{}, len(methods))
+	for _, method := range methods {
+		recorded[method] = struct{}{}
+	}
+	errorLogger := config.ErrorLogger
+	if errorLogger == nil {
+		errorLogger = slog.Default()
+	}
+	var mu sync.Mutex
+	write := func(c *gin.Context, e *Entry) {
+		line, err := json.Marshal(e)
+		if err != nil {
+			errorLogger.ErrorContext(c, "audit: failed to encode entry", "error", err)
+			return
+		}
+		line = append(line, '\n')
+		mu.Lock()
+		_, err = config.Writer.Write(line)
+		mu.Unlock()
+		if err != nil {
+			errorLogger.ErrorContext(c, "audit: failed to write entry", "error", err)
+		}
+	}
+	return func(c *gin.Context) {
+		if _, ok := recorded[c.Request.Method]; !ok || (config.Skip != nil && config.Skip(c)) {
+			c.Next()
+			return
+		}
+		defer func() {
+			if v := recover(); v != nil {
+				e := NewEntry(c)
+				e.Status = http.StatusInternalServerError
+				e.Errors = append(e.Errors, fmt.Sprintf("panic: %v", v))
+				write(c, e)
+				panic(v)
+			}
+		}()
+		c.Next()
+		write(c, NewEntry(c))
+	}
+}
+
+// NewEntry builds the entry of a handled request.
+func NewEntry(c *gin.Context) *Entry {
+	route := c.FullPath()
+	e := Entry{
+		Time:      time.Now(),
+		RequestID: logger.GetRequestID(c),
+		ClientIP:  c.ClientIP(),
+		Method:    c.Request.Method,
+		Route:     route,
+		Action:    c.Request.Method + " " + route,
+		Status:    c.Writer.Status(),
+	}
+	if principal, exists := c.Get(logger.ContextPrincipal); exists {
+		e.Principal = principal
+	}
+	if len(c.Params) > 0 {
+		e.Resources = make(map[string]string, len(c.Params))
+		for _, param := range c.Params {
+			e.Resources[param.Key] = param.Value
+		}
+	}
+	if resources, ok := c.Value(ContextResources).(map[string]string); ok {
+		if e.Resources == nil {
+			e.Resources = make(map[string]string, len(resources))
+		}
+		for k, v := range resources {
+			e.Resources[k] = v
+		}
+	}
+	if code, ok := response.GetCode(c); ok {
+		e.Code = &code
+	}
+	for _, err := range c.Errors {
+		e.Errors = append(e.Errors, err.Error())
+	}
+	return &e
+}
+
+// AddResource records a target resource that is not a path parameter, e.g. the ID of a created record.
+func AddResource(c *gin.Context, name string, id string) {
+	resources, ok := c.Value(ContextResources).(map[string]string)
+	if !ok {
+		resources = make(map[string]string)
+		c.Set(ContextResources, resources)
+	}
+	resources[name] = id
+}
@@ -0,0 +1,146 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rhosocial/go-rush-common/components/logger"
+	"github.com/rhosocial/go-rush-common/components/logger/sink"
+	"github.com/rhosocial/go-rush-common/components/response"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupRouterAudit(config Config) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(logger.AppendRequestID(), func(c *gin.Context) {
+		c.Set(logger.ContextPrincipal, "alice")
+	}, MiddlewareWithConfig(config))
+	r.GET("/users/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, response.NewBase(c, 0, "ok"))
+	})
+	r.DELETE("/users/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, response.NewBase(c, 0, "deleted"))
+	})
+	r.POST("/users", func(c *gin.Context) {
+		AddResource(c, "id", "42")
+		c.JSON(http.StatusConflict, response.NewBase(c, 1001, "duplicated"))
+	})
+	return r
+}
+
+func serve(r *gin.Engine, method string, path string) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, nil)
+	r.ServeHTTP(w, req)
+}
+
+func decodeEntries(t *testing.T, buffer *bytes.Buffer) []Entry {
+	entries := make([]Entry, 0)
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		e := Entry{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &e))
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Run("modifying methods", func(t *testing.T) {
+		buffer := bytes.Buffer{}
+		r := setupRouterAudit(Config{Writer: &buffer})
+		serve(r, http.MethodGet, "/users/1")
+		serve(r, http.MethodDelete, "/users/1")
+		serve(r, http.MethodPost, "/users")
+
+		entries := decodeEntries(t, &buffer)
+		assert.Len(t, entries, 2)
+
+		assert.NotEmpty(t, entries[0].RequestID)
+		assert.Equal(t, "alice", entries[0].Principal)
+		assert.Equal(t, "DELETE /users/:id", entries[0].Action)
+		assert.Equal(t, map[string]string{"id": "1"}, entries[0].Resources)
+		assert.Equal(t, http.StatusOK, entries[0].Status)
+		assert.Equal(t, uint32(0), *entries[0].Code)
+
+		assert.Equal(t, "POST /users", entries[1].Action)
+		assert.Equal(t, map[string]string{"id": "42"}, entries[1].Resources)
+		assert.Equal(t, http.StatusConflict, entries[1].Status)
+		assert.Equal(t, uint32(1001), *entries[1].Code)
+	})
+
+	t.Run("methods and skip", func(t *testing.T) {
+		buffer := bytes.Buffer{}
+		r := setupRouterAudit(Config{Writer: &buffer, Methods: []string{http.MethodGet}, Skip: func(c *gin.Context) bool {
+			return c.Param("id") == "2"
+		}})
+		serve(r, http.MethodGet, "/users/1")
+		serve(r, http.MethodGet, "/users/2")
+		serve(r, http.MethodDelete, "/users/1")
+
+		entries := decodeEntries(t, &buffer)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, "GET /users/:id", entries[0].Action)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		buffer := bytes.Buffer{}
+		r := gin.New()
+		r.Use(gin.RecoveryWithWriter(io.Discard), MiddlewareWithConfig(Config{Writer: &buffer}))
+		r.DELETE("/users/:id", func(c *gin.Context) {
+			_ = c.Error(errors.New("cascade failed"))
+			panic("boom")
+		})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodDelete, "/users/1", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+		entries := decodeEntries(t, &buffer)
+		if assert.Len(t, entries, 1) {
+			assert.Equal(t, "DELETE /users/:id", entries[0].Action)
+			assert.Equal(t, map[string]string{"id": "1"}, entries[0].Resources)
+			assert.Equal(t, http.StatusInternalServerError, entries[0].Status)
+			assert.Equal(t, []string{"cascade failed", "panic: boom"}, entries[0].Errors)
+		}
+	})
+}
+
+func TestNew(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audit.log")
+	e := EnvAudit{Sinks: sink.EnvSinks{Sinks: []sink.EnvSink{{Type: sink.TypeFile, File: &sink.EnvFile{Filename: filename}}}}}
+	middleware, closer, err := New(&e)
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware)
+	r.PUT("/users/:id", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	serve(r, http.MethodPut, "/users/1")
+	serve(r, http.MethodPut, "/users/2")
+	assert.NoError(t, closer.Close())
+
+	content, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	entries := decodeEntries(t, bytes.NewBuffer(content))
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "2", entries[1].Resources["id"])
+	assert.Nil(t, entries[1].Code)
+
+	_, _, err = New(&EnvAudit{Sinks: e.Sinks, Methods: []string{"TRACE"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,402 @@
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	EncodingBrotli  = "br"
+	EncodingZstd    = "zstd"
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+
+	HeaderAcceptEncoding  = "Accept-Encoding"
+	HeaderContentEncoding = "Content-Encoding"
+	HeaderContentLength   = "Content-Length"
+	HeaderContentType     = "Content-Type"
+	HeaderVary            = "Vary"
+)
+
+// DefaultMinLength is the body size, in bytes, below which responses are sent uncompressed.
+const DefaultMinLength = 1024
+
+// DefaultEncodings lists the supported encodings in server preference order.
+// It is used to break ties between encodings the client accepts with the same quality value.
+var DefaultEncodings = []string{EncodingBrotli, EncodingZstd, EncodingGzip, EncodingDeflate}
+
+// DefaultExcludedContentTypes lists content type prefixes that are already compressed.
+var DefaultExcludedContentTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"font/woff",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/zstd",
+	"application/x-brotli",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/pdf",
+	"application/octet-stream",
+}
+
+// Config defines the behavior of the compression middleware.
+type Config struct {
+	// MinLength is the minimum body size to compress. Zero means DefaultMinLength.
+	MinLength int
+	// Encodings lists the enabled encodings in server preference order. Empty means DefaultEncodings.
+	Encodings []string
+	// ExcludedContentTypes lists content type prefixes that are never compressed.
+	// Nil means DefaultExcludedContentTypes.
+	ExcludedContentTypes []string
+	// ExcludedPaths lists request path prefixes that are never compressed.
+	ExcludedPaths []string
+}
+
+// Compress returns a compression middleware with the default configuration.
+func Compress() gin.HandlerFunc {
+	return New(Config{})
+}
+
+// New returns a compression middleware that negotiates the encoding via Accept-Encoding.
+func New(config Config) gin.HandlerFunc {
+	if config.MinLength <= 0 {
+		config.MinLength = DefaultMinLength
+	}
+	if len(config.Encodings) == 0 {
+		config.Encodings = DefaultEncodings
+	}
+	if config.ExcludedContentTypes == nil {
+		config.ExcludedContentTypes = DefaultExcludedContentTypes
+	}
+	for _, encoding := range config.Encodings {
+		if _, ok := encoderPools[encoding]; !ok {
+			panic(ErrUnsupportedEncoding{Encoding: encoding})
+		}
+	}
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead || config.isExcludedPath(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+		encoding := Negotiate(c.GetHeader(HeaderAcceptEncoding), config.Encodings)
+		appendVary(c.Writer.Header())
+		if encoding == "" {
+			c.Next()
+			return
+		}
+		w := newWriter(c.Writer, encoding, &config)
+		c.Writer = w
+		defer func() {
+			w.close()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// ErrUnsupportedEncoding is reported when the configuration names an encoding that cannot be produced.
+type ErrUnsupportedEncoding struct {
+	Encoding string
+}
+
+func (e ErrUnsupportedEncoding) Error() string {
+	return "unsupported content encoding: " + e.Encoding
+}
+
+func (c *Config) isExcludedPath(path string) bool {
+	for _, prefix := range c.ExcludedPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) isExcludedContentType(contentType string) bool {
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	for _, prefix := range c.ExcludedContentTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func appendVary(header http.Header) {
+	for _, value := range header.Values(HeaderVary) {
+		for _, field := range strings.Split(value, ",") {
+			field = strings.TrimSpace(field)
+			if field == "*" || strings.EqualFold(field, HeaderAcceptEncoding) {
+				return
+			}
+		}
+	}
+	header.Add(HeaderVary, HeaderAcceptEncoding)
+}
+
+// Negotiate selects the encoding to use from the Accept-Encoding header value.
+//
+// The encoding with the highest quality value wins; ties are broken by the order of supported.
+// An empty string is returned if the client accepts none of the supported encodings.
+func Negotiate(acceptEncoding string, supported []string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+	qualities := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, q := parseCoding(part)
+		if name == "" {
+			continue
+		}
+		if name == "*" {
+			wildcard = q
+			continue
+		}
+		qualities[name] = q
+	}
+	best, bestQ := "", 0.0
+	for _, encoding := range supported {
+		q, ok := qualities[encoding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+func parseCoding(part string) (string, float64) {
+	name, params, _ := strings.Cut(part, ";")
+	name = strings.ToLower(strings.TrimSpace(name))
+	q := 1.0
+	for _, param := range strings.Split(params, ";") {
+		key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+		if !found || strings.TrimSpace(key) != "q" {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return "", 0
+		}
+		q = parsed
+	}
+	return name, q
+}
+
+// encoder is the common subset of all pooled compressors.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var encoderPools = map[string]*sync.Pool{
+	EncodingGzip: {New: func() any {
+		return gzip.NewWriter(io.Discard)
+	}},
+	EncodingDeflate: {New: func() any {
+		w, _ := flate.NewWriter(io.Discard, flate.DefaultCompression)
+		return w
+	}},
+	EncodingBrotli: {New: func() any {
+		return brotli.NewWriterLevel(io.Discard, brotli.DefaultCompression)
+	}},
+	EncodingZstd: {New: func() any {
+		w, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithEncoderLevel(zstd.SpeedDefault))
+		return w
+	}},
+}
+
+func acquireEncoder(encoding string, w io.Writer) encoder {
+	e := encoderPools[encoding].Get().(encoder)
+	e.Reset(w)
+	return e
+}
+
+func releaseEncoder(encoding string, e encoder) {
+	e.Reset(io.Discard)
+	encoderPools[encoding].Put(e)
+}
+
+var bufferPool = sync.Pool{New: func() any {
+	return new(bytes.Buffer)
+}}
+
+// writer buffers the body until MinLength is reached, then decides whether to compress it.
+type writer struct {
+	gin.ResponseWriter
+	config   *Config
+	encoding string
+	encoder  encoder
+	buffer   *bytes.Buffer
+	status   int
+	size     int
+	decided  bool
+	bypass   bool
+}
+
+var _ gin.ResponseWriter = (*writer)(nil)
+
+func newWriter(w gin.ResponseWriter, encoding string, config *Config) *writer {
+	return &writer{
+		ResponseWriter: w,
+		config:         config,
+		encoding:       encoding,
+		status:         http.StatusOK,
+		size:           -1,
+	}
+}
+
+func (w *writer) WriteHeader(code int) {
+	if code > 0 && !w.decided {
+		w.status = code
+	}
+}
+
+// WriteHeaderNow is deferred until the encoding decision is made, since the headers depend on it.
+func (w *writer) WriteHeaderNow() {
+	if w.size < 0 {
+		w.size = 0
+	}
+}
+
+func (w *writer) Status() int {
+	return w.status
+}
+
+// Size returns the number of uncompressed body bytes written by the handler.
+func (w *writer) Size() int {
+	return w.size
+}
+
+func (w *writer) Written() bool {
+	return w.size >= 0
+}
+
+func (w *writer) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *writer) Write(data []byte) (int, error) {
+	w.WriteHeaderNow()
+	w.size += len(data)
+	if !w.decided {
+		if w.buffer == nil {
+			w.buffer = bufferPool.Get().(*bytes.Buffer)
+		}
+		w.buffer.Write(data)
+		if w.buffer.Len() < w.config.MinLength {
+			return len(data), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.bypass {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.encoder.Write(data)
+}
+
+// decide commits the headers and flushes the buffered body, compressed if eligible.
+func (w *writer) decide(eligible bool) error {
+	w.decided = true
+	header := w.Header()
+	if eligible && !w.compressible(header) {
+		eligible = false
+	}
+	w.bypass = !eligible
+	if eligible {
+		header.Del(HeaderContentLength)
+		header.Set(HeaderContentEncoding, w.encoding)
+		w.encoder = acquireEncoder(w.encoding, w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.WriteHeaderNow()
+	if w.buffer == nil {
+		return nil
+	}
+	var err error
+	if w.bypass {
+		_, err = w.ResponseWriter.Write(w.buffer.Bytes())
+	} else {
+		_, err = w.encoder.Write(w.buffer.Bytes())
+	}
+	w.buffer.Reset()
+	bufferPool.Put(w.buffer)
+	w.buffer = nil
+	return err
+}
+
+func (w *writer) compressible(header http.Header) bool {
+	if w.status < http.StatusOK || w.status == http.StatusNoContent ||
+		w.status == http.StatusNotModified || w.status == http.StatusPartialContent {
+		return false
+	}
+	if header.Get(HeaderContentEncoding) != "" {
+		return false
+	}
+	contentType := header.Get(HeaderContentType)
+	if contentType == "" && w.buffer != nil {
+		contentType = http.DetectContentType(w.buffer.Bytes())
+		header.Set(HeaderContentType, contentType)
+	}
+	return !w.config.isExcludedContentType(contentType)
+}
+
+// Flush commits to compression, regardless of the current body size, so that streamed responses are not held back.
+func (w *writer) Flush() {
+	if !w.decided {
+		_ = w.decide(true)
+	}
+	if w.encoder != nil {
+		_ = w.encoder.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if w.decided || w.buffer != nil {
+		return nil, nil, ErrHijackAfterWrite
+	}
+	w.decided, w.bypass = true, true
+	return w.ResponseWriter.Hijack()
+}
+
+var ErrHijackAfterWrite = errors.New("compress: cannot hijack a connection after the body was written")
+
+// close finishes the response: small bodies are written as-is, and the encoder is returned to its pool.
+func (w *writer) close() {
+	if !w.decided {
+		if !w.Written() {
+			w.ResponseWriter.WriteHeader(w.status)
+			return
+		}
+		_ = w.decide(false)
+	}
+	if w.encoder != nil {
+		_ = w.encoder.Close()
+		releaseEncoder(w.encoding, w.encoder)
+		w.encoder = nil
+	}
+}
@@ -0,0 +1,234 @@
+package compress
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+	"github.com/rhosocial/go-rush-common/components/logger"
+	"github.com/rhosocial/go-rush-common/components/response"
+	"github.com/stretchr/testify/assert"
+)
+
+type envelopeItem struct {
+	ID          uint64 `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func newEnvelopeData(n int) []envelopeItem {
+	items := make([]envelopeItem, n)
+	for i := range items {
+		items[i] = envelopeItem{ID: uint64(i), Name: "activity", Description: "the standard response envelope"}
+	}
+	return items
+}
+
+func setupRouterCompress(middlewares ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(logger.AppendRequestID())
+	r.Use(middlewares...)
+	r.GET("/small", func(c *gin.Context) {
+		c.JSON(http.StatusOK, response.NewGeneric[any, any](c, 0, "success", nil, nil))
+	})
+	r.GET("/large", func(c *gin.Context) {
+		c.JSON(http.StatusOK, response.NewGeneric[[]envelopeItem, any](c, 0, "success", newEnvelopeData(100), nil))
+	})
+	r.GET("/image", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", bytes.Repeat([]byte{0x89}, 4096))
+	})
+	r.GET("/no_content", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	return r
+}
+
+func decode(t *testing.T, encoding string, body io.Reader) []byte {
+	var reader io.Reader
+	switch encoding {
+	case EncodingGzip:
+		r, err := gzip.NewReader(body)
+		assert.NoError(t, err)
+		reader = r
+	case EncodingDeflate:
+		reader = flate.NewReader(body)
+	case EncodingBrotli:
+		reader = brotli.NewReader(body)
+	case EncodingZstd:
+		r, err := zstd.NewReader(body)
+		assert.NoError(t, err)
+		defer r.Close()
+		reader = r
+	default:
+		reader = body
+	}
+	result, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	return result
+}
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		header   string
+		expected string
+	}{
+		{"", ""},
+		{"gzip", EncodingGzip},
+		{"gzip, deflate, br", EncodingBrotli},
+		{"gzip;q=1.0, br;q=0.5", EncodingGzip},
+		{"zstd, gzip", EncodingZstd},
+		{"*", EncodingBrotli},
+		{"br;q=0, *;q=0.1", EncodingZstd},
+		{"identity", ""},
+		{"gzip;q=0", ""},
+		{"GZIP", EncodingGzip},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, Negotiate(c.header, DefaultEncodings), c.header)
+	}
+}
+
+func TestCompress(t *testing.T) {
+	r := setupRouterCompress(Compress())
+
+	for _, encoding := range DefaultEncodings {
+		t.Run("large body with "+encoding, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/large", nil)
+			req.Header.Set(HeaderAcceptEncoding, encoding)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, encoding, w.Header().Get(HeaderContentEncoding))
+			assert.Equal(t, HeaderAcceptEncoding, w.Header().Get(HeaderVary))
+			assert.Empty(t, w.Header().Get(HeaderContentLength))
+			body := response.Generic[[]envelopeItem, any]{}
+			assert.NoError(t, json.Unmarshal(decode(t, encoding, w.Body), &body))
+			assert.Len(t, body.Data, 100)
+		})
+	}
+
+	t.Run("small body is not compressed", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/small", nil)
+		req.Header.Set(HeaderAcceptEncoding, "gzip")
+
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get(HeaderContentEncoding))
+		assert.Equal(t, HeaderAcceptEncoding, w.Header().Get(HeaderVary))
+		body := response.Generic[any, any]{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, "success", body.Message)
+	})
+
+	t.Run("already compressed content type is not compressed", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/image", nil)
+		req.Header.Set(HeaderAcceptEncoding, "gzip")
+
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get(HeaderContentEncoding))
+		assert.Equal(t, 4096, w.Body.Len())
+	})
+
+	t.Run("no accepted encoding", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/large", nil)
+
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get(HeaderContentEncoding))
+		assert.Equal(t, HeaderAcceptEncoding, w.Header().Get(HeaderVary))
+		assert.True(t, strings.HasPrefix(w.Body.String(), "{"))
+	})
+
+	t.Run("status without body is preserved", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/no_content", nil)
+		req.Header.Set(HeaderAcceptEncoding, "gzip")
+
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Empty(t, w.Header().Get(HeaderContentEncoding))
+	})
+}
+
+func TestNew(t *testing.T) {
+	t.Run("unsupported encoding", func(t *testing.T) {
+		assert.PanicsWithValue(t, ErrUnsupportedEncoding{Encoding: "lzma"}, func() {
+			New(Config{Encodings: []string{"lzma"}})
+		})
+	})
+
+	t.Run("excluded path", func(t *testing.T) {
+		r := setupRouterCompress(New(Config{ExcludedPaths: []string{"/large"}}))
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/large", nil)
+		req.Header.Set(HeaderAcceptEncoding, "gzip")
+
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get(HeaderContentEncoding))
+	})
+
+	t.Run("lower threshold", func(t *testing.T) {
+		r := setupRouterCompress(New(Config{MinLength: 16}))
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/small", nil)
+		req.Header.Set(HeaderAcceptEncoding, "gzip")
+
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, EncodingGzip, w.Header().Get(HeaderContentEncoding))
+	})
+}
+
+func benchmarkEnvelope(b *testing.B, r *gin.Engine, path string, encoding string) {
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	if encoding != "" {
+		req.Header.Set(HeaderAcceptEncoding, encoding)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		b.SetBytes(int64(w.Body.Len()))
+	}
+}
+
+func BenchmarkCompress(b *testing.B) {
+	plain := setupRouterCompress()
+	compressed := setupRouterCompress(Compress())
+	for _, path := range []string{"/small", "/large"} {
+		b.Run("baseline"+path, func(b *testing.B) {
+			benchmarkEnvelope(b, plain, path, "")
+		})
+		b.Run("identity"+path, func(b *testing.B) {
+			benchmarkEnvelope(b, compressed, path, "")
+		})
+		for _, encoding := range DefaultEncodings {
+			b.Run(encoding+path, func(b *testing.B) {
+				benchmarkEnvelope(b, compressed, path, encoding)
+			})
+		}
+	}
+}
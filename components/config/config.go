@@ -0,0 +1,193 @@
+// Package config loads configuration structs, such as environment.Env, from YAML, JSON or TOML files, overlaid by
+// environment variables and command-line flags.
+//
+// The fields are named by their `yaml` tags in every format. Once the sources are merged, the `default` tags are
+// applied to the zero fields and the `validate` tags are checked; all errors are reported at once, qualified by the
+// path of their field, e.g. `Redis[0].Weight: must be at most 10`.
+//
+// Secrets are kept out of the files with references resolved at load time: `${env:REDIS_PASS}`, `${file:/run/secrets/db}`
+// or any scheme added by RegisterProvider, and values encrypted by Cipher.Encrypt, starting with `enc:`.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+	FormatTOML = "toml"
+)
+
+// FormatOf returns the format of a file from its extension, YAML by default.
+func FormatOf(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		return FormatJSON
+	case ".toml":
+		return FormatTOML
+	}
+	return FormatYAML
+}
+
+// Decode decodes the document into target, keeping the fields absent from the document. JSON and TOML documents are
+// converted to YAML first, so that the `yaml` tags name the fields whatever the format.
+func Decode(data []byte, format string, target any) error {
+	switch format {
+	case FormatYAML, "":
+		return yaml.Unmarshal(data, target)
+	case FormatJSON, FormatTOML:
+		var document map[string]any
+		var err error
+		if format == FormatJSON {
+			err = json.Unmarshal(data, &document)
+		} else {
+			err = toml.Unmarshal(data, &document)
+		}
+		if err != nil {
+			return err
+		}
+		if data, err = yaml.Marshal(document); err != nil {
+			return err
+		}
+		return yaml.Unmarshal(data, target)
+	}
+	return fmt.Errorf("unsupported configuration format %q", format)
+}
+
+// Loader loads a configuration from its sources, in this order: the files, the environment variables, then the
+// overrides.
+type Loader struct {
+	// Files are read in order, each one overriding the fields it sets, along with their optional layers, see Layers.
+	// The format comes from the extension.
+	Files []string
+	// Profile selects the profile layers of the files, e.g. `config.prod.yaml`. Empty means none.
+	Profile string
+	// EnvPrefix enables the environment variables starting with it and an underscore, see SetEnv. Empty disables them.
+	EnvPrefix string
+	// Overrides are `path=value` assignments, see Set.
+	Overrides []string
+	// Secrets resolves the secret references and encrypted values. Nil uses the registered providers and the key from
+	// the environment, see ResolveSecrets.
+	Secrets *Resolver
+	// Production rejects the weak secrets, see CheckSecrets.
+	Production bool
+}
+
+// Layers returns the files read for filename, in order: the file itself, then if they exist its profile layer, e.g.
+// `config.prod.yaml` for `config.yaml`, its local layer `config.local.yaml` and its local profile layer
+// `config.prod.local.yaml`. The local layers hold the settings of a machine, and are kept out of version control.
+func Layers(filename string, profile string) []string {
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+	layers := []string{filename}
+	if profile != "" {
+		layers = append(layers, base+"."+profile+ext)
+	}
+	layers = append(layers, base+".local"+ext)
+	if profile != "" {
+		layers = append(layers, base+"."+profile+".local"+ext)
+	}
+	return layers
+}
+
+// readFiles calls fn with the content of the files and their existing layers, in order.
+func (l *Loader) readFiles(fn func(filename string, data []byte) error) error {
+	for _, filename := range l.Files {
+		for i, layer := range Layers(filename, l.Profile) {
+			data, err := os.ReadFile(layer)
+			if i > 0 && errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := fn(layer, data); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RegisterFlags adds to fs the repeatable flags `-config file`, appending to Files, and `-set path=value`, appending to
+// Overrides, and the flag `-profile name`, setting Profile.
+func (l *Loader) RegisterFlags(fs *flag.FlagSet) {
+	fs.Func("config", "configuration `file` (YAML, JSON or TOML), may be repeated", func(s string) error {
+		l.Files = append(l.Files, s)
+		return nil
+	})
+	fs.Func("set", "override a configuration field, e.g. Logger.Level=debug, may be repeated", func(s string) error {
+		if !strings.Contains(s, "=") {
+			return fmt.Errorf("%q is not path=value", s)
+		}
+		l.Overrides = append(l.Overrides, s)
+		return nil
+	})
+	fs.StringVar(&l.Profile, "profile", l.Profile, "configuration `profile`, selecting the profile layers of the files")
+}
+
+// Load loads the configuration into the struct pointed to by target, applies the defaults, resolves the secrets and
+// validates it. The errors of the environment variables, the overrides, the secrets and the validation are returned
+// together as Errors.
+func (l *Loader) Load(target any) error {
+	err := l.readFiles(func(filename string, data []byte) error {
+		if err := Decode(data, FormatOf(filename), target); err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	errs := Errors{}
+	if l.EnvPrefix != "" {
+		if err := SetEnv(target, l.EnvPrefix); err != nil {
+			errs.add("", err)
+		}
+	}
+	for _, override := range l.Overrides {
+		path, value, _ := strings.Cut(override, "=")
+		if err := Set(target, path, value); err != nil {
+			errs.add(path, err)
+		}
+	}
+	if err := ApplyDefaults(target); err != nil {
+		errs.add("", err)
+	}
+	secrets := l.Secrets
+	if secrets == nil {
+		secrets = &Resolver{}
+	}
+	if err := secrets.Resolve(target); err != nil {
+		errs.add("", err)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	if err := Validate(target); err != nil {
+		errs.add("", err)
+	}
+	if l.Production {
+		if err := CheckSecrets(target); err != nil {
+			errs.add("", err)
+		}
+	}
+	return errs.err()
+}
+
+// Load loads the files into the struct pointed to by target, applies the defaults and validates it.
+func Load(target any, files ...string) error {
+	l := Loader{Files: files}
+	return l.Load(target)
+}
@@ -0,0 +1,264 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rhosocial/go-rush-common/components/redact"
+	"github.com/stretchr/testify/assert"
+)
+
+type testDialer struct {
+	Timeout uint8 `yaml:"Timeout,omitempty" default:"1" validate:"min=1,max=10"`
+}
+
+type testServer struct {
+	Host   string      `yaml:"Host" default:"localhost" validate:"required"`
+	Port   uint16      `yaml:"Port,omitempty" default:"6379"`
+	Weight uint8       `yaml:"Weight,omitempty" default:"1" validate:"min=1,max=10"`
+	Dialer *testDialer `yaml:"Dialer,omitempty" default:"{}"`
+}
+
+type testPool struct {
+	Limit uint32 `yaml:"Limit" default:"16" validate:"min=1"`
+}
+
+type testEnv struct {
+	Name            string              `yaml:"Name" default:"app"`
+	Level           string              `yaml:"Level,omitempty" default:"info" validate:"oneof=debug info warn error"`
+	Timeout         time.Duration       `yaml:"Timeout,omitempty" default:"5s"`
+	Tags            []string            `yaml:"Tags,omitempty" default:"a,b"`
+	Ratio           *float64            `yaml:"Ratio,omitempty" default:"0.5"`
+	Servers         []testServer        `yaml:"Servers,omitempty"`
+	Optional        *testServer         `yaml:"Optional,omitempty"`
+	Pools           map[string]testPool `yaml:"Pools,omitempty"`
+	Labels          map[string]string   `yaml:"Labels,omitempty"`
+	ShutdownTimeout uint16              `yaml:"ShutdownTimeout,omitempty"`
+}
+
+func TestApplyDefaults(t *testing.T) {
+	e := testEnv{
+		Level:   "debug",
+		Servers: []testServer{{Host: "redis", Dialer: &testDialer{Timeout: 3}}, {}},
+		Pools:   map[string]testPool{"rooms": {}, "games": {Limit: 2}},
+	}
+	assert.NoError(t, ApplyDefaults(&e))
+	assert.Equal(t, "app", e.Name)
+	assert.Equal(t, "debug", e.Level)
+	assert.Equal(t, 5*time.Second, e.Timeout)
+	assert.Equal(t, []string{"a", "b"}, e.Tags)
+	assert.Equal(t, 0.5, *e.Ratio)
+	assert.Equal(t, "redis", e.Servers[0].Host)
+	assert.Equal(t, uint8(3), e.Servers[0].Dialer.Timeout)
+	assert.Equal(t, "localhost", e.Servers[1].Host)
+	assert.Equal(t, uint16(6379), e.Servers[1].Port)
+	assert.Equal(t, uint8(1), e.Servers[1].Dialer.Timeout)
+	assert.Nil(t, e.Optional)
+	assert.Equal(t, uint32(16), e.Pools["rooms"].Limit)
+	assert.Equal(t, uint32(2), e.Pools["games"].Limit)
+
+	type invalid struct {
+		Port uint16 `default:"http"`
+	}
+	var errs Errors
+	assert.ErrorAs(t, ApplyDefaults(&invalid{}), &errs)
+	assert.Equal(t, "port", errs[0].Path)
+	assert.Error(t, ApplyDefaults(invalid{}))
+}
+
+func TestValidate(t *testing.T) {
+	e := testEnv{
+		Level:    "verbose",
+		Servers:  []testServer{{Host: "redis", Weight: 1}, {Weight: 11}},
+		Optional: &testServer{Host: "x", Weight: 1, Dialer: &testDialer{Timeout: 20}},
+		Pools:    map[string]testPool{"rooms": {}},
+	}
+	err := Validate(&e)
+	var errs Errors
+	assert.ErrorAs(t, err, &errs)
+	assert.ElementsMatch(t, []string{
+		"Level: must be one of debug info warn error",
+		"Servers[1].Host: is required",
+		"Servers[1].Weight: must be at most 10",
+		"Optional.Dialer.Timeout: must be at most 10",
+		"Pools[rooms].Limit: must be at least 1",
+	}, messages(errs))
+
+	assert.NoError(t, ApplyDefaults(&e))
+	e.Level = "info"
+	e.Servers[1] = testServer{Host: "redis", Weight: 2}
+	e.Optional.Dialer.Timeout = 2
+	assert.NoError(t, Validate(&e))
+}
+
+func messages(errs Errors) []string {
+	result := make([]string, len(errs))
+	for i, err := range errs {
+		result[i] = err.Error()
+	}
+	return result
+}
+
+func TestSet(t *testing.T) {
+	e := testEnv{Servers: []testServer{{Host: "redis"}}}
+	assert.NoError(t, Set(&e, "level", "warn"))
+	assert.NoError(t, Set(&e, "Servers[0].Port", "6380"))
+	assert.NoError(t, Set(&e, "Servers.1.Host", "replica"))
+	assert.NoError(t, Set(&e, "Optional.Dialer.Timeout", "2"))
+	assert.NoError(t, Set(&e, "Pools.rooms.Limit", "8"))
+	assert.NoError(t, Set(&e, "Labels.team", "core"))
+	assert.NoError(t, Set(&e, "Timeout", "1m"))
+	assert.NoError(t, Set(&e, "Tags", "x,y"))
+	assert.Equal(t, "warn", e.Level)
+	assert.Equal(t, uint16(6380), e.Servers[0].Port)
+	assert.Equal(t, "replica", e.Servers[1].Host)
+	assert.Equal(t, uint8(2), e.Optional.Dialer.Timeout)
+	assert.Equal(t, uint32(8), e.Pools["rooms"].Limit)
+	assert.Equal(t, "core", e.Labels["team"])
+	assert.Equal(t, time.Minute, e.Timeout)
+	assert.Equal(t, []string{"x", "y"}, e.Tags)
+
+	var fieldError *FieldError
+	assert.ErrorAs(t, Set(&e, "Missing", "1"), &fieldError)
+	assert.Equal(t, "Missing: no such field", fieldError.Error())
+	assert.ErrorContains(t, Set(&e, "Servers.3.Host", "x"), "no such field")
+	assert.ErrorContains(t, Set(&e, "Servers[0].Port", "x"), "Servers[0].Port: ")
+	assert.ErrorContains(t, Set(&e, "Servers", "x"), "cannot set")
+}
+
+func TestSetEnv(t *testing.T) {
+	e := testEnv{}
+	err := setEnv(&e, "APP", []string{
+		"APP_LEVEL=debug",
+		"APP_SHUTDOWN_TIMEOUT=10",
+		"APP_SERVERS_0_HOST=redis",
+		"APP_OPTIONAL_DIALER_TIMEOUT=x",
+		"APP_UNKNOWN=1",
+		"OTHER_LEVEL=warn",
+	})
+	assert.Equal(t, "debug", e.Level)
+	assert.Equal(t, uint16(10), e.ShutdownTimeout)
+	assert.Equal(t, "redis", e.Servers[0].Host)
+	assert.Nil(t, e.Optional)
+	var errs Errors
+	assert.ErrorAs(t, err, &errs)
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "APP_OPTIONAL_DIALER_TIMEOUT", errs[0].Path)
+}
+
+func TestLoader_Load(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) string {
+		filename := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(filename, []byte(content), 0o600))
+		return filename
+	}
+	base := write("base.yaml", "Name: base\nLevel: warn\nServers:\n  - Host: redis\n    Weight: 2\n")
+	jsonFile := write("override.json", `{"Name": "json", "Pools": {"rooms": {"Limit": 4}}}`)
+	tomlFile := write("override.toml", "Level = \"error\"\n[Optional]\nHost = \"toml\"\n")
+
+	t.Run("layers", func(t *testing.T) {
+		t.Setenv("TEST_CONFIG_LEVEL", "debug")
+		l := Loader{EnvPrefix: "TEST_CONFIG"}
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		l.RegisterFlags(fs)
+		assert.NoError(t, fs.Parse([]string{"-config", base, "-config", jsonFile, "-config", tomlFile, "-set", "Servers[0].Port=6380"}))
+		var e testEnv
+		assert.NoError(t, l.Load(&e))
+		assert.Equal(t, "json", e.Name)
+		assert.Equal(t, "debug", e.Level)
+		assert.Equal(t, "redis", e.Servers[0].Host)
+		assert.Equal(t, uint8(2), e.Servers[0].Weight)
+		assert.Equal(t, uint16(6380), e.Servers[0].Port)
+		assert.Equal(t, uint8(1), e.Servers[0].Dialer.Timeout)
+		assert.Equal(t, uint32(4), e.Pools["rooms"].Limit)
+		assert.Equal(t, "toml", e.Optional.Host)
+		assert.Equal(t, uint8(1), e.Optional.Weight)
+	})
+
+	t.Run("aggregated errors", func(t *testing.T) {
+		l := Loader{Files: []string{base}, Overrides: []string{"Missing=1", "Servers[0].Weight=20", "Level=verbose"}}
+		var e testEnv
+		err := l.Load(&e)
+		var errs Errors
+		assert.ErrorAs(t, err, &errs)
+		assert.Equal(t, []string{"Missing: no such field"}, messages(errs))
+
+		l.Overrides = l.Overrides[1:]
+		e = testEnv{}
+		assert.ErrorAs(t, l.Load(&e), &errs)
+		assert.ElementsMatch(t, []string{"Level: must be one of debug info warn error", "Servers[0].Weight: must be at most 10"}, messages(errs))
+	})
+
+	t.Run("invalid file", func(t *testing.T) {
+		invalid := write("invalid.yaml", "Servers: 1\n")
+		var e testEnv
+		err := Load(&e, invalid)
+		assert.ErrorContains(t, err, invalid)
+		assert.ErrorIs(t, Load(&e, filepath.Join(dir, "missing.yaml")), os.ErrNotExist)
+		assert.False(t, errors.As(err, new(Errors)))
+	})
+
+	t.Run("profile layers", func(t *testing.T) {
+		config := write("config.yaml", "Name: base\nLevel: warn\n")
+		write("config.prod.yaml", "Level: error\nTags: [prod]\n")
+		write("config.local.yaml", "Name: local\n")
+		write("config.test.local.yaml", "Name: test\n")
+		assert.Equal(t, []string{config, filepath.Join(dir, "config.local.yaml")}, Layers(config, ""))
+
+		l := Loader{}
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		l.RegisterFlags(fs)
+		assert.NoError(t, fs.Parse([]string{"-config", config, "-profile", "prod"}))
+		var e testEnv
+		assert.NoError(t, l.Load(&e))
+		assert.Equal(t, "local", e.Name)
+		assert.Equal(t, "error", e.Level)
+		assert.Equal(t, []string{"prod"}, e.Tags)
+
+		e = testEnv{}
+		l.Profile = "test"
+		assert.NoError(t, l.Load(&e))
+		assert.Equal(t, "test", e.Name)
+		assert.Equal(t, "warn", e.Level)
+	})
+
+	t.Run("flags", func(t *testing.T) {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(new(nopWriter))
+		(&Loader{}).RegisterFlags(fs)
+		assert.Error(t, fs.Parse([]string{"-set", "Level"}))
+	})
+}
+
+type nopWriter struct{}
+
+func (nopWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func TestDump(t *testing.T) {
+	s := testSecrets{
+		Database: testCredentials{Username: "app", Password: "s3cr3t"},
+		Caches:   []testCredentials{{Password: "other"}, {}},
+	}
+	data, err := Dump(&s)
+	assert.NoError(t, err)
+	assert.Equal(t, "Database:\n    Username: app\n    Password: '"+redact.Mask+"'\nCaches:\n"+
+		"    - Username: \"\"\n      Password: '"+redact.Mask+"'\n    - Username: \"\"\n      Password: \"\"\n", string(data))
+	assert.Equal(t, "s3cr3t", s.Database.Password)
+	assert.Equal(t, "other", s.Caches[0].Password)
+	_, err = Dump("config")
+	assert.Error(t, err)
+}
+
+func TestFormatOf(t *testing.T) {
+	assert.Equal(t, FormatYAML, FormatOf("config.yml"))
+	assert.Equal(t, FormatJSON, FormatOf("config.JSON"))
+	assert.Equal(t, FormatTOML, FormatOf("config.toml"))
+	assert.Error(t, Decode(nil, "ini", &testEnv{}))
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ApplyDefaults sets the zero fields of the struct pointed to by v to the value of their `default` tag, recursively.
+//
+// Nested structs, the elements of slices and the values of maps are filled in as well. A nil pointer is left nil,
+// unless its field has a `default` tag: `default:"{}"` allocates a struct with its own defaults, and any other value
+// allocates a scalar. As explicit zero values cannot be told apart from absent ones, a field whose default is not
+// zero cannot be set to zero.
+func ApplyDefaults(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("config: ApplyDefaults of non-pointer %T", v)
+	}
+	errs := Errors{}
+	applyDefaults(rv.Elem(), "", &errs)
+	return errs.err()
+}
+
+func applyDefaults(v reflect.Value, path string, errs *Errors) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			applyDefaults(v.Elem(), path, errs)
+		}
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return
+		}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			fieldPath := join(path, fieldName(f))
+			if f.Anonymous {
+				fieldPath = path
+			}
+			field := v.Field(i)
+			if value, ok := f.Tag.Lookup("default"); ok {
+				if err := setDefault(field, value); err != nil {
+					errs.add(fieldPath, err)
+					continue
+				}
+			}
+			applyDefaults(field, fieldPath, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			applyDefaults(v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case reflect.Map:
+		if !hasDefaults(v.Type().Elem()) {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			// Map values are not addressable: fill a copy in and store it back.
+			value := reflect.New(v.Type().Elem()).Elem()
+			value.Set(iter.Value())
+			applyDefaults(value, fmt.Sprintf("%s[%v]", path, iter.Key()), errs)
+			v.SetMapIndex(iter.Key(), value)
+		}
+	}
+}
+
+// setDefault sets the field to the default value if it is zero.
+func setDefault(field reflect.Value, value string) error {
+	if !field.IsZero() {
+		return nil
+	}
+	if field.Kind() == reflect.Pointer {
+		elem := reflect.New(field.Type().Elem())
+		if elem.Elem().Kind() != reflect.Struct {
+			if err := parseValue(elem.Elem(), value); err != nil {
+				return err
+			}
+		} else if value != "{}" {
+			return fmt.Errorf("invalid default %q of a struct, only {} is supported", value)
+		}
+		field.Set(elem)
+		return nil
+	}
+	if field.Kind() == reflect.Struct {
+		// The defaults of its fields apply.
+		return nil
+	}
+	return parseValue(field, value)
+}
+
+// hasDefaults reports whether t has any `default` tag, so that maps of scalars are not copied needlessly.
+func hasDefaults(t reflect.Type) bool {
+	return hasDefaultsSeen(t, make(map[reflect.Type]bool))
+}
+
+func hasDefaultsSeen(t reflect.Type, seen map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return false
+	}
+	seen[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup("default"); ok || hasDefaultsSeen(t.Field(i).Type, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	timeType     = reflect.TypeFor[time.Time]()
+	durationType = reflect.TypeFor[time.Duration]()
+)
+
+// parseValue sets the scalar v from its textual representation. Slices of scalars are comma-separated.
+func parseValue(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		if s == "" {
+			v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+			return nil
+		}
+		items := strings.Split(s, ",")
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := parseValue(slice.Index(i), strings.TrimSpace(item)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if err := parseValue(elem.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(elem)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/rhosocial/go-rush-common/components/redact"
+)
+
+// Change is a field whose value differs between two configurations. Old or New is nil if the field, an element of a
+// slice or a map, is absent from one of them. The values of the fields tagged `log:"secret"` are replaced by
+// redact.Mask, or empty if unset, so that the changes may be logged.
+type Change struct {
+	Path string
+	Old  any
+	New  any
+}
+
+// Changed reports whether one of the changes is at or below one of the paths, e.g. `Redis` for `Redis[0].Weight`. No
+// paths means any change.
+func Changed(changes []Change, paths ...string) bool {
+	if len(paths) == 0 {
+		return len(changes) > 0
+	}
+	for _, change := range changes {
+		for _, p := range paths {
+			if change.Path == p || strings.HasPrefix(change.Path, p+".") || strings.HasPrefix(change.Path, p+"[") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Diff returns the changes from old to new, two values of the same type, down to their scalar fields.
+func Diff(old any, new any) []Change {
+	changes := make([]Change, 0)
+	diff(reflect.ValueOf(old), reflect.ValueOf(new), "", &changes)
+	return changes
+}
+
+func diff(old reflect.Value, new reflect.Value, path string, changes *[]Change) {
+	if !old.IsValid() || !new.IsValid() {
+		if old.IsValid() != new.IsValid() {
+			*changes = append(*changes, Change{Path: path, Old: interfaceOf(old), New: interfaceOf(new)})
+		}
+		return
+	}
+	switch old.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if old.IsNil() || new.IsNil() {
+			if old.IsNil() != new.IsNil() {
+				*changes = append(*changes, Change{Path: path, Old: interfaceOf(old), New: interfaceOf(new)})
+			}
+			return
+		}
+		diff(old.Elem(), new.Elem(), path, changes)
+		return
+	case reflect.Struct:
+		if old.Type() == timeType {
+			break
+		}
+		t := old.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			fieldPath := join(path, fieldName(f))
+			if f.Anonymous {
+				fieldPath = path
+			}
+			if redact.IsSecret(f) {
+				if !reflect.DeepEqual(old.Field(i).Interface(), new.Field(i).Interface()) {
+					*changes = append(*changes, Change{Path: fieldPath, Old: maskSecret(old.Field(i)), New: maskSecret(new.Field(i))})
+				}
+				continue
+			}
+			diff(old.Field(i), new.Field(i), fieldPath, changes)
+		}
+		return
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < max(old.Len(), new.Len()); i++ {
+			var o, n reflect.Value
+			if i < old.Len() {
+				o = old.Index(i)
+			}
+			if i < new.Len() {
+				n = new.Index(i)
+			}
+			diff(o, n, fmt.Sprintf("%s[%d]", path, i), changes)
+		}
+		return
+	case reflect.Map:
+		keys := old.MapKeys()
+		for _, key := range new.MapKeys() {
+			if !old.MapIndex(key).IsValid() {
+				keys = append(keys, key)
+			}
+		}
+		// Sorted, so that the changes come in a stable order.
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+		})
+		for _, key := range keys {
+			diff(old.MapIndex(key), new.MapIndex(key), fmt.Sprintf("%s[%v]", path, key), changes)
+		}
+		return
+	}
+	if !reflect.DeepEqual(old.Interface(), new.Interface()) {
+		*changes = append(*changes, Change{Path: path, Old: old.Interface(), New: new.Interface()})
+	}
+}
+
+func maskSecret(v reflect.Value) any {
+	if v.IsZero() {
+		return ""
+	}
+	return redact.Mask
+}
+
+func interfaceOf(v reflect.Value) any {
+	if !v.IsValid() || ((v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil()) {
+		return nil
+	}
+	return v.Interface()
+}
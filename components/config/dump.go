@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/rhosocial/go-rush-common/components/redact"
+	"gopkg.in/yaml.v3"
+)
+
+// Dump returns the YAML document of the configuration v, typically once loaded, with the non-empty fields tagged
+// `log:"secret"` replaced by redact.Mask. v is left untouched.
+func Dump(v any) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config: Dump of non-struct %T", v)
+	}
+	// A copy made through YAML shares no slice, map or pointer with v.
+	data, err := yaml.Marshal(rv.Interface())
+	if err != nil {
+		return nil, err
+	}
+	copied := reflect.New(rv.Type())
+	if err := yaml.Unmarshal(data, copied.Interface()); err != nil {
+		return nil, err
+	}
+	maskSecrets(copied.Elem())
+	return yaml.Marshal(copied.Interface())
+}
+
+func maskSecrets(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			maskSecrets(v.Elem())
+		}
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return
+		}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			field := v.Field(i)
+			if field.Kind() == reflect.String && redact.IsSecret(f) {
+				if field.String() != "" {
+					field.SetString(redact.Mask)
+				}
+				continue
+			}
+			maskSecrets(field)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			maskSecrets(v.Index(i))
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			// Map values are not addressable: mask a copy and store it back.
+			value := reflect.New(v.Type().Elem()).Elem()
+			value.Set(iter.Value())
+			maskSecrets(value)
+			v.SetMapIndex(iter.Key(), value)
+		}
+	}
+}
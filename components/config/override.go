@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Set sets the field at path, e.g. `Logger.Level` or `Redis[0].Host` (also written `Redis.0.Host`), of the struct
+// pointed to by v. The names are the YAML ones, matched case-insensitively. Nil pointers are allocated, and the element
+// just past the end of a slice is appended.
+func Set(v any, path string, value string) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("config: Set of non-pointer %T", v)
+	}
+	segments := strings.FieldsFunc(path, func(r rune) bool { return r == '.' || r == '[' || r == ']' })
+	if len(segments) == 0 {
+		return &FieldError{Path: path, Err: fmt.Errorf("empty path")}
+	}
+	found, err := setPath(rv.Elem(), segments, ".", value)
+	if err == nil && !found {
+		err = fmt.Errorf("no such field")
+	}
+	if err != nil {
+		return &FieldError{Path: path, Err: err}
+	}
+	return nil
+}
+
+// SetEnv sets the fields named by the environment variables starting with prefix and an underscore: `APP_LOGGER_LEVEL`
+// sets `Logger.Level`, `APP_REDIS_0_HOST` sets `Redis[0].Host` and `APP_SHUTDOWN_TIMEOUT` sets `ShutdownTimeout` with
+// the prefix `APP`. The names are matched case-insensitively; the variables naming no field are ignored.
+func SetEnv(v any, prefix string) error {
+	return setEnv(v, prefix, os.Environ())
+}
+
+func setEnv(v any, prefix string, environ []string) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("config: SetEnv of non-pointer %T", v)
+	}
+	errs := Errors{}
+	for _, variable := range environ {
+		name, value, _ := strings.Cut(variable, "=")
+		rest, ok := strings.CutPrefix(name, prefix+"_")
+		if !ok || rest == "" {
+			continue
+		}
+		if _, err := setPath(rv.Elem(), strings.Split(rest, "_"), "_", value); err != nil {
+			errs.add(name, err)
+		}
+	}
+	return errs.err()
+}
+
+// setPath sets the field at the path segments, and reports whether it exists. The name of a struct field may span
+// several segments, joined by sep or not, so that `SHUTDOWN_TIMEOUT` finds `ShutdownTimeout`.
+func setPath(v reflect.Value, segments []string, sep string, value string) (bool, error) {
+	if len(segments) == 0 {
+		switch v.Kind() {
+		case reflect.Struct, reflect.Map, reflect.Interface:
+			if v.Type() != timeType {
+				return true, fmt.Errorf("cannot set %s from a string", v.Type())
+			}
+		}
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Struct {
+			return true, fmt.Errorf("cannot set %s from a string", v.Type())
+		}
+		return true, parseText(v, value)
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			elem := reflect.New(v.Type().Elem())
+			found, err := setPath(elem.Elem(), segments, sep, value)
+			if found && err == nil {
+				v.Set(elem)
+			}
+			return found, err
+		}
+		return setPath(v.Elem(), segments, sep, value)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if f.Anonymous {
+				if found, err := setPath(v.Field(i), segments, sep, value); found {
+					return found, err
+				}
+				continue
+			}
+			name := fieldName(f)
+			for n := 1; n <= len(segments); n++ {
+				if strings.EqualFold(name, strings.Join(segments[:n], sep)) || strings.EqualFold(name, strings.Join(segments[:n], "")) {
+					return setPath(v.Field(i), segments[n:], sep, value)
+				}
+			}
+		}
+		return false, nil
+	case reflect.Slice:
+		index, err := strconv.Atoi(segments[0])
+		if err != nil || index < 0 || index > v.Len() {
+			return false, nil
+		}
+		if index == v.Len() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			found, err := setPath(elem, segments[1:], sep, value)
+			if found && err == nil {
+				v.Set(reflect.Append(v, elem))
+			}
+			return found, err
+		}
+		return setPath(v.Index(index), segments[1:], sep, value)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return false, nil
+		}
+		key := reflect.ValueOf(segments[0]).Convert(v.Type().Key())
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if current := v.MapIndex(key); current.IsValid() {
+			elem.Set(current)
+		}
+		found, err := setPath(elem, segments[1:], sep, value)
+		if found && err == nil {
+			if v.IsNil() {
+				v.Set(reflect.MakeMap(v.Type()))
+			}
+			v.SetMapIndex(key, elem)
+		}
+		return found, err
+	}
+	return false, nil
+}
+
+// parseText sets v from a string, with the encoding.TextUnmarshaler of its type if any.
+func parseText(v reflect.Value, s string) error {
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(interface{ UnmarshalText([]byte) error }); ok {
+			return u.UnmarshalText([]byte(s))
+		}
+	}
+	return parseValue(v, s)
+}
@@ -0,0 +1,330 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/rhosocial/go-rush-common/components/redact"
+)
+
+const (
+	// EncryptedPrefix starts the values encrypted by Cipher.Encrypt.
+	EncryptedPrefix = "enc:"
+	// EnvSecretKey is the environment variable holding the base64 key of the `enc:` values.
+	EnvSecretKey = "CONFIG_SECRET_KEY"
+	// EnvSecretKeyFile is the environment variable naming a file holding the base64 key, if EnvSecretKey is not set.
+	EnvSecretKeyFile = "CONFIG_SECRET_KEY_FILE"
+)
+
+// ErrNoSecretKey is returned when an `enc:` value is found but no key is configured.
+var ErrNoSecretKey = errors.New("no secret key, set " + EnvSecretKey + " or " + EnvSecretKeyFile)
+
+// WeakSecrets are the values rejected by CheckSecrets, compared case-insensitively.
+var WeakSecrets = []string{"123456", "12345678", "password", "passwd", "changeme", "secret", "root", "admin", "default"}
+
+// Provider resolves the references of a scheme, e.g. the name of the environment variable in `${env:REDIS_PASS}`.
+type Provider interface {
+	Resolve(ref string) (string, error)
+}
+
+// ProviderFunc adapts a function to Provider.
+type ProviderFunc func(ref string) (string, error)
+
+func (f ProviderFunc) Resolve(ref string) (string, error) {
+	return f(ref)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]Provider{
+		"env":  ProviderFunc(resolveEnv),
+		"file": ProviderFunc(resolveFile),
+	}
+)
+
+// RegisterProvider makes `${scheme:ref}` resolved by p, e.g. a secret store client. It replaces the provider of the
+// scheme if any, including the built-in `env` and `file` ones.
+func RegisterProvider(scheme string, p Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[scheme] = p
+}
+
+// resolveEnv resolves `${env:NAME}` to the value of the environment variable, which must be set.
+func resolveEnv(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// resolveFile resolves `${file:/path}` to the content of the file without its trailing newlines, as written by most
+// secret mounts.
+func resolveFile(filename string) (string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// Cipher encrypts and decrypts the `enc:` values with AES-GCM.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher creates a Cipher from an AES key of 16, 24 or 32 bytes.
+func NewCipher(key []byte) (*Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// CipherFromEnv creates a Cipher from the base64 key in EnvSecretKey, or in the file named by EnvSecretKeyFile. It
+// returns ErrNoSecretKey if neither is set.
+func CipherFromEnv() (*Cipher, error) {
+	encoded, ok := os.LookupEnv(EnvSecretKey)
+	if !ok {
+		filename, ok := os.LookupEnv(EnvSecretKeyFile)
+		if !ok {
+			return nil, ErrNoSecretKey
+		}
+		var err error
+		if encoded, err = resolveFile(filename); err != nil {
+			return nil, err
+		}
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("secret key: %w", err)
+	}
+	return NewCipher(key)
+}
+
+// Encrypt returns the `enc:` value of the plaintext: the base64 of a random nonce followed by the sealed plaintext.
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return EncryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the plaintext of an `enc:` value.
+func (c *Cipher) Decrypt(value string) (string, error) {
+	encoded, ok := strings.CutPrefix(value, EncryptedPrefix)
+	if !ok {
+		return "", fmt.Errorf("not an %q value", EncryptedPrefix)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < c.aead.NonceSize() {
+		return "", errors.New("encrypted value too short")
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("cannot decrypt, wrong key or corrupted value")
+	}
+	return string(plaintext), nil
+}
+
+// reference matches `${scheme:ref}`, and `$${` escaping a literal `${`.
+var reference = regexp.MustCompile(`\$?\$\{([A-Za-z][A-Za-z0-9+.-]*):([^}]*)\}`)
+
+// Resolver resolves the secrets of a configuration: the `${scheme:ref}` references in string fields are replaced by the
+// value resolved by the provider of the scheme, then a field starting with `enc:` is decrypted.
+type Resolver struct {
+	// Cipher decrypts the `enc:` values. Nil uses CipherFromEnv once an `enc:` value is found.
+	Cipher *Cipher
+	// Providers resolve their schemes, before the registered providers.
+	Providers map[string]Provider
+}
+
+// ResolveSecrets resolves the secrets of the struct pointed to by v with the registered providers and the key from the
+// environment, see Resolver.
+func ResolveSecrets(v any) error {
+	return (&Resolver{}).Resolve(v)
+}
+
+// Resolve resolves the secrets of the string fields of the struct pointed to by v, recursively. The errors name the
+// fields and references, never the values.
+func (r *Resolver) Resolve(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("config: Resolve of non-pointer %T", v)
+	}
+	errs := Errors{}
+	r.resolveValue(rv.Elem(), "", &errs)
+	return errs.err()
+}
+
+func (r *Resolver) resolveValue(v reflect.Value, path string, errs *Errors) {
+	switch v.Kind() {
+	case reflect.String:
+		resolved, err := r.resolveString(v.String())
+		if err != nil {
+			errs.add(path, err)
+			return
+		}
+		v.SetString(resolved)
+	case reflect.Pointer:
+		if !v.IsNil() {
+			r.resolveValue(v.Elem(), path, errs)
+		}
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return
+		}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			fieldPath := join(path, fieldName(f))
+			if f.Anonymous {
+				fieldPath = path
+			}
+			r.resolveValue(v.Field(i), fieldPath, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			r.resolveValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			// Map values are not addressable: resolve a copy and store it back.
+			value := reflect.New(v.Type().Elem()).Elem()
+			value.Set(iter.Value())
+			r.resolveValue(value, fmt.Sprintf("%s[%v]", path, iter.Key()), errs)
+			v.SetMapIndex(iter.Key(), value)
+		}
+	}
+}
+
+func (r *Resolver) resolveString(s string) (string, error) {
+	var err error
+	if strings.Contains(s, "${") {
+		s = reference.ReplaceAllStringFunc(s, func(match string) string {
+			if strings.HasPrefix(match, "$$") {
+				return match[1:]
+			}
+			groups := reference.FindStringSubmatch(match)
+			value, e := r.provide(groups[1], groups[2])
+			if e != nil {
+				err = errors.Join(err, fmt.Errorf("%s:%s: %w", groups[1], groups[2], e))
+			}
+			return value
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	if !strings.HasPrefix(s, EncryptedPrefix) {
+		return s, nil
+	}
+	if r.Cipher == nil {
+		if r.Cipher, err = CipherFromEnv(); err != nil {
+			return "", err
+		}
+	}
+	return r.Cipher.Decrypt(s)
+}
+
+func (r *Resolver) provide(scheme string, ref string) (string, error) {
+	p, ok := r.Providers[scheme]
+	if !ok {
+		providersMu.RLock()
+		p, ok = providers[scheme]
+		providersMu.RUnlock()
+	}
+	if !ok {
+		return "", fmt.Errorf("unknown secret provider %q", scheme)
+	}
+	return p.Resolve(ref)
+}
+
+// CheckSecrets reports the fields tagged `log:"secret"` of the struct pointed to by v whose value is one of
+// WeakSecrets, such as a default password, or is empty unless tagged `log:"secret,allowempty"`. Only the values present
+// are checked, e.g. the servers configured, not the absent optional sections. It is meant to reject such
+// configurations in production.
+func CheckSecrets(v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("config: CheckSecrets of non-struct %T", v)
+	}
+	errs := Errors{}
+	checkSecrets(rv, "", &errs)
+	return errs.err()
+}
+
+func checkSecrets(v reflect.Value, path string, errs *Errors) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			checkSecrets(v.Elem(), path, errs)
+		}
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return
+		}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			fieldPath := join(path, fieldName(f))
+			if f.Anonymous {
+				fieldPath = path
+			}
+			field := v.Field(i)
+			if field.Kind() == reflect.String && redact.IsSecret(f) {
+				switch {
+				case field.String() == "":
+					if !redact.AllowsEmpty(f) {
+						errs.add(fieldPath, errors.New("is an empty secret, not allowed in production"))
+					}
+				case slices.ContainsFunc(WeakSecrets, func(weak string) bool { return strings.EqualFold(weak, field.String()) }):
+					errs.add(fieldPath, errors.New("is a weak secret, not allowed in production"))
+				}
+				continue
+			}
+			checkSecrets(field, fieldPath, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			checkSecrets(v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			checkSecrets(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key()), errs)
+		}
+	}
+}
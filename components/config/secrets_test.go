@@ -0,0 +1,151 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testCredentials struct {
+	Username string            `yaml:"Username"`
+	Password string            `yaml:"Password" log:"secret"`
+	Extra    map[string]string `yaml:"Extra,omitempty"`
+}
+
+type testSecrets struct {
+	Database testCredentials   `yaml:"Database"`
+	Caches   []testCredentials `yaml:"Caches,omitempty"`
+	URL      *string           `yaml:"URL,omitempty"`
+}
+
+func TestCipher(t *testing.T) {
+	c, err := NewCipher([]byte("0123456789abcdef0123456789abcdef"))
+	assert.NoError(t, err)
+	encrypted, err := c.Encrypt("s3cr3t")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encrypted, EncryptedPrefix))
+	other, _ := c.Encrypt("s3cr3t")
+	assert.NotEqual(t, encrypted, other, "the nonce is random")
+
+	plaintext, err := c.Decrypt(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cr3t", plaintext)
+
+	wrong, _ := NewCipher([]byte("fedcba9876543210fedcba9876543210"))
+	_, err = wrong.Decrypt(encrypted)
+	assert.ErrorContains(t, err, "cannot decrypt")
+	_, err = c.Decrypt("enc:AAAA")
+	assert.Error(t, err)
+	_, err = c.Decrypt("s3cr3t")
+	assert.Error(t, err)
+	_, err = NewCipher([]byte("short"))
+	assert.Error(t, err)
+}
+
+func TestCipherFromEnv(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef"))
+	t.Setenv(EnvSecretKey, "")
+	os.Unsetenv(EnvSecretKey)
+	_, err := CipherFromEnv()
+	assert.ErrorIs(t, err, ErrNoSecretKey)
+
+	filename := filepath.Join(t.TempDir(), "key")
+	assert.NoError(t, os.WriteFile(filename, []byte(key+"\n"), 0o600))
+	t.Setenv(EnvSecretKeyFile, filename)
+	_, err = CipherFromEnv()
+	assert.NoError(t, err)
+
+	t.Setenv(EnvSecretKey, "not base64!")
+	_, err = CipherFromEnv()
+	assert.ErrorContains(t, err, "secret key")
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	c, _ := NewCipher([]byte("0123456789abcdef"))
+	encrypted, _ := c.Encrypt("cache-password")
+	filename := filepath.Join(t.TempDir(), "password")
+	assert.NoError(t, os.WriteFile(filename, []byte("db-password\n"), 0o600))
+	t.Setenv("TEST_DB_USER", "app")
+
+	url := "redis://${vault:cache/url}"
+	s := testSecrets{
+		Database: testCredentials{Username: "${env:TEST_DB_USER}", Password: "${file:" + filename + "}"},
+		Caches:   []testCredentials{{Password: encrypted, Extra: map[string]string{"literal": "$${env:TEST_DB_USER}"}}},
+		URL:      &url,
+	}
+	r := Resolver{Cipher: c, Providers: map[string]Provider{
+		"vault": ProviderFunc(func(ref string) (string, error) { return "cache:6379", nil }),
+	}}
+	assert.NoError(t, r.Resolve(&s))
+	assert.Equal(t, "app", s.Database.Username)
+	assert.Equal(t, "db-password", s.Database.Password)
+	assert.Equal(t, "cache-password", s.Caches[0].Password)
+	assert.Equal(t, "${env:TEST_DB_USER}", s.Caches[0].Extra["literal"])
+	assert.Equal(t, "redis://cache:6379", *s.URL)
+
+	t.Run("errors", func(t *testing.T) {
+		s := testSecrets{
+			Database: testCredentials{Username: "${env:TEST_MISSING}", Password: "${unknown:x}"},
+			Caches:   []testCredentials{{Password: encrypted}},
+		}
+		t.Setenv(EnvSecretKey, "")
+		os.Unsetenv(EnvSecretKey)
+		err := ResolveSecrets(&s)
+		var errs Errors
+		assert.True(t, errors.As(err, &errs))
+		assert.Len(t, errs, 3)
+		assert.ErrorContains(t, err, "Database.Username: env:TEST_MISSING: environment variable TEST_MISSING is not set")
+		assert.ErrorContains(t, err, `Database.Password: unknown:x: unknown secret provider "unknown"`)
+		assert.ErrorIs(t, err, ErrNoSecretKey)
+	})
+
+	t.Run("registered provider", func(t *testing.T) {
+		RegisterProvider("test", ProviderFunc(func(ref string) (string, error) { return strings.ToUpper(ref), nil }))
+		s := testSecrets{Database: testCredentials{Password: "${test:secret}"}}
+		assert.NoError(t, ResolveSecrets(&s))
+		assert.Equal(t, "SECRET", s.Database.Password)
+	})
+}
+
+func TestCheckSecrets(t *testing.T) {
+	s := testSecrets{
+		Database: testCredentials{Username: "root", Password: "123456"},
+		Caches:   []testCredentials{{Password: "ChangeMe"}, {Password: "k8Jq-2mZ"}, {}},
+	}
+	err := CheckSecrets(&s)
+	assert.EqualError(t, err, "Database.Password: is a weak secret, not allowed in production\n"+
+		"Caches[0].Password: is a weak secret, not allowed in production\n"+
+		"Caches[2].Password: is an empty secret, not allowed in production")
+	s.Database.Password, s.Caches[0].Password = "", "r4nd0m"
+	assert.EqualError(t, CheckSecrets(s), "Database.Password: is an empty secret, not allowed in production\n"+
+		"Caches[2].Password: is an empty secret, not allowed in production")
+	s.Database.Password, s.Caches = "r4nd0m", nil
+	assert.NoError(t, CheckSecrets(s))
+
+	// A secret tagged allowempty may be empty, not weak.
+	optional := struct {
+		Token string `log:"secret,allowempty"`
+	}{}
+	assert.NoError(t, CheckSecrets(&optional))
+	optional.Token = "changeme"
+	assert.ErrorContains(t, CheckSecrets(&optional), "token: is a weak secret")
+}
+
+func TestLoader_Secrets(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(filename, []byte("Database:\n  Password: ${env:TEST_DB_PASSWORD}\n"), 0o600))
+	t.Setenv("TEST_DB_PASSWORD", "password")
+
+	var s testSecrets
+	l := Loader{Files: []string{filename}}
+	assert.NoError(t, l.Load(&s))
+	assert.Equal(t, "password", s.Database.Password)
+
+	l.Production = true
+	assert.ErrorContains(t, l.Load(&testSecrets{}), "Database.Password: is a weak secret")
+}
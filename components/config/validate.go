@@ -0,0 +1,186 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError is an error about the field at Path, e.g. `Redis[0].Weight`. Path is empty for errors about the whole
+// configuration.
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Errors aggregates the errors found in a configuration, one per line.
+type Errors []*FieldError
+
+func (e Errors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e Errors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+func (e *Errors) add(path string, err error) {
+	var errs Errors
+	if errors.As(err, &errs) {
+		*e = append(*e, errs...)
+		return
+	}
+	if fe, ok := err.(*FieldError); ok {
+		*e = append(*e, fe)
+		return
+	}
+	*e = append(*e, &FieldError{Path: path, Err: err})
+}
+
+// err returns nil if there is no error, so that a nil Errors is not returned as a non-nil error.
+func (e Errors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+var validate = newValidate()
+
+func newValidate() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		return fieldName(f)
+	})
+	return v
+}
+
+// Validate checks the `validate` rules of the struct pointed to by v and of all structs it contains, including the
+// elements of slices and the values of maps, and returns Errors whose paths are made of the YAML names of the fields.
+func Validate(v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("config: Validate of non-struct %T", v)
+	}
+	errs := Errors{}
+	validateValue(rv, "", &errs)
+	return errs.err()
+}
+
+func validateValue(v reflect.Value, path string, errs *Errors) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			validateValue(v.Elem(), path, errs)
+		}
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return
+		}
+		validateStruct(v, path, errs)
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			fieldPath := join(path, fieldName(f))
+			if f.Anonymous {
+				fieldPath = path
+			}
+			validateValue(v.Field(i), fieldPath, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			validateValue(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key()), errs)
+		}
+	}
+}
+
+// validateStruct checks the rules of the fields of the struct itself, the nested structs being checked by
+// validateValue so that every struct is checked once whatever its container.
+func validateStruct(v reflect.Value, path string, errs *Errors) {
+	if !v.CanAddr() {
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		v = copied
+	}
+	err := validate.StructFiltered(v.Addr().Interface(), func(ns []byte) bool {
+		// ns is `Type.Field` for the fields of the struct itself.
+		return strings.Count(string(ns), ".") > 1
+	})
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		if err != nil {
+			errs.add(path, err)
+		}
+		return
+	}
+	for _, fe := range validationErrors {
+		errs.add(join(path, fe.Field()), errors.New(message(fe)))
+	}
+}
+
+// message describes the failed rule.
+func message(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "oneof":
+		return "must be one of " + fe.Param()
+	case "min", "gte":
+		return "must be at least " + fe.Param()
+	case "max", "lte":
+		return "must be at most " + fe.Param()
+	}
+	if fe.Param() != "" {
+		return fmt.Sprintf("fails rule %s=%s", fe.Tag(), fe.Param())
+	}
+	return "fails rule " + fe.Tag()
+}
+
+// fieldName returns the YAML name of the field, its lower-cased name if untagged.
+func fieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	if name == "" || name == "-" {
+		return strings.ToLower(f.Name)
+	}
+	return name
+}
+
+func join(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,188 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const DefaultWatchInterval = 5 * time.Second
+
+// Subscriber applies a new configuration. Returning an error rejects it.
+type Subscriber[T any] func(old *T, new *T, changes []Change) error
+
+type subscription[T any] struct {
+	fn    Subscriber[T]
+	paths []string
+}
+
+// Watcher holds the current configuration loaded by a Loader, and reloads it when its files change.
+//
+// A reloaded configuration that fails to load or validate is discarded. Otherwise the subscribers interested in the
+// changes are notified in order; if one rejects the configuration, those already notified are notified again with the
+// previous configuration, and the previous configuration stays current.
+type Watcher[T any] struct {
+	loader *Loader
+	// OnError receives the errors of the reloads triggered by Run. Nil ignores them.
+	OnError func(error)
+
+	current atomic.Pointer[T]
+	// reloadMu serializes the reloads, mu guards the subscriptions, so that the subscribers may subscribe or unsubscribe.
+	reloadMu      sync.Mutex
+	fingerprint   [sha256.Size]byte
+	mu            sync.Mutex
+	subscriptions []*subscription[T]
+}
+
+// NewWatcher loads the initial configuration.
+func NewWatcher[T any](loader *Loader) (*Watcher[T], error) {
+	w := Watcher[T]{loader: loader}
+	fingerprint, err := w.fingerprintFiles()
+	if err != nil {
+		return nil, err
+	}
+	current := new(T)
+	if err := loader.Load(current); err != nil {
+		return nil, err
+	}
+	w.current.Store(current)
+	w.fingerprint = fingerprint
+	return &w, nil
+}
+
+// Current returns the current configuration. It must not be modified.
+func (w *Watcher[T]) Current() *T {
+	return w.current.Load()
+}
+
+// Subscribe adds a subscriber notified of the changes at or below the paths, e.g. `Redis`, or of any change if no path
+// is given. The returned function removes it.
+func (w *Watcher[T]) Subscribe(fn Subscriber[T], paths ...string) (unsubscribe func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s := &subscription[T]{fn: fn, paths: paths}
+	w.subscriptions = append(w.subscriptions, s)
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		for i, other := range w.subscriptions {
+			if other == s {
+				w.subscriptions = append(w.subscriptions[:i:i], w.subscriptions[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Reload loads the configuration again and, if it changed, notifies the subscribers. It returns the changes applied.
+func (w *Watcher[T]) Reload() ([]Change, error) {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+	fingerprint, err := w.fingerprintFiles()
+	if err != nil {
+		return nil, err
+	}
+	return w.reload(fingerprint)
+}
+
+func (w *Watcher[T]) reload(fingerprint [sha256.Size]byte) ([]Change, error) {
+	old := w.current.Load()
+	next := new(T)
+	if err := w.loader.Load(next); err != nil {
+		return nil, fmt.Errorf("reload: %w", err)
+	}
+	changes := Diff(old, next)
+	if len(changes) == 0 {
+		w.fingerprint = fingerprint
+		return changes, nil
+	}
+	w.mu.Lock()
+	subscriptions := append([]*subscription[T](nil), w.subscriptions...)
+	w.mu.Unlock()
+	for i, s := range subscriptions {
+		if !Changed(changes, s.paths...) {
+			continue
+		}
+		if err := s.fn(old, next, changes); err != nil {
+			w.rollback(subscriptions[:i], next, old)
+			return nil, fmt.Errorf("reload: %w", err)
+		}
+	}
+	w.current.Store(next)
+	w.fingerprint = fingerprint
+	return changes, nil
+}
+
+// rollback notifies the subscribers again, in reverse order, with the previous configuration.
+func (w *Watcher[T]) rollback(subscriptions []*subscription[T], rejected *T, previous *T) {
+	changes := Diff(rejected, previous)
+	for i := len(subscriptions) - 1; i >= 0; i-- {
+		if s := subscriptions[i]; Changed(changes, s.paths...) {
+			if err := s.fn(rejected, previous, changes); err != nil {
+				w.report(fmt.Errorf("rollback: %w", err))
+			}
+		}
+	}
+}
+
+// Run polls the files every interval, zero meaning DefaultWatchInterval, and reloads the configuration when their
+// content changes, until ctx is done.
+func (w *Watcher[T]) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.poll()
+		}
+	}
+}
+
+func (w *Watcher[T]) poll() {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+	fingerprint, err := w.fingerprintFiles()
+	if err != nil {
+		w.report(err)
+		return
+	}
+	if fingerprint == w.fingerprint {
+		return
+	}
+	if _, err := w.reload(fingerprint); err != nil {
+		// The fingerprint is kept, so that the files are reloaded again once fixed rather than on every tick.
+		w.fingerprint = fingerprint
+		w.report(err)
+	}
+}
+
+func (w *Watcher[T]) report(err error) {
+	if w.OnError != nil {
+		w.OnError(err)
+	}
+}
+
+// fingerprintFiles hashes the content of the files and their layers, which tells changes apart whatever the
+// resolution of the modification times, and whether a file is replaced or modified in place.
+func (w *Watcher[T]) fingerprintFiles() ([sha256.Size]byte, error) {
+	h := sha256.New()
+	err := w.loader.readFiles(func(filename string, data []byte) error {
+		_, _ = fmt.Fprintf(h, "%s\x00%d\x00", filename, len(data))
+		h.Write(data)
+		return nil
+	})
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum, nil
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rhosocial/go-rush-common/components/redact"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	old := testEnv{
+		Level:   "info",
+		Servers: []testServer{{Host: "a", Weight: 1}, {Host: "b", Weight: 1}},
+		Pools:   map[string]testPool{"rooms": {Limit: 1}, "games": {Limit: 1}},
+	}
+	new := testEnv{
+		Level:    "debug",
+		Servers:  []testServer{{Host: "a", Weight: 2}},
+		Optional: &testServer{Host: "c"},
+		Pools:    map[string]testPool{"rooms": {Limit: 1}, "lobbies": {Limit: 3}},
+	}
+	changes := Diff(&old, &new)
+	paths := make([]string, len(changes))
+	for i, change := range changes {
+		paths[i] = change.Path
+	}
+	assert.Equal(t, []string{"Level", "Servers[0].Weight", "Servers[1]", "Optional", "Pools[games]", "Pools[lobbies]"}, paths)
+	assert.Equal(t, Change{Path: "Level", Old: "info", New: "debug"}, changes[0])
+	assert.Nil(t, changes[2].New)
+	assert.Nil(t, changes[3].Old)
+	assert.Empty(t, Diff(&old, &old))
+
+	changes = Diff(&testCredentials{Password: "old"}, &testCredentials{Username: "root", Password: "new"})
+	assert.Equal(t, []Change{{Path: "Username", Old: "", New: "root"}, {Path: "Password", Old: redact.Mask, New: redact.Mask}}, changes)
+	assert.Equal(t, []Change{{Path: "Password", Old: redact.Mask, New: ""}}, Diff(&testCredentials{Password: "old"}, &testCredentials{}))
+	assert.Empty(t, Diff(&testCredentials{Password: "same"}, &testCredentials{Password: "same"}))
+
+	changes = Diff(&old, &new)
+	assert.True(t, Changed(changes, "Servers"))
+	assert.True(t, Changed(changes, "Servers[0].Weight"))
+	assert.False(t, Changed(changes, "Serv", "Name"))
+	assert.True(t, Changed(changes))
+	assert.False(t, Changed(nil))
+}
+
+func TestWatcher(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(content string) {
+		assert.NoError(t, os.WriteFile(filename, []byte(content), 0o600))
+	}
+	write("Level: info\nServers:\n  - Host: a\n")
+	w, err := NewWatcher[testEnv](&Loader{Files: []string{filename}})
+	assert.NoError(t, err)
+	assert.Equal(t, "info", w.Current().Level)
+
+	t.Run("subscribers", func(t *testing.T) {
+		var notified []string
+		unsubscribe := w.Subscribe(func(old, new *testEnv, changes []Change) error {
+			notified = append(notified, old.Level+"->"+new.Level)
+			return nil
+		}, "Level")
+		w.Subscribe(func(old, new *testEnv, changes []Change) error {
+			notified = append(notified, "servers")
+			return nil
+		}, "Servers")
+
+		write("Level: debug\nServers:\n  - Host: a\n")
+		changes, err := w.Reload()
+		assert.NoError(t, err)
+		assert.Len(t, changes, 1)
+		assert.Equal(t, "debug", w.Current().Level)
+		assert.Equal(t, []string{"info->debug"}, notified)
+
+		unsubscribe()
+		write("Level: warn\nServers:\n  - Host: b\n")
+		_, err = w.Reload()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"info->debug", "servers"}, notified)
+	})
+
+	t.Run("subscribing subscribers", func(t *testing.T) {
+		var notified []string
+		var unsubscribe, unsubscribeSecond func()
+		defer func() { unsubscribeSecond() }()
+		unsubscribe = w.Subscribe(func(old, new *testEnv, changes []Change) error {
+			// Subscribing and unsubscribing from a subscriber take effect on the next reload.
+			unsubscribe()
+			unsubscribeSecond = w.Subscribe(func(old, new *testEnv, changes []Change) error {
+				notified = append(notified, "second "+new.Level)
+				return nil
+			}, "Level")
+			notified = append(notified, "first "+new.Level)
+			return nil
+		}, "Level")
+
+		write("Level: error\nServers:\n  - Host: b\n")
+		_, err := w.Reload()
+		assert.NoError(t, err)
+		write("Level: info\nServers:\n  - Host: b\n")
+		_, err = w.Reload()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"first error", "second info"}, notified)
+	})
+
+	t.Run("invalid configuration", func(t *testing.T) {
+		current := w.Current()
+		write("Level: verbose\n")
+		_, err := w.Reload()
+		assert.ErrorContains(t, err, "Level: must be one of")
+		assert.Same(t, current, w.Current())
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		write("Level: warn\nServers:\n  - Host: b\n")
+		_, _ = w.Reload()
+		current := w.Current()
+		var applied []string
+		unsubscribeFirst := w.Subscribe(func(old, new *testEnv, changes []Change) error {
+			applied = append(applied, new.Level)
+			return nil
+		})
+		unsubscribeSecond := w.Subscribe(func(old, new *testEnv, changes []Change) error {
+			return errors.New("rejected")
+		})
+		defer unsubscribeFirst()
+		defer unsubscribeSecond()
+
+		write("Level: error\nServers:\n  - Host: b\n")
+		_, err := w.Reload()
+		assert.ErrorContains(t, err, "rejected")
+		assert.Equal(t, []string{"error", "warn"}, applied)
+		assert.Same(t, current, w.Current())
+	})
+}
+
+func TestWatcher_Run(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(filename, []byte("Level: info\n"), 0o600))
+	w, err := NewWatcher[testEnv](&Loader{Files: []string{filename}})
+	assert.NoError(t, err)
+	var errs atomic.Int32
+	w.OnError = func(error) {
+		errs.Add(1)
+	}
+	var reloads atomic.Int32
+	w.Subscribe(func(old, new *testEnv, changes []Change) error {
+		reloads.Add(1)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx, 10*time.Millisecond)
+
+	assert.NoError(t, os.WriteFile(filename, []byte("Level: verbose\n"), 0o600))
+	assert.Eventually(t, func() bool { return errs.Load() == 1 }, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), errs.Load(), "an invalid file is reported once")
+
+	assert.NoError(t, os.WriteFile(filename, []byte("Level: debug\n"), 0o600))
+	assert.Eventually(t, func() bool { return w.Current().Level == "debug" }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(1), reloads.Load())
+}
@@ -5,6 +5,8 @@ import (
 	"github.com/rhosocial/go-rush-common/components/response"
 )
 
+// Base registers its routes itself. Prefer implementing Controller and registering through a Registry, which checks
+// for route conflicts and lists the routes.
 type Base interface {
 	RegisterActions(r *gin.Engine)
 }
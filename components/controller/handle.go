@@ -0,0 +1,190 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/rhosocial/go-rush-common/components/response"
+)
+
+// The codes of the response envelopes rendered by Handle.
+const (
+	CodeSuccess        uint32 = 0
+	CodeInvalidRequest uint32 = 1
+	CodeInternalError  uint32 = 2
+)
+
+// MessageSuccess is the message of successful responses rendered by Handle.
+const MessageSuccess = "ok"
+
+// Error is an error carrying the HTTP status and the envelope code and message to render.
+type Error struct {
+	Status  int
+	Code    uint32
+	Message string
+	// Err is the underlying error. It is attached to the context, but not rendered.
+	Err error
+}
+
+// NewError creates an Error.
+func NewError(status int, code uint32, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// WrapError creates an Error rendering the message of err.
+func WrapError(status int, code uint32, err error) *Error {
+	return &Error{Status: status, Code: code, Message: err.Error(), Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil && e.Err.Error() != e.Message {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// HandlerFunc is a typed handler. The context is the *gin.Context of the request, so that loggers and tracers can get
+// the request-scoped values from it.
+type HandlerFunc[Req any, Resp any] func(ctx context.Context, req Req) (Resp, error)
+
+// Handle adapts a typed handler to gin.
+//
+// The request is bound from the sources its fields are tagged for, in this order: `header`, `form` (query), the body
+// according to its content type, then `uri` (path parameters, which thus take precedence); the `binding` rules are
+// validated once all sources are bound. A binding failure renders 400 with CodeInvalidRequest.
+//
+// The result is rendered as a response.Generic[Resp, any] with CodeSuccess, with the status set by c.Status if any,
+// 200 otherwise. Nothing is rendered if the handler has written the response itself.
+//
+// An *Error (or an error wrapping one) is rendered as it says. Any other error is attached to the context and renders
+// 500 with CodeInternalError and a generic message, so that internal details do not leak.
+func Handle[Req any, Resp any](fn HandlerFunc[Req, Resp]) gin.HandlerFunc {
+	sources := sourcesOf(reflect.TypeFor[Req]())
+	return func(c *gin.Context) {
+		var req Req
+		if err := bind(c, &req, sources); err != nil {
+			_ = c.Error(err).SetType(gin.ErrorTypeBind)
+			renderError(c, WrapError(http.StatusBadRequest, CodeInvalidRequest, err))
+			return
+		}
+		resp, err := fn(c, req)
+		if err != nil {
+			renderError(c, err)
+			return
+		}
+		if c.Writer.Written() {
+			return
+		}
+		c.JSON(c.Writer.Status(), response.NewGeneric[Resp, any](c, CodeSuccess, MessageSuccess, resp, nil))
+	}
+}
+
+// NewAction creates an Action handling the route with Handle(fn), and records the request and response types for
+// documentation.
+func NewAction[Req any, Resp any](method string, path string, name string, fn HandlerFunc[Req, Resp]) Action {
+	return Action{
+		Method:   method,
+		Path:     path,
+		Name:     name,
+		Handler:  Handle(fn),
+		Request:  reflect.TypeFor[Req](),
+		Response: reflect.TypeFor[Resp](),
+	}
+}
+
+func renderError(c *gin.Context, err error) {
+	var e *Error
+	if !errors.As(err, &e) {
+		_ = c.Error(err)
+		e = NewError(http.StatusInternalServerError, CodeInternalError, http.StatusText(http.StatusInternalServerError))
+	} else if e.Err != nil && e.Status >= http.StatusInternalServerError {
+		_ = c.Error(e.Err)
+	}
+	c.AbortWithStatusJSON(e.Status, response.NewGeneric[any, any](c, e.Code, e.Message, nil, nil))
+}
+
+// sources tells which parts of the request a type is bound from.
+type sources struct {
+	header bool
+	query  bool
+	uri    bool
+}
+
+func sourcesOf(t reflect.Type) sources {
+	s := sources{}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return s
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if _, ok := field.Tag.Lookup("header"); ok {
+			s.header = true
+		}
+		if _, ok := field.Tag.Lookup("form"); ok {
+			s.query = true
+		}
+		if _, ok := field.Tag.Lookup("uri"); ok {
+			s.uri = true
+		}
+		ft := field.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if field.Anonymous && ft.Kind() == reflect.Struct {
+			nested := sourcesOf(ft)
+			s.header, s.query, s.uri = s.header || nested.header, s.query || nested.query, s.uri || nested.uri
+		}
+	}
+	return s
+}
+
+// bind binds every source, ignoring the validation errors until all of them are bound.
+func bind(c *gin.Context, req any, s sources) error {
+	steps := make([]func() error, 0, 4)
+	if s.header {
+		steps = append(steps, func() error { return c.ShouldBindHeader(req) })
+	}
+	if s.query {
+		steps = append(steps, func() error { return c.ShouldBindQuery(req) })
+	}
+	if hasBody(c.Request) {
+		steps = append(steps, func() error {
+			return c.ShouldBindWith(req, binding.Default(c.Request.Method, c.ContentType()))
+		})
+	}
+	if s.uri && len(c.Params) > 0 {
+		steps = append(steps, func() error { return c.ShouldBindUri(req) })
+	}
+	for _, step := range steps {
+		if err := step(); err != nil && !isValidationError(err) {
+			return err
+		}
+	}
+	if binding.Validator == nil {
+		return nil
+	}
+	return binding.Validator.ValidateStruct(req)
+}
+
+func hasBody(r *http.Request) bool {
+	return r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0
+}
+
+func isValidationError(err error) bool {
+	var validationErrors validator.ValidationErrors
+	var sliceErrors binding.SliceValidationError
+	return errors.As(err, &validationErrors) || errors.As(err, &sliceErrors)
+}
@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rhosocial/go-rush-common/components/response"
+	"github.com/stretchr/testify/assert"
+)
+
+type Pagination struct {
+	Page uint32 `form:"page,default=1" binding:"min=1"`
+}
+
+type updateUserRequest struct {
+	Pagination
+	ID      uint64 `uri:"id" binding:"required"`
+	Token   string `header:"X-Token" binding:"required"`
+	Name    string `json:"name" binding:"required,max=8"`
+	Verbose bool   `form:"verbose"`
+}
+
+type user struct {
+	ID   uint64 `json:"id"`
+	Name string `json:"name"`
+}
+
+var errUserNotFound = NewError(http.StatusNotFound, 1001, "user not found")
+
+func setupRouterHandle() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.PUT("/users/:id", Handle(func(ctx context.Context, req updateUserRequest) (*user, error) {
+		switch req.ID {
+		case 404:
+			return nil, fmt.Errorf("updating: %w", errUserNotFound)
+		case 500:
+			return nil, errors.New("connection refused by 10.0.0.1")
+		case 201:
+			ctx.(*gin.Context).Status(http.StatusCreated)
+		}
+		return &user{ID: req.ID, Name: fmt.Sprintf("%s/%d/%t", req.Name, req.Page, req.Verbose)}, nil
+	}))
+	r.GET("/ping", Handle(func(ctx context.Context, req struct{}) (string, error) {
+		return "pong", nil
+	}))
+	return r
+}
+
+func request(r *gin.Engine, method string, path string, token string, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	if body == "" {
+		req.Body = http.NoBody
+		req.ContentLength = 0
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("X-Token", token)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandle(t *testing.T) {
+	r := setupRouterHandle()
+
+	t.Run("success", func(t *testing.T) {
+		w := request(r, http.MethodPut, "/users/1?page=3&verbose=true", "secret", `{"name":"alice"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp response.Generic[user, any]
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, CodeSuccess, resp.Code)
+		assert.Equal(t, MessageSuccess, resp.Message)
+		assert.Equal(t, user{ID: 1, Name: "alice/3/true"}, resp.Data)
+	})
+
+	t.Run("defaults and status", func(t *testing.T) {
+		w := request(r, http.MethodPut, "/users/201", "secret", `{"name":"alice"}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var resp response.Generic[user, any]
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "alice/1/false", resp.Data.Name)
+	})
+
+	t.Run("no request fields", func(t *testing.T) {
+		w := request(r, http.MethodGet, "/ping", "", "")
+		var resp response.Generic[string, any]
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "pong", resp.Data)
+	})
+
+	t.Run("binding failures", func(t *testing.T) {
+		for name, w := range map[string]*httptest.ResponseRecorder{
+			"missing header":   request(r, http.MethodPut, "/users/1", "", `{"name":"alice"}`),
+			"missing body":     request(r, http.MethodPut, "/users/1", "secret", ""),
+			"invalid body":     request(r, http.MethodPut, "/users/1", "secret", `{"name":`),
+			"invalid rule":     request(r, http.MethodPut, "/users/1", "secret", `{"name":"too long name"}`),
+			"invalid path":     request(r, http.MethodPut, "/users/abc", "secret", `{"name":"alice"}`),
+			"invalid query":    request(r, http.MethodPut, "/users/1?page=0", "secret", `{"name":"alice"}`),
+			"zero path number": request(r, http.MethodPut, "/users/0", "secret", `{"name":"alice"}`),
+		} {
+			assert.Equal(t, http.StatusBadRequest, w.Code, name)
+			var resp response.Base
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), name)
+			assert.Equal(t, CodeInvalidRequest, resp.Code, name)
+			assert.NotEmpty(t, resp.Message, name)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		w := request(r, http.MethodPut, "/users/404", "secret", `{"name":"alice"}`)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		var resp response.Base
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, uint32(1001), resp.Code)
+		assert.Equal(t, "user not found", resp.Message)
+
+		w = request(r, http.MethodPut, "/users/500", "secret", `{"name":"alice"}`)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, CodeInternalError, resp.Code)
+		assert.NotContains(t, w.Body.String(), "10.0.0.1")
+	})
+}
+
+func TestError(t *testing.T) {
+	cause := errors.New("duplicated key")
+	err := WrapError(http.StatusConflict, 1002, cause)
+	assert.Equal(t, "duplicated key", err.Error())
+	assert.ErrorIs(t, err, cause)
+
+	err = &Error{Status: http.StatusConflict, Code: 1002, Message: "user exists", Err: cause}
+	assert.Equal(t, "user exists: duplicated key", err.Error())
+	assert.Equal(t, "user not found", errUserNotFound.Error())
+}
@@ -0,0 +1,336 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rhosocial/go-rush-common/components/response"
+)
+
+// Action is one route of a controller.
+type Action struct {
+	Method string
+	// Path is relative to the base path of the controller. Empty means the base path itself.
+	Path string
+	// Name identifies the action in the route listing, e.g. `getUser`. It is the OpenAPI operation ID.
+	Name string
+	// Summary and Description document the action.
+	Summary     string
+	Description string
+	// Middlewares run before the handler, after the middlewares of the controller.
+	Middlewares []gin.HandlerFunc
+	Handler     gin.HandlerFunc
+	// Request and Response are the types the handler binds and renders as data, if known. NewAction sets them.
+	Request  reflect.Type
+	Response reflect.Type
+	// Versions restricts the action to some versions of the controller, by name. Empty means all of them.
+	Versions []string
+}
+
+// Definition declares the routes of a controller.
+type Definition struct {
+	// Name identifies the controller in the route listing and in errors. Empty means its type name.
+	Name string
+	// BasePath prefixes the paths of all actions, e.g. `/users`.
+	BasePath string
+	// Version, if set, prefixes the base path, e.g. `v1` gives `/v1/users`. It is ignored if Versions is set.
+	Version string
+	// Versions are the versions the actions are registered under, side by side, e.g. `/v1/users` and `/v2/users`.
+	// The last one is the default of Registry.Negotiate.
+	Versions []Version
+	// Middlewares run before every action of the controller, e.g. authentication or rate limiting.
+	Middlewares []gin.HandlerFunc
+	Actions     []Action
+}
+
+// Controller declares its routes, to be registered by a Registry.
+type Controller interface {
+	Definition() Definition
+}
+
+// Validator is implemented by the controllers checking their configuration, rejected by Registry.Register on error.
+type Validator interface {
+	Validate() error
+}
+
+// Route describes a registered route.
+type Route struct {
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	Controller string `json:"controller"`
+	Action     string `json:"action,omitempty"`
+	Version    string `json:"version,omitempty"`
+	Deprecated bool   `json:"deprecated,omitempty"`
+	Summary    string `json:"summary,omitempty"`
+	// Description, Request and Response come from the Action, for documentation.
+	Description string       `json:"-"`
+	Request     reflect.Type `json:"-"`
+	Response    reflect.Type `json:"-"`
+}
+
+// RouteConflictError reports two routes that cannot be registered together.
+type RouteConflictError struct {
+	Route Route
+	// Existing is the route it conflicts with. Its Controller is empty if it was registered outside the Registry.
+	Existing Route
+	// Reason is the message of gin, for conflicts other than duplicated routes, e.g. different wildcard names.
+	Reason string
+}
+
+func (e *RouteConflictError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("controller %s: route %s %s conflicts: %s", e.Route.Controller, e.Route.Method, e.Route.Path, e.Reason)
+	}
+	return fmt.Sprintf("controller %s: route %s %s is already registered by %s", e.Route.Controller, e.Route.Method,
+		e.Route.Path, nameOrExternal(e.Existing.Controller))
+}
+
+func nameOrExternal(name string) string {
+	if name == "" {
+		return "another handler"
+	}
+	return "controller " + name
+}
+
+// Registry registers controllers, and lists their routes.
+type Registry struct {
+	controllers []Controller
+	// registered is the number of controllers registered by Register, serialized by registerMu.
+	registered int
+	registerMu sync.Mutex
+	routes     []Route
+	mu         sync.RWMutex
+	mounts     []mount
+}
+
+// NewRegistry creates a Registry of the controllers.
+func NewRegistry(controllers ...Controller) *Registry {
+	return &Registry{controllers: controllers}
+}
+
+// Add adds controllers to be registered by the next call to Register.
+func (r *Registry) Add(controllers ...Controller) *Registry {
+	r.mu.Lock()
+	r.controllers = append(r.controllers, controllers...)
+	r.mu.Unlock()
+	return r
+}
+
+type pendingRoute struct {
+	Route
+	relativePath string
+	group        groupKey
+	handlers     gin.HandlersChain
+}
+
+// groupKey identifies the router group of a version of a controller.
+type groupKey struct {
+	definition *Definition
+	version    string
+}
+
+// Register registers the routes of the controllers added since the last successful call on router, a *gin.Engine or a
+// *gin.RouterGroup, so that a registry grows with Add. A controller is registered once: to serve the same controllers
+// on two routers, use two registries.
+//
+// All routes are checked before any is registered: if two routes conflict, with each other or with a route already
+// registered on the engine, a *RouteConflictError is returned and router is left untouched. A *gin.RouterGroup does
+// not expose the routes of its engine, so a conflict with a route registered outside the Registry is only detected
+// when registering it on the group: the error is the same, but the routes before it are left registered.
+func (r *Registry) Register(router gin.IRouter) error {
+	prefix := "/"
+	var existing []gin.RouteInfo
+	switch v := router.(type) {
+	case *gin.Engine:
+		existing = v.Routes()
+	case *gin.RouterGroup:
+		prefix = v.BasePath()
+	}
+	r.registerMu.Lock()
+	defer r.registerMu.Unlock()
+	r.mu.RLock()
+	controllers := r.controllers[r.registered:]
+	r.mu.RUnlock()
+	pending := make([]pendingRoute, 0)
+	mounts := make([]mount, 0)
+	definitions := make([]Definition, len(controllers))
+	for i, c := range controllers {
+		definitions[i] = c.Definition()
+		d := &definitions[i]
+		if d.Name == "" {
+			d.Name = typeName(c)
+		}
+		if v, ok := c.(Validator); ok {
+			if err := v.Validate(); err != nil {
+				return fmt.Errorf("controller %s: %w", d.Name, err)
+			}
+		}
+		versions := versionsOf(d)
+		for _, v := range versions {
+			if v.Deprecated && v.DeprecatedAt.IsZero() {
+				return fmt.Errorf("controller %s: deprecated version %s has no DeprecatedAt", d.Name, v.Name)
+			}
+		}
+		if len(versions) > 0 {
+			mounts = append(mounts, mount{prefix: prefix, basePath: d.BasePath, versions: versions})
+		} else {
+			versions = []Version{{}}
+		}
+		for _, a := range d.Actions {
+			if a.Handler == nil {
+				return fmt.Errorf("controller %s: action %s %s has no handler", d.Name, a.Method, a.Path)
+			}
+			for _, name := range a.Versions {
+				if !slices.ContainsFunc(versions, func(v Version) bool { return v.Name == name }) {
+					return fmt.Errorf("controller %s: action %s %s has unknown version %s", d.Name, a.Method, a.Path, name)
+				}
+			}
+			for _, v := range versions {
+				if len(a.Versions) > 0 && !slices.Contains(a.Versions, v.Name) {
+					continue
+				}
+				relativePath := joinPaths(v.Name, d.BasePath, a.Path)
+				handlers := append(append(gin.HandlersChain{}, a.Middlewares...), a.Handler)
+				pending = append(pending, pendingRoute{
+					Route: Route{
+						Method:      strings.ToUpper(a.Method),
+						Path:        joinPaths(prefix, relativePath),
+						Controller:  d.Name,
+						Action:      a.Name,
+						Version:     v.Name,
+						Deprecated:  v.Deprecated,
+						Summary:     a.Summary,
+						Description: a.Description,
+						Request:     a.Request,
+						Response:    a.Response,
+					},
+					relativePath: relativePath,
+					group:        groupKey{definition: d, version: v.Name},
+					handlers:     handlers,
+				})
+			}
+		}
+	}
+	r.mu.RLock()
+	registered := append([]Route{}, r.routes...)
+	r.mu.RUnlock()
+	if err := checkConflicts(existing, registered, routesOf(pending)); err != nil {
+		return err
+	}
+	groups := make(map[groupKey]*gin.RouterGroup)
+	for _, p := range pending {
+		group, ok := groups[p.group]
+		if !ok {
+			middlewares := p.group.definition.Middlewares
+			if p.group.version != "" {
+				// The version comes first, so that the deprecation is advertised even if a middleware aborts.
+				version, _ := mount{versions: versionsOf(p.group.definition)}.find(p.group.version)
+				middlewares = append([]gin.HandlerFunc{versionMiddleware(version)}, middlewares...)
+			}
+			group = router.Group("", middlewares...)
+			groups[p.group] = group
+		}
+		if err := tryHandle(group, p.Route, p.relativePath, p.handlers...); err != nil {
+			return err
+		}
+		r.mu.Lock()
+		r.routes = append(r.routes, p.Route)
+		r.mu.Unlock()
+	}
+	r.addMounts(mounts)
+	r.registered += len(controllers)
+	return nil
+}
+
+func routesOf(pending []pendingRoute) []Route {
+	routes := make([]Route, len(pending))
+	for i, p := range pending {
+		routes[i] = p.Route
+	}
+	return routes
+}
+
+// checkConflicts registers the routes on a scratch engine after the existing ones of the engine and the ones registered
+// by the registry before, which may be among them, turning the panics of gin into errors.
+func checkConflicts(existing []gin.RouteInfo, before []Route, routes []Route) error {
+	registered := make(map[string]Route, len(existing)+len(before)+len(routes))
+	engine := gin.New()
+	noop := func(*gin.Context) {}
+	for _, info := range existing {
+		registered[info.Method+" "+info.Path] = Route{Method: info.Method, Path: info.Path}
+		engine.Handle(info.Method, info.Path, noop)
+	}
+	for _, route := range before {
+		// The routes registered on another router are not among the existing ones.
+		if _, ok := registered[route.Method+" "+route.Path]; !ok {
+			engine.Handle(route.Method, route.Path, noop)
+		}
+		registered[route.Method+" "+route.Path] = route
+	}
+	for _, route := range routes {
+		if previous, ok := registered[route.Method+" "+route.Path]; ok {
+			return &RouteConflictError{Route: route, Existing: previous}
+		}
+		if err := tryHandle(engine, route, route.Path, noop); err != nil {
+			return err
+		}
+		registered[route.Method+" "+route.Path] = route
+	}
+	return nil
+}
+
+// tryHandle registers the route at relativePath on router, turning the panic of gin on a conflict into an error.
+func tryHandle(router gin.IRoutes, route Route, relativePath string, handlers ...gin.HandlerFunc) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &RouteConflictError{Route: route, Reason: fmt.Sprint(v)}
+		}
+	}()
+	router.Handle(route.Method, relativePath, handlers...)
+	return nil
+}
+
+// Routes returns the routes registered so far, sorted by path and method.
+func (r *Registry) Routes() []Route {
+	r.mu.RLock()
+	routes := append([]Route{}, r.routes...)
+	r.mu.RUnlock()
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// RoutesHandler returns a handler listing the registered routes, for introspection.
+// It should be registered on a protected group.
+func (r *Registry) RoutesHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, response.NewGeneric[[]Route, any](c, 0, "ok", r.Routes(), nil))
+	}
+}
+
+func joinPaths(paths ...string) string {
+	joined := path.Join(append([]string{"/"}, paths...)...)
+	if last := paths[len(paths)-1]; strings.HasSuffix(last, "/") && joined != "/" {
+		joined += "/"
+	}
+	return joined
+}
+
+func typeName(c Controller) string {
+	t := reflect.TypeOf(c)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
@@ -0,0 +1,180 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rhosocial/go-rush-common/components/response"
+	"github.com/stretchr/testify/assert"
+)
+
+type userController struct {
+	GenericController
+}
+
+func (u *userController) Definition() Definition {
+	return Definition{
+		BasePath: "/users",
+		Version:  "v1",
+		Middlewares: []gin.HandlerFunc{func(c *gin.Context) {
+			c.Header("X-Controller", "user")
+		}},
+		Actions: []Action{
+			{Method: http.MethodGet, Path: "", Name: "listUsers", Handler: func(c *gin.Context) {
+				c.JSON(http.StatusOK, u.NewResponseBase(c, 0, "list"))
+			}},
+			{Method: http.MethodGet, Path: "/:id", Name: "getUser", Handler: func(c *gin.Context) {
+				c.JSON(http.StatusOK, u.NewResponseBase(c, 0, c.Param("id")))
+			}},
+			{Method: http.MethodDelete, Path: "/:id", Name: "deleteUser", Middlewares: []gin.HandlerFunc{func(c *gin.Context) {
+				c.AbortWithStatus(http.StatusForbidden)
+			}}, Handler: func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			}},
+		},
+	}
+}
+
+type definitionController Definition
+
+func (d definitionController) Definition() Definition {
+	return Definition(d)
+}
+
+func serve(r *gin.Engine, method string, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, nil)
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func noop(c *gin.Context) {}
+
+func TestRegistry_Register(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("engine", func(t *testing.T) {
+		r := gin.New()
+		health := definitionController{Name: "health", Actions: []Action{{Method: http.MethodGet, Path: "/health", Handler: noop}}}
+		registry := NewRegistry(&userController{}).Add(health)
+		assert.NoError(t, registry.Register(r))
+
+		w := serve(r, http.MethodGet, "/v1/users/42")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "user", w.Header().Get("X-Controller"))
+		var resp response.Base
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "42", resp.Message)
+
+		assert.Equal(t, http.StatusOK, serve(r, http.MethodGet, "/v1/users").Code)
+		assert.Equal(t, http.StatusForbidden, serve(r, http.MethodDelete, "/v1/users/42").Code)
+		assert.Empty(t, serve(r, http.MethodGet, "/health").Header().Get("X-Controller"))
+
+		assert.Equal(t, []Route{
+			{Method: http.MethodGet, Path: "/health", Controller: "health"},
+			{Method: http.MethodGet, Path: "/v1/users", Controller: "userController", Action: "listUsers", Version: "v1"},
+			{Method: http.MethodDelete, Path: "/v1/users/:id", Controller: "userController", Action: "deleteUser", Version: "v1"},
+			{Method: http.MethodGet, Path: "/v1/users/:id", Controller: "userController", Action: "getUser", Version: "v1"},
+		}, registry.Routes())
+	})
+
+	t.Run("router group", func(t *testing.T) {
+		r := gin.New()
+		registry := NewRegistry(&userController{})
+		assert.NoError(t, registry.Register(r.Group("/api")))
+		assert.Equal(t, http.StatusOK, serve(r, http.MethodGet, "/api/v1/users/42").Code)
+		assert.Equal(t, "/api/v1/users/:id", registry.Routes()[2].Path)
+	})
+
+	t.Run("duplicated route", func(t *testing.T) {
+		r := gin.New()
+		other := definitionController{Name: "other", BasePath: "/v1/users", Actions: []Action{
+			{Method: http.MethodGet, Path: "/:id", Handler: noop},
+		}}
+		err := NewRegistry(&userController{}, other).Register(r)
+		var conflict *RouteConflictError
+		assert.True(t, errors.As(err, &conflict))
+		assert.Equal(t, "other", conflict.Route.Controller)
+		assert.Equal(t, "userController", conflict.Existing.Controller)
+		assert.Empty(t, r.Routes(), "nothing should be registered")
+	})
+
+	t.Run("wildcard conflict", func(t *testing.T) {
+		r := gin.New()
+		other := definitionController{Name: "other", Version: "v1", BasePath: "/users", Actions: []Action{
+			{Method: http.MethodGet, Path: "/:name/posts", Handler: noop},
+		}}
+		err := NewRegistry(&userController{}, other).Register(r)
+		var conflict *RouteConflictError
+		assert.True(t, errors.As(err, &conflict))
+		assert.NotEmpty(t, conflict.Reason)
+		assert.Empty(t, r.Routes())
+	})
+
+	t.Run("conflict with an existing route", func(t *testing.T) {
+		r := gin.New()
+		r.GET("/v1/users", noop)
+		err := NewRegistry(&userController{}).Register(r)
+		var conflict *RouteConflictError
+		assert.True(t, errors.As(err, &conflict))
+		assert.Contains(t, conflict.Error(), "another handler")
+	})
+
+	t.Run("conflict with an existing route outside the group", func(t *testing.T) {
+		r := gin.New()
+		r.GET("/api/v1/users/:id", noop)
+		registry := NewRegistry(&userController{})
+		err := registry.Register(r.Group("/api"))
+		var conflict *RouteConflictError
+		assert.True(t, errors.As(err, &conflict))
+		assert.Equal(t, "/api/v1/users/:id", conflict.Route.Path)
+		assert.Contains(t, conflict.Reason, "already registered")
+		// The routes before it are left registered.
+		if assert.Len(t, registry.Routes(), 1) {
+			assert.Equal(t, "/api/v1/users", registry.Routes()[0].Path)
+		}
+	})
+
+	t.Run("added controllers", func(t *testing.T) {
+		r := gin.New()
+		registry := NewRegistry(&userController{})
+		assert.NoError(t, registry.Register(r))
+		// The controllers registered already are not registered again.
+		assert.NoError(t, registry.Register(r))
+		assert.NoError(t, registry.Add(definitionController{
+			Name: "groups", BasePath: "/groups", Actions: []Action{{Method: http.MethodGet, Handler: noop}},
+		}).Register(r))
+		assert.Len(t, registry.Routes(), 4)
+		assert.Len(t, r.Routes(), 4)
+		assert.Equal(t, http.StatusOK, serve(r, http.MethodGet, "/groups").Code)
+
+		// An added controller conflicting with a registered one is rejected, and can be retried after it.
+		err := registry.Add(&userController{}).Register(r.Group(""))
+		var conflict *RouteConflictError
+		assert.True(t, errors.As(err, &conflict))
+		assert.Equal(t, "userController", conflict.Existing.Controller)
+		assert.Len(t, registry.Routes(), 4)
+	})
+
+	t.Run("missing handler", func(t *testing.T) {
+		err := NewRegistry(definitionController{Actions: []Action{{Method: http.MethodGet, Path: "/"}}}).Register(gin.New())
+		assert.Error(t, err)
+	})
+}
+
+func TestRegistry_RoutesHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	registry := NewRegistry(&userController{})
+	assert.NoError(t, registry.Register(r))
+	r.GET("/routes", registry.RoutesHandler())
+
+	w := serve(r, http.MethodGet, "/routes")
+	var resp response.Generic[[]Route, any]
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Data, 3)
+}
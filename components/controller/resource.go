@@ -0,0 +1,401 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/rhosocial/go-rush-common/components/response"
+)
+
+// The codes of the errors returned by repositories, rendered by Resource.
+const (
+	CodeNotFound uint32 = 3
+	CodeConflict uint32 = 4
+)
+
+// Repository errors. Wrap them to add details.
+var (
+	ErrNotFound = errors.New("resource not found")
+	ErrConflict = errors.New("resource conflict")
+)
+
+const (
+	DefaultPageSize uint32 = 20
+	DefaultMaxSize  uint32 = 100
+)
+
+// MIMEMergePatch is the content type of JSON Merge Patch (RFC 7396) documents. PATCH also accepts application/json.
+const MIMEMergePatch = "application/merge-patch+json"
+
+// SortField is one sort criterion, written `field` (ascending) or `-field` (descending) in the query.
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+// ListQuery is the parsed query of a list request.
+type ListQuery struct {
+	// Page starts at 1.
+	Page uint32
+	Size uint32
+	Sort []SortField
+	// Filters holds the values of the allowed filter fields present in the query, e.g. `?status=active`.
+	Filters map[string]string
+}
+
+// Offset returns the number of items preceding the page.
+func (q ListQuery) Offset() uint64 {
+	return uint64(q.Page-1) * uint64(q.Size)
+}
+
+// Repository stores the items of a Resource. Get, Update and Delete return an error wrapping ErrNotFound if the item
+// does not exist, and Create and Update an error wrapping ErrConflict if the item clashes with another one.
+type Repository[T any, ID comparable] interface {
+	// List returns the items of the page, and the total number of items matching the filters.
+	List(ctx context.Context, query ListQuery) ([]T, uint64, error)
+	Get(ctx context.Context, id ID) (T, error)
+	Create(ctx context.Context, item T) (T, error)
+	// Update replaces the item. From a PATCH, the fields the JSON document of the item lacks keep the values from Get.
+	Update(ctx context.Context, id ID, item T) (T, error)
+	Delete(ctx context.Context, id ID) error
+}
+
+// Page is the data of a list response.
+type Page[T any] struct {
+	Items []T    `json:"items"`
+	Total uint64 `json:"total"`
+	Page  uint32 `json:"page"`
+	Size  uint32 `json:"size"`
+}
+
+// ListRequest is the query of a list request, the filters aside.
+type ListRequest struct {
+	Page uint32 `form:"page,default=1" binding:"min=1"`
+	Size uint32 `form:"size" binding:"omitempty,min=1"`
+	// Sort is a comma-separated list of fields, prefixed with `-` for descending order, e.g. `-created_at,name`.
+	Sort string `form:"sort"`
+}
+
+type idRequest struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+// Resource is a Controller exposing a Repository:
+//
+//   - GET /: list, with `page`, `size`, `sort` and the allowed filters in the query;
+//   - GET /:id: get;
+//   - POST /: create, answering 201;
+//   - PUT /:id: replace;
+//   - PATCH /:id: partial update with a JSON Merge Patch (RFC 7396);
+//   - DELETE /:id: delete.
+//
+// The item type is validated with its `binding` rules on create, replace and update. PATCH merges the patch into the
+// current item, so the fields absent from its JSON document, tagged `json:"-"` or unexported, keep their current values;
+// PUT replaces the item with the request body, where they are zero.
+type Resource[T any, ID comparable] struct {
+	// Name identifies the controller, and prefixes the operation IDs, e.g. `users` gives `users.list`.
+	Name        string
+	BasePath    string
+	Version     string
+	Middlewares []gin.HandlerFunc
+	Repository  Repository[T, ID]
+	// ParseID converts the `:id` path parameter. Nil supports the string and integer ID types.
+	ParseID func(string) (ID, error)
+	// Filters are the query parameters passed to Repository.List as filters, other than `page`, `size` and `sort`.
+	Filters []string
+	// Sorts are the fields the list may be sorted by.
+	Sorts []string
+	// DefaultSize is the page size when absent from the query, at most MaxSize. Zero means DefaultPageSize.
+	DefaultSize uint32
+	// MaxSize is the largest page size accepted. Zero means DefaultMaxSize.
+	MaxSize uint32
+}
+
+var (
+	_ Controller = (*Resource[struct{}, string])(nil)
+	_ Validator  = (*Resource[struct{}, string])(nil)
+)
+
+// Validate rejects the filters named after the parameters of ListRequest, which they would shadow.
+func (r *Resource[T, ID]) Validate() error {
+	for _, name := range r.Filters {
+		if name == "page" || name == "size" || name == "sort" {
+			return fmt.Errorf("filter %s is a list parameter", name)
+		}
+	}
+	return nil
+}
+
+// Definition declares the routes of the resource.
+func (r *Resource[T, ID]) Definition() Definition {
+	actions := []Action{
+		NewAction(http.MethodGet, "", r.Name+".list", r.list),
+		NewAction(http.MethodGet, "/:id", r.Name+".get", func(ctx context.Context, req idRequest) (T, error) {
+			var zero T
+			id, err := r.parseID(req.ID)
+			if err != nil {
+				return zero, err
+			}
+			item, err := r.Repository.Get(ctx, id)
+			return item, repositoryError(err)
+		}),
+		NewAction(http.MethodPost, "", r.Name+".create", func(ctx context.Context, item T) (T, error) {
+			created, err := r.Repository.Create(ctx, item)
+			if err != nil {
+				return created, repositoryError(err)
+			}
+			if c, ok := ctx.(*gin.Context); ok {
+				c.Status(http.StatusCreated)
+			}
+			return created, nil
+		}),
+		NewAction(http.MethodPut, "/:id", r.Name+".replace", func(ctx context.Context, item T) (T, error) {
+			var zero T
+			id, err := r.parseID(ctx.(*gin.Context).Param("id"))
+			if err != nil {
+				return zero, err
+			}
+			updated, err := r.Repository.Update(ctx, id, item)
+			return updated, repositoryError(err)
+		}),
+		{
+			Method:   http.MethodPatch,
+			Path:     "/:id",
+			Name:     r.Name + ".update",
+			Handler:  r.patch,
+			Request:  reflect.TypeFor[map[string]any](),
+			Response: reflect.TypeFor[T](),
+		},
+		NewAction(http.MethodDelete, "/:id", r.Name+".delete", func(ctx context.Context, req idRequest) (any, error) {
+			id, err := r.parseID(req.ID)
+			if err != nil {
+				return nil, err
+			}
+			return nil, repositoryError(r.Repository.Delete(ctx, id))
+		}),
+	}
+	return Definition{
+		Name:        r.Name,
+		BasePath:    r.BasePath,
+		Version:     r.Version,
+		Middlewares: r.Middlewares,
+		Actions:     actions,
+	}
+}
+
+func (r *Resource[T, ID]) list(ctx context.Context, req ListRequest) (*Page[T], error) {
+	query, err := r.listQuery(ctx.(*gin.Context), req)
+	if err != nil {
+		return nil, err
+	}
+	items, total, err := r.Repository.List(ctx, query)
+	if err != nil {
+		return nil, repositoryError(err)
+	}
+	if items == nil {
+		items = make([]T, 0)
+	}
+	return &Page[T]{Items: items, Total: total, Page: query.Page, Size: query.Size}, nil
+}
+
+func (r *Resource[T, ID]) listQuery(c *gin.Context, req ListRequest) (ListQuery, error) {
+	query := ListQuery{Page: req.Page, Size: req.Size, Filters: make(map[string]string)}
+	maxSize := r.MaxSize
+	if maxSize == 0 {
+		maxSize = DefaultMaxSize
+	}
+	if query.Size == 0 {
+		query.Size = r.DefaultSize
+		if query.Size == 0 {
+			query.Size = DefaultPageSize
+		}
+		query.Size = min(query.Size, maxSize)
+	}
+	if query.Size > maxSize {
+		return query, invalidRequest(fmt.Sprintf("size must not exceed %d", maxSize))
+	}
+	if req.Sort != "" {
+		for _, name := range strings.Split(req.Sort, ",") {
+			field := SortField{Field: strings.TrimSpace(name)}
+			if rest, ok := strings.CutPrefix(field.Field, "-"); ok {
+				field.Field, field.Descending = rest, true
+			}
+			if !slices.Contains(r.Sorts, field.Field) {
+				return query, invalidRequest("cannot sort by " + field.Field)
+			}
+			query.Sort = append(query.Sort, field)
+		}
+	}
+	for _, name := range r.Filters {
+		if value, ok := c.GetQuery(name); ok {
+			query.Filters[name] = value
+		}
+	}
+	return query, nil
+}
+
+// patch applies a JSON Merge Patch to the current item. The body is not bound by Handle, whose content type
+// negotiation does not know merge patches.
+func (r *Resource[T, ID]) patch(c *gin.Context) {
+	id, err := r.parseID(c.Param("id"))
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	if ct := c.ContentType(); ct != MIMEMergePatch && ct != binding.MIMEJSON {
+		renderError(c, NewError(http.StatusUnsupportedMediaType, CodeInvalidRequest, "unsupported content type "+ct))
+		return
+	}
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		renderError(c, WrapError(http.StatusBadRequest, CodeInvalidRequest, err))
+		return
+	}
+	current, err := r.Repository.Get(c, id)
+	if err != nil {
+		renderError(c, repositoryError(err))
+		return
+	}
+	document, err := json.Marshal(current)
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	if document, err = MergePatch(document, patch); err != nil {
+		renderError(c, WrapError(http.StatusBadRequest, CodeInvalidRequest, err))
+		return
+	}
+	item := current
+	resetJSONFields(reflect.ValueOf(&item).Elem())
+	if err := json.Unmarshal(document, &item); err != nil {
+		renderError(c, WrapError(http.StatusBadRequest, CodeInvalidRequest, err))
+		return
+	}
+	if binding.Validator != nil {
+		if err := binding.Validator.ValidateStruct(&item); err != nil {
+			renderError(c, WrapError(http.StatusBadRequest, CodeInvalidRequest, err))
+			return
+		}
+	}
+	updated, err := r.Repository.Update(c, id, item)
+	if err != nil {
+		renderError(c, repositoryError(err))
+		return
+	}
+	c.JSON(http.StatusOK, response.NewGeneric[T, any](c, CodeSuccess, MessageSuccess, updated, nil))
+}
+
+// resetJSONFields zeroes the fields of v encoded in JSON, so that decoding a document into v replaces them while the
+// others are kept. v is zeroed if not a struct.
+func resetJSONFields(v reflect.Value) {
+	if v.Kind() != reflect.Struct {
+		v.SetZero()
+		return
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		switch {
+		case f.Anonymous && f.Type.Kind() == reflect.Struct && f.Tag.Get("json") == "":
+			// The fields of an embedded struct are promoted.
+			resetJSONFields(v.Field(i))
+		case f.IsExported() && f.Tag.Get("json") != "-":
+			v.Field(i).SetZero()
+		}
+	}
+}
+
+func (r *Resource[T, ID]) parseID(s string) (ID, error) {
+	var id ID
+	var err error
+	if r.ParseID != nil {
+		id, err = r.ParseID(s)
+	} else {
+		err = parseID(s, reflect.ValueOf(&id).Elem())
+	}
+	if err != nil {
+		return id, WrapError(http.StatusBadRequest, CodeInvalidRequest, fmt.Errorf("invalid id %q: %w", s, err))
+	}
+	return id, nil
+}
+
+func parseID(s string, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	default:
+		return fmt.Errorf("unsupported id type %s", v.Type())
+	}
+	return nil
+}
+
+// repositoryError maps the repository errors to the rendered ones.
+func repositoryError(err error) error {
+	var e *Error
+	switch {
+	case err == nil || errors.As(err, &e):
+		return err
+	case errors.Is(err, ErrNotFound):
+		return WrapError(http.StatusNotFound, CodeNotFound, err)
+	case errors.Is(err, ErrConflict):
+		return WrapError(http.StatusConflict, CodeConflict, err)
+	}
+	return err
+}
+
+func invalidRequest(message string) *Error {
+	return NewError(http.StatusBadRequest, CodeInvalidRequest, message)
+}
+
+// MergePatch applies a JSON Merge Patch (RFC 7396) to a JSON document.
+func MergePatch(document []byte, patch []byte) ([]byte, error) {
+	var target, changes any
+	if err := json.Unmarshal(document, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergePatch(target, changes))
+}
+
+func mergePatch(target any, patch any) any {
+	changes, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	object, ok := target.(map[string]any)
+	if !ok {
+		object = make(map[string]any)
+	}
+	for key, value := range changes {
+		if value == nil {
+			delete(object, key)
+		} else {
+			object[key] = mergePatch(object[key], value)
+		}
+	}
+	return object
+}
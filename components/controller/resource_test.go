@@ -0,0 +1,311 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rhosocial/go-rush-common/components/response"
+	"github.com/stretchr/testify/assert"
+)
+
+type article struct {
+	ID     uint64   `json:"id"`
+	Title  string   `json:"title" binding:"required,max=32"`
+	Status string   `json:"status" binding:"omitempty,oneof=draft published"`
+	Tags   []string `json:"tags,omitempty"`
+	// Author is stored, never rendered.
+	Author string `json:"-"`
+}
+
+// articles is an in-memory Repository.
+type articles struct {
+	mu     sync.Mutex
+	items  map[uint64]article
+	nextID uint64
+	last   ListQuery
+}
+
+func newArticles(titles ...string) *articles {
+	a := articles{items: make(map[uint64]article)}
+	for _, title := range titles {
+		_, _ = a.Create(context.Background(), article{Title: title, Status: "draft"})
+	}
+	return &a
+}
+
+func (a *articles) List(ctx context.Context, query ListQuery) ([]article, uint64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.last = query
+	result := make([]article, 0)
+	for _, item := range a.items {
+		if status, ok := query.Filters["status"]; ok && item.Status != status {
+			continue
+		}
+		result = append(result, item)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if len(query.Sort) > 0 && query.Sort[0].Descending {
+			return result[i].ID > result[j].ID
+		}
+		return result[i].ID < result[j].ID
+	})
+	total := uint64(len(result))
+	start := min(query.Offset(), total)
+	end := min(start+uint64(query.Size), total)
+	return result[start:end], total, nil
+}
+
+func (a *articles) Get(ctx context.Context, id uint64) (article, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	item, ok := a.items[id]
+	if !ok {
+		return item, fmt.Errorf("article %d: %w", id, ErrNotFound)
+	}
+	return item, nil
+}
+
+func (a *articles) Create(ctx context.Context, item article) (article, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, existing := range a.items {
+		if existing.Title == item.Title {
+			return item, fmt.Errorf("title %q: %w", item.Title, ErrConflict)
+		}
+	}
+	a.nextID++
+	item.ID = a.nextID
+	a.items[item.ID] = item
+	return item, nil
+}
+
+func (a *articles) Update(ctx context.Context, id uint64, item article) (article, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.items[id]; !ok {
+		return item, fmt.Errorf("article %d: %w", id, ErrNotFound)
+	}
+	item.ID = id
+	a.items[id] = item
+	return item, nil
+}
+
+func (a *articles) Delete(ctx context.Context, id uint64) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.items[id]; !ok {
+		return fmt.Errorf("article %d: %w", id, ErrNotFound)
+	}
+	delete(a.items, id)
+	return nil
+}
+
+func setupRouterResource(t *testing.T, repository *articles) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	resource := &Resource[article, uint64]{
+		Name:       "articles",
+		BasePath:   "/articles",
+		Version:    "v1",
+		Repository: repository,
+		Filters:    []string{"status"},
+		Sorts:      []string{"id"},
+		MaxSize:    10,
+	}
+	assert.NoError(t, NewRegistry(resource).Register(r))
+	return r
+}
+
+func requestResource(r *gin.Engine, method string, path string, contentType string, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	if body == "" {
+		req.Body = http.NoBody
+		req.ContentLength = 0
+	}
+	req.Header.Set("Content-Type", contentType)
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func decodeResource[T any](t *testing.T, w *httptest.ResponseRecorder) response.Generic[T, any] {
+	var resp response.Generic[T, any]
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp
+}
+
+func TestResource(t *testing.T) {
+	t.Run("list", func(t *testing.T) {
+		repository := newArticles("a", "b", "c")
+		r := setupRouterResource(t, repository)
+		_, _ = repository.Update(context.Background(), 2, article{Title: "b", Status: "published"})
+
+		w := requestResource(r, http.MethodGet, "/v1/articles?size=2&sort=-id", "", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		page := decodeResource[Page[article]](t, w).Data
+		assert.Equal(t, uint64(3), page.Total)
+		assert.Equal(t, uint32(1), page.Page)
+		assert.Equal(t, uint32(2), page.Size)
+		assert.Equal(t, []uint64{3, 2}, []uint64{page.Items[0].ID, page.Items[1].ID})
+		assert.Equal(t, []SortField{{Field: "id", Descending: true}}, repository.last.Sort)
+
+		w = requestResource(r, http.MethodGet, "/v1/articles?status=published&ignored=1", "", "")
+		page = decodeResource[Page[article]](t, w).Data
+		assert.Equal(t, uint64(1), page.Total)
+		assert.Equal(t, uint32(10), page.Size, "the default size should not exceed the maximum")
+		assert.Equal(t, map[string]string{"status": "published"}, repository.last.Filters)
+
+		w = requestResource(r, http.MethodGet, "/v1/articles?page=5", "", "")
+		assert.NotNil(t, decodeResource[Page[article]](t, w).Data.Items)
+
+		for _, query := range []string{"size=11", "sort=title", "page=0", "size=abc"} {
+			w = requestResource(r, http.MethodGet, "/v1/articles?"+query, "", "")
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+			assert.Equal(t, CodeInvalidRequest, decodeResource[any](t, w).Code, query)
+		}
+	})
+
+	t.Run("configuration", func(t *testing.T) {
+		repository := newArticles("a", "b", "c")
+		r := gin.New()
+		assert.NoError(t, NewRegistry(&Resource[article, uint64]{
+			Name: "articles", BasePath: "/articles", Repository: repository, DefaultSize: 50, MaxSize: 2,
+		}).Register(r))
+		w := requestResource(r, http.MethodGet, "/articles", "", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, uint32(2), decodeResource[Page[article]](t, w).Data.Size, "the default size should not exceed the maximum")
+
+		err := NewRegistry(&Resource[article, uint64]{
+			Name: "articles", BasePath: "/articles", Repository: repository, Filters: []string{"status", "sort"},
+		}).Register(gin.New())
+		assert.EqualError(t, err, "controller articles: filter sort is a list parameter")
+	})
+
+	t.Run("get", func(t *testing.T) {
+		r := setupRouterResource(t, newArticles("a"))
+		w := requestResource(r, http.MethodGet, "/v1/articles/1", "", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "a", decodeResource[article](t, w).Data.Title)
+
+		w = requestResource(r, http.MethodGet, "/v1/articles/2", "", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, CodeNotFound, decodeResource[any](t, w).Code)
+
+		w = requestResource(r, http.MethodGet, "/v1/articles/abc", "", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("create", func(t *testing.T) {
+		r := setupRouterResource(t, newArticles("a"))
+		w := requestResource(r, http.MethodPost, "/v1/articles", "application/json", `{"title":"b"}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, uint64(2), decodeResource[article](t, w).Data.ID)
+
+		w = requestResource(r, http.MethodPost, "/v1/articles", "application/json", `{"title":"a"}`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Equal(t, CodeConflict, decodeResource[any](t, w).Code)
+
+		w = requestResource(r, http.MethodPost, "/v1/articles", "application/json", `{"status":"archived"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		r := setupRouterResource(t, newArticles("a"))
+		w := requestResource(r, http.MethodPut, "/v1/articles/1", "application/json", `{"title":"b"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, article{ID: 1, Title: "b"}, decodeResource[article](t, w).Data)
+
+		w = requestResource(r, http.MethodPut, "/v1/articles/2", "application/json", `{"title":"b"}`)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		repository := newArticles("a")
+		r := setupRouterResource(t, repository)
+		_, _ = repository.Update(context.Background(), 1, article{Title: "a", Status: "draft", Tags: []string{"x"}, Author: "alice"})
+
+		w := requestResource(r, http.MethodPatch, "/v1/articles/1", MIMEMergePatch, `{"status":"published","tags":null}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, article{ID: 1, Title: "a", Status: "published"}, decodeResource[article](t, w).Data)
+		// The fields absent from JSON are kept.
+		stored, _ := repository.Get(context.Background(), 1)
+		assert.Equal(t, article{ID: 1, Title: "a", Status: "published", Author: "alice"}, stored)
+
+		for name, w := range map[string]*httptest.ResponseRecorder{
+			"invalid value": requestResource(r, http.MethodPatch, "/v1/articles/1", MIMEMergePatch, `{"status":"archived"}`),
+			"removed title": requestResource(r, http.MethodPatch, "/v1/articles/1", "application/json", `{"title":null}`),
+			"invalid json":  requestResource(r, http.MethodPatch, "/v1/articles/1", MIMEMergePatch, `{`),
+			"invalid type":  requestResource(r, http.MethodPatch, "/v1/articles/1", MIMEMergePatch, `{"title":1}`),
+		} {
+			assert.Equal(t, http.StatusBadRequest, w.Code, name)
+		}
+		w = requestResource(r, http.MethodPatch, "/v1/articles/1", "text/plain", `{}`)
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+		w = requestResource(r, http.MethodPatch, "/v1/articles/2", MIMEMergePatch, `{}`)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		r := setupRouterResource(t, newArticles("a"))
+		w := requestResource(r, http.MethodDelete, "/v1/articles/1", "", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, CodeSuccess, decodeResource[any](t, w).Code)
+		w = requestResource(r, http.MethodDelete, "/v1/articles/1", "", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestMergePatch(t *testing.T) {
+	// Examples of RFC 7396, appendix A.
+	cases := [][3]string{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, c := range cases {
+		result, err := MergePatch([]byte(c[0]), []byte(c[1]))
+		assert.NoError(t, err)
+		assert.JSONEq(t, c[2], string(result), c[1])
+	}
+	_, err := MergePatch([]byte(`{`), []byte(`{}`))
+	assert.Error(t, err)
+}
+
+func TestParseID(t *testing.T) {
+	var s string
+	var i int16
+	var u uint8
+	var f float64
+	assert.NoError(t, parseID("abc", reflectValue(&s)))
+	assert.Equal(t, "abc", s)
+	assert.NoError(t, parseID("-12", reflectValue(&i)))
+	assert.Equal(t, int16(-12), i)
+	assert.Error(t, parseID("256", reflectValue(&u)))
+	assert.Error(t, parseID("1", reflectValue(&f)))
+}
+
+func reflectValue(v any) reflect.Value {
+	return reflect.ValueOf(v).Elem()
+}
@@ -0,0 +1,250 @@
+package controller
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rhosocial/go-rush-common/components/response"
+)
+
+// CodeUnsupportedVersion is the code of the envelopes answering a request for a version the controller does not
+// support.
+const CodeUnsupportedVersion uint32 = 5
+
+const (
+	// HeaderAPIVersion is the default request header selecting the version, e.g. `X-API-Version: 2`.
+	HeaderAPIVersion = "X-API-Version"
+	// AcceptVersionParameter is the media type parameter of the Accept header selecting the version, e.g.
+	// `Accept: application/json; version=2`.
+	AcceptVersionParameter = "version"
+	// ContextVersion is the key under which the version of the route is stored in the context.
+	ContextVersion = "APIVersion"
+)
+
+// Version is a version of a controller. Its routes are prefixed with its name, e.g. `v1` gives `/v1/users`.
+type Version struct {
+	// Name is the path segment of the version. Headers may select it with or without its `v` prefix, e.g. `2` selects
+	// `v2`.
+	Name string
+	// Deprecated adds the Deprecation header (RFC 9745) to the responses of the version.
+	Deprecated bool
+	// DeprecatedAt is the date sent in the Deprecation header, required if Deprecated is set.
+	DeprecatedAt time.Time
+	// Sunset, if set, is the date the version will be removed, sent in the Sunset header (RFC 8594).
+	Sunset time.Time
+	// Link, if set, is the URL of the migration guide, sent as a Link header with the `deprecation` relation.
+	Link string
+}
+
+// GetVersion returns the version of the route handling the request, empty if the controller is not versioned.
+func GetVersion(c *gin.Context) string {
+	return c.GetString(ContextVersion)
+}
+
+// versionMiddleware stores the version in the context and advertises the deprecation of the version.
+func versionMiddleware(v Version) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(v.DeprecatedAt.Unix(), 10)
+	sunset := ""
+	if !v.Sunset.IsZero() {
+		sunset = v.Sunset.UTC().Format(http.TimeFormat)
+	}
+	return func(c *gin.Context) {
+		c.Set(ContextVersion, v.Name)
+		if v.Deprecated {
+			c.Header("Deprecation", deprecation)
+			if v.Link != "" {
+				c.Writer.Header().Add("Link", "<"+v.Link+`>; rel="deprecation"`)
+			}
+		}
+		if sunset != "" {
+			c.Header("Sunset", sunset)
+		}
+	}
+}
+
+// versionsOf returns the versions of the controller, nil if it is not versioned.
+func versionsOf(d *Definition) []Version {
+	if len(d.Versions) > 0 {
+		return d.Versions
+	}
+	if d.Version != "" {
+		return []Version{{Name: d.Version}}
+	}
+	return nil
+}
+
+// mount is the base path of a versioned controller, for negotiation.
+type mount struct {
+	// prefix is the path of the router group the controller is registered on.
+	prefix   string
+	basePath string
+	versions []Version
+}
+
+func (m mount) path() string {
+	return joinPaths(m.prefix, m.basePath)
+}
+
+// find returns the version named by value, with or without its `v` prefix.
+func (m mount) find(value string) (Version, bool) {
+	for _, v := range m.versions {
+		if v.Name == value || strings.TrimPrefix(v.Name, "v") == value {
+			return v, true
+		}
+	}
+	return Version{}, false
+}
+
+// match returns the path relative to the group of the controller if the path is within the controller and not
+// prefixed with a version.
+func (m mount) match(p string) (string, bool) {
+	base := m.path()
+	if p != base && !strings.HasPrefix(p, strings.TrimSuffix(base, "/")+"/") {
+		return "", false
+	}
+	relative := strings.TrimPrefix(p, strings.TrimSuffix(m.prefix, "/"))
+	first, _, _ := strings.Cut(strings.TrimPrefix(relative, "/"), "/")
+	if slices.ContainsFunc(m.versions, func(v Version) bool { return v.Name == first }) {
+		return "", false
+	}
+	return relative, true
+}
+
+// Negotiation configures Registry.Negotiate.
+type Negotiation struct {
+	// Header is the request header selecting the version. Empty means HeaderAPIVersion.
+	Header string
+	// DisableAccept ignores the version parameter of the Accept header.
+	DisableAccept bool
+}
+
+// Negotiate returns a handler selecting the version of the requests to versioned controllers without a version in
+// their path, before handing them to next, usually the engine the registry is registered on:
+//
+//	server := http.Server{Handler: registry.Negotiate(engine, controller.Negotiation{})}
+//
+// The version comes from the header, then from the version parameter of the Accept header, and defaults to the last
+// version of the controller; the path is then rewritten, e.g. `/users` into `/v2/users`. A request for a version the
+// controller does not support is answered with 406 and CodeUnsupportedVersion. Requests with a version in their path
+// are handed over unchanged.
+func (r *Registry) Negotiate(next http.Handler, config Negotiation) http.Handler {
+	if config.Header == "" {
+		config.Header = HeaderAPIVersion
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		m, relative, ok := r.mountOf(req.URL.Path)
+		if !ok {
+			next.ServeHTTP(w, req)
+			return
+		}
+		requested := strings.TrimSpace(req.Header.Get(config.Header))
+		if requested == "" && !config.DisableAccept {
+			requested = acceptedVersion(req.Header.Values("Accept"))
+		}
+		version := m.versions[len(m.versions)-1]
+		if requested != "" {
+			if version, ok = m.find(requested); !ok {
+				writeUnsupportedVersion(w, requested)
+				return
+			}
+		}
+		rewritten := new(http.Request)
+		*rewritten = *req
+		u := *req.URL
+		u.Path = joinPaths(m.prefix, version.Name, relative)
+		u.RawPath = ""
+		rewritten.URL = &u
+		next.ServeHTTP(w, rewritten)
+	})
+}
+
+// mountOf returns the versioned controller with the longest base path matching p.
+func (r *Registry) mountOf(p string) (mount, string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, m := range r.mounts {
+		if relative, ok := m.match(p); ok {
+			return m, relative, true
+		}
+	}
+	return mount{}, "", false
+}
+
+func (r *Registry) addMounts(mounts []mount) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mounts = append(r.mounts, mounts...)
+	sort.SliceStable(r.mounts, func(i, j int) bool {
+		return len(r.mounts[i].path()) > len(r.mounts[j].path())
+	})
+}
+
+// acceptedVersion returns the first version parameter of the Accept header values.
+func acceptedVersion(values []string) string {
+	for _, value := range values {
+		for _, mediaRange := range strings.Split(value, ",") {
+			_, params, err := mime.ParseMediaType(mediaRange)
+			if err != nil {
+				continue
+			}
+			if version := params[AcceptVersionParameter]; version != "" {
+				return version
+			}
+		}
+	}
+	return ""
+}
+
+func unsupportedVersionMessage(version string) string {
+	return "unsupported API version " + version
+}
+
+// writeUnsupportedVersion answers outside of gin, hence without request ID.
+func writeUnsupportedVersion(w http.ResponseWriter, version string) {
+	body, _ := json.Marshal(response.Base{Code: CodeUnsupportedVersion, Message: unsupportedVersionMessage(version)})
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusNotAcceptable)
+	_, _ = w.Write(body)
+}
+
+// NoRoute returns a handler for engine.NoRoute, answering the requests for an unsupported version in the path of a
+// versioned controller, e.g. `/v9/users`, with 404 and CodeUnsupportedVersion, and the other requests with 404 and
+// CodeNotFound.
+func (r *Registry) NoRoute() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if version, ok := r.unsupportedVersion(c.Request.URL.Path); ok {
+			c.AbortWithStatusJSON(http.StatusNotFound, response.NewBase(c, CodeUnsupportedVersion, unsupportedVersionMessage(version)))
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusNotFound, response.NewBase(c, CodeNotFound, http.StatusText(http.StatusNotFound)))
+	}
+}
+
+// unsupportedVersion returns the first segment of p if it is not a version of the versioned controller p is within
+// once that segment is removed.
+func (r *Registry) unsupportedVersion(p string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, m := range r.mounts {
+		prefix := strings.TrimSuffix(m.prefix, "/")
+		relative, ok := strings.CutPrefix(p, prefix+"/")
+		if !ok {
+			continue
+		}
+		version, rest, _ := strings.Cut(relative, "/")
+		if version == "" || slices.ContainsFunc(m.versions, func(v Version) bool { return v.Name == version }) {
+			continue
+		}
+		if _, ok := m.match(joinPaths(m.prefix, rest)); ok {
+			return version, true
+		}
+	}
+	return "", false
+}
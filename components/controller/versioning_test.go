@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rhosocial/go-rush-common/components/response"
+	"github.com/stretchr/testify/assert"
+)
+
+func versionedController() definitionController {
+	version := func(c *gin.Context) {
+		c.JSON(http.StatusOK, response.NewBase(c, CodeSuccess, GetVersion(c)))
+	}
+	return definitionController{
+		Name:     "users",
+		BasePath: "/users",
+		Versions: []Version{
+			{
+				Name:         "v1",
+				Deprecated:   true,
+				DeprecatedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+				Sunset:       time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+				Link:         "https://example.com/migrate",
+			},
+			{Name: "v2"},
+		},
+		Actions: []Action{
+			{Method: http.MethodGet, Path: "/:id", Name: "getUser", Handler: version},
+			{Method: http.MethodGet, Path: "/:id/groups", Name: "listGroups", Versions: []string{"v2"}, Handler: version},
+		},
+	}
+}
+
+func serveRequest(h http.Handler, req *http.Request) (*httptest.ResponseRecorder, response.Base) {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	var resp response.Base
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestRegistry_Versions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	registry := NewRegistry(versionedController())
+	assert.NoError(t, registry.Register(r.Group("/api")))
+	r.NoRoute(registry.NoRoute())
+
+	t.Run("routes", func(t *testing.T) {
+		assert.Equal(t, []Route{
+			{Method: http.MethodGet, Path: "/api/v1/users/:id", Controller: "users", Action: "getUser", Version: "v1", Deprecated: true},
+			{Method: http.MethodGet, Path: "/api/v2/users/:id", Controller: "users", Action: "getUser", Version: "v2"},
+			{Method: http.MethodGet, Path: "/api/v2/users/:id/groups", Controller: "users", Action: "listGroups", Version: "v2"},
+		}, registry.Routes())
+	})
+
+	t.Run("deprecated", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil)
+		w, resp := serveRequest(r, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "v1", resp.Message)
+		assert.Equal(t, "@1767225600", w.Header().Get("Deprecation"))
+		assert.Equal(t, "Fri, 01 Jan 2027 00:00:00 GMT", w.Header().Get("Sunset"))
+		assert.Equal(t, `<https://example.com/migrate>; rel="deprecation"`, w.Header().Get("Link"))
+
+		req = httptest.NewRequest(http.MethodGet, "/api/v2/users/1", nil)
+		w, resp = serveRequest(r, req)
+		assert.Equal(t, "v2", resp.Message)
+		assert.Empty(t, w.Header().Get("Deprecation"))
+		assert.Empty(t, w.Header().Get("Sunset"))
+	})
+
+	t.Run("restricted action", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/1/groups", nil)
+		w, resp := serveRequest(r, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, CodeNotFound, resp.Code)
+	})
+
+	t.Run("unsupported path version", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v9/users/1", nil)
+		w, resp := serveRequest(r, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, CodeUnsupportedVersion, resp.Code)
+		assert.Equal(t, "unsupported API version v9", resp.Message)
+	})
+
+	t.Run("unknown action version", func(t *testing.T) {
+		d := versionedController()
+		d.Actions[0].Versions = []string{"v3"}
+		assert.ErrorContains(t, NewRegistry(d).Register(gin.New()), "unknown version v3")
+	})
+
+	t.Run("deprecated without date", func(t *testing.T) {
+		d := versionedController()
+		d.Versions[0].DeprecatedAt = time.Time{}
+		r := gin.New()
+		assert.ErrorContains(t, NewRegistry(d).Register(r), "deprecated version v1 has no DeprecatedAt")
+		assert.Empty(t, r.Routes())
+	})
+}
+
+func TestRegistry_Negotiate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	registry := NewRegistry(versionedController())
+	assert.NoError(t, registry.Register(r.Group("/api")))
+	r.GET("/health", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	h := registry.Negotiate(r, Negotiation{})
+
+	tests := []struct {
+		name    string
+		path    string
+		headers map[string]string
+		version string
+	}{
+		{name: "default", path: "/api/users/1", version: "v2"},
+		{name: "header", path: "/api/users/1", headers: map[string]string{HeaderAPIVersion: "1"}, version: "v1"},
+		{name: "header with prefix", path: "/api/users/1", headers: map[string]string{HeaderAPIVersion: "v1"}, version: "v1"},
+		{name: "accept", path: "/api/users/1", headers: map[string]string{"Accept": "text/html, application/json; version=1"}, version: "v1"},
+		{name: "header before accept", path: "/api/users/1", headers: map[string]string{HeaderAPIVersion: "2", "Accept": "application/json; version=1"}, version: "v2"},
+		{name: "path wins", path: "/api/v1/users/1", headers: map[string]string{HeaderAPIVersion: "2"}, version: "v1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w, resp := serveRequest(h, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.version, resp.Message)
+			assert.Equal(t, tt.path, req.URL.Path)
+		})
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/users/1", nil)
+		req.Header.Set("Accept", "application/json; version=3")
+		w, resp := serveRequest(h, req)
+		assert.Equal(t, http.StatusNotAcceptable, w.Code)
+		assert.Equal(t, CodeUnsupportedVersion, resp.Code)
+		assert.Equal(t, "unsupported API version 3", resp.Message)
+	})
+
+	t.Run("unversioned", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/health", nil)
+		req.Header.Set(HeaderAPIVersion, "3")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
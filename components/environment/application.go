@@ -0,0 +1,510 @@
+package environment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rhosocial/go-rush-common/components/config"
+	apperror "github.com/rhosocial/go-rush-common/components/error"
+	"github.com/rhosocial/go-rush-common/components/logger"
+	"github.com/rhosocial/go-rush-common/components/logger/sink"
+	"github.com/rhosocial/go-rush-common/components/mysql"
+	"github.com/rhosocial/go-rush-common/components/redis"
+	"github.com/rhosocial/go-rush-common/components/tracing"
+	"github.com/rhosocial/go-rush-common/models/activity"
+)
+
+// The names of the built-in components.
+const (
+	ComponentLogger     = "logger"
+	ComponentTracing    = "tracing"
+	ComponentRedis      = "redis"
+	ComponentMySQL      = "mysql"
+	ComponentActivities = "activities"
+)
+
+var (
+	ErrComponentNotFound = errors.New("component not found")
+	ErrAlreadyStarted    = errors.New("application already started")
+)
+
+// Component is a part of the application created on start and released on stop.
+type Component struct {
+	Name string
+	// DependsOn names the components started before, and stopped after, this one.
+	DependsOn []string
+	// Start creates the component. The value returned is available to the components started later through Get.
+	Start func(ctx context.Context, app *Application) (any, error)
+	// Stop releases the component. Nil means there is nothing to release.
+	Stop func(ctx context.Context, app *Application) error
+}
+
+// Application starts and stops the components of an application in dependency order, and serves its HTTP servers.
+type Application struct {
+	env        *Env
+	mu         sync.RWMutex
+	components []Component
+	values     map[string]any
+	started    []Component
+	onStart    []func(context.Context) error
+	onStop     []func(context.Context) error
+	servers    []*http.Server
+}
+
+// New creates an application with the built-in components of the configuration. A nil configuration means the
+// defaults.
+func New(env *Env) (*Application, error) {
+	if env == nil {
+		env = &Env{}
+	}
+	if err := env.Validate(); err != nil {
+		return nil, err
+	}
+	a := Application{env: env, values: make(map[string]any)}
+	a.Provide(a.loggerComponent())
+	if env.Tracing != nil {
+		a.Provide(a.tracingComponent())
+	}
+	if len(env.Redis) > 0 {
+		a.Provide(a.redisComponent())
+	}
+	if len(env.MySQL) > 0 {
+		a.Provide(a.mysqlComponent())
+	}
+	if len(env.Activities) > 0 {
+		a.Provide(a.activitiesComponent())
+	}
+	return &a, nil
+}
+
+// Env returns the configuration of the application.
+func (a *Application) Env() *Env {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.env
+}
+
+// reloadable are the configuration paths Reload applies; the other changes need a restart.
+var reloadable = []string{"Logger.Level", "Logger.Packages", "Redis", "ShutdownTimeout"}
+
+// Reload applies a new configuration to the started components, as a config.Subscriber:
+//
+//	watcher.Subscribe(app.Reload)
+//
+// The logger levels and the Redis servers are changed in place, the latter if Redis was configured at startup; the
+// other changes are logged, and only take effect on restart. If a change cannot be applied, the changes already applied
+// are reverted and an error is returned.
+func (a *Application) Reload(old *Env, next *Env, changes []config.Change) error {
+	pool := a.Redis()
+	paths := reloadable
+	if pool == nil {
+		paths = slices.DeleteFunc(slices.Clone(reloadable), func(path string) bool { return path == "Redis" })
+	}
+	ignored := make([]string, 0)
+	for _, change := range changes {
+		if !config.Changed([]config.Change{change}, paths...) {
+			ignored = append(ignored, change.Path)
+		}
+	}
+	if len(ignored) > 0 {
+		a.Logger().Warn("configuration changes need a restart", "paths", ignored)
+	}
+	redisChanged := pool != nil && config.Changed(changes, "Redis")
+	if redisChanged {
+		if err := pool.Reconfigure(next.Redis); err != nil {
+			return err
+		}
+	}
+	if config.Changed(changes, "Logger.Level", "Logger.Packages") {
+		e := next.Logger
+		if e == nil {
+			e = &logger.EnvLogger{}
+		}
+		if err := a.Logger().ApplyEnv(e); err != nil {
+			if redisChanged {
+				// The clients of the previous servers are still open, and reused.
+				_ = pool.Reconfigure(old.Redis)
+			}
+			return err
+		}
+	}
+	a.mu.Lock()
+	a.env = next
+	a.mu.Unlock()
+	a.Logger().Info("configuration reloaded", "changes", len(changes))
+	return nil
+}
+
+// Provide adds components to be started by Start.
+func (a *Application) Provide(components ...Component) *Application {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.components = append(a.components, components...)
+	return a
+}
+
+// OnStart adds a hook run once all components are started, before the servers.
+func (a *Application) OnStart(fn func(context.Context) error) *Application {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.onStart = append(a.onStart, fn)
+	return a
+}
+
+// OnStop adds a hook run once the servers are drained, before the components are stopped. The hooks run in reverse
+// order.
+func (a *Application) OnStop(fn func(context.Context) error) *Application {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.onStop = append(a.onStop, fn)
+	return a
+}
+
+// AddServer adds a server started by Run, and drained first on shutdown.
+func (a *Application) AddServer(server *http.Server) *Application {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.servers = append(a.servers, server)
+	return a
+}
+
+// Get returns the value of the named component.
+func Get[T any](a *Application, name string) (T, error) {
+	var zero T
+	a.mu.RLock()
+	value, ok := a.values[name]
+	a.mu.RUnlock()
+	if !ok {
+		return zero, fmt.Errorf("%w: %s", ErrComponentNotFound, name)
+	}
+	typed, ok := value.(T)
+	if !ok {
+		return zero, fmt.Errorf("component %s is a %T, not a %T", name, value, zero)
+	}
+	return typed, nil
+}
+
+// Logger returns the application logger, logger.Default until the application is started.
+func (a *Application) Logger() *logger.Logger {
+	if l, err := Get[*logger.Logger](a, ComponentLogger); err == nil {
+		return l
+	}
+	return logger.Default()
+}
+
+// Redis returns the Redis pool, nil if not configured or not started.
+func (a *Application) Redis() *redis.ClientPool {
+	pool, _ := Get[*redis.ClientPool](a, ComponentRedis)
+	return pool
+}
+
+// MySQL returns the MySQL cluster, nil if not configured or not started.
+func (a *Application) MySQL() *mysql.Cluster {
+	cluster, _ := Get[*mysql.Cluster](a, ComponentMySQL)
+	return cluster
+}
+
+// ErrorHandler returns the error.ErrorHandler middleware for the profile of the application, adding the stack traces
+// of the panics to the responses in dev only, see Profile.DebugStack.
+func (a *Application) ErrorHandler() gin.HandlerFunc {
+	return apperror.ErrorHandlerWithConfig(apperror.ErrorHandlerConfig{Stack: a.Env().Profile.DebugStack()})
+}
+
+// ActivityPools returns the activity pools by name, nil if not configured or not started.
+func (a *Application) ActivityPools() map[string]*activity.Pool {
+	pools, _ := Get[map[string]*activity.Pool](a, ComponentActivities)
+	return pools
+}
+
+// ActivityPool returns the named activity pool, nil if absent.
+func (a *Application) ActivityPool(name string) *activity.Pool {
+	return a.ActivityPools()[name]
+}
+
+// order sorts the components so that every one comes after its dependencies, keeping the order of addition otherwise.
+func order(components []Component) ([]Component, error) {
+	byName := make(map[string]Component, len(components))
+	for _, c := range components {
+		if _, ok := byName[c.Name]; ok {
+			return nil, fmt.Errorf("component %s is provided twice", c.Name)
+		}
+		byName[c.Name] = c
+	}
+	sorted := make([]Component, 0, len(components))
+	// 0: not visited, 1: visiting, 2: sorted.
+	state := make(map[string]int, len(components))
+	var visit func(c Component, path []string) error
+	visit = func(c Component, path []string) error {
+		switch state[c.Name] {
+		case 1:
+			return fmt.Errorf("dependency cycle: %v", append(path, c.Name))
+		case 2:
+			return nil
+		}
+		state[c.Name] = 1
+		for _, name := range c.DependsOn {
+			dependency, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("component %s depends on %w: %s", c.Name, ErrComponentNotFound, name)
+			}
+			if err := visit(dependency, append(path, c.Name)); err != nil {
+				return err
+			}
+		}
+		state[c.Name] = 2
+		sorted = append(sorted, c)
+		return nil
+	}
+	for _, c := range components {
+		if err := visit(c, nil); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// Start sets the gin mode of the profile, if any, starts the components in dependency order, then runs the start hooks.
+// If any fails, the components already started are stopped.
+func (a *Application) Start(ctx context.Context) error {
+	a.mu.Lock()
+	if len(a.started) > 0 {
+		a.mu.Unlock()
+		return ErrAlreadyStarted
+	}
+	components, err := order(a.components)
+	hooks := slices.Clone(a.onStart)
+	a.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if profile := a.Env().Profile; profile != "" {
+		gin.SetMode(profile.GinMode())
+	}
+	for _, c := range components {
+		value, err := c.Start(ctx, a)
+		if err != nil {
+			err = fmt.Errorf("start %s: %w", c.Name, err)
+			return errors.Join(err, a.stopComponents(ctx))
+		}
+		a.mu.Lock()
+		a.values[c.Name] = value
+		a.started = append(a.started, c)
+		a.mu.Unlock()
+		a.Logger().DebugContext(ctx, "component started", "component", c.Name)
+	}
+	for _, hook := range hooks {
+		if err := hook(ctx); err != nil {
+			return errors.Join(err, a.stopComponents(ctx))
+		}
+	}
+	return nil
+}
+
+// Stop drains the servers, runs the stop hooks, then stops the components in reverse dependency order. It goes on
+// when a step fails, and returns all errors.
+func (a *Application) Stop(ctx context.Context) error {
+	a.mu.RLock()
+	servers := slices.Clone(a.servers)
+	hooks := slices.Clone(a.onStop)
+	a.mu.RUnlock()
+	errs := make([]error, 0)
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown server %s: %w", server.Addr, err))
+		}
+	}
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i](ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	errs = append(errs, a.stopComponents(ctx))
+	return errors.Join(errs...)
+}
+
+func (a *Application) stopComponents(ctx context.Context) error {
+	a.mu.Lock()
+	started := a.started
+	a.started = nil
+	a.mu.Unlock()
+	errs := make([]error, 0)
+	for i := len(started) - 1; i >= 0; i-- {
+		c := started[i]
+		if c.Stop != nil {
+			if err := c.Stop(ctx, a); err != nil {
+				errs = append(errs, fmt.Errorf("stop %s: %w", c.Name, err))
+			}
+		}
+		a.mu.Lock()
+		delete(a.values, c.Name)
+		a.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// Run starts the application and its servers, waits for ctx to be done, a shutdown signal (SIGINT, SIGTERM) or a
+// server to fail, then stops the application within the shutdown timeout.
+func (a *Application) Run(ctx context.Context) error {
+	ctx, cancel := signal.NotifyContext(ctx, shutdownSignals...)
+	defer cancel()
+	if err := a.Start(ctx); err != nil {
+		return err
+	}
+	a.mu.RLock()
+	servers := slices.Clone(a.servers)
+	a.mu.RUnlock()
+	failed := make(chan error, len(servers))
+	for _, server := range servers {
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				failed <- fmt.Errorf("server %s: %w", server.Addr, err)
+			}
+		}()
+	}
+	var err error
+	select {
+	case <-ctx.Done():
+		a.Logger().InfoContext(ctx, "shutting down")
+	case err = <-failed:
+		a.Logger().ErrorContext(ctx, "shutting down", "error", err)
+	}
+	timeout := a.Env().ShutdownTimeout
+	if timeout == 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	shutdown, cancelShutdown := context.WithTimeout(context.WithoutCancel(ctx), time.Duration(timeout)*time.Second)
+	defer cancelShutdown()
+	return errors.Join(err, a.Stop(shutdown))
+}
+
+// loggerComponent creates the logger and makes it the default one. Stopping it flushes and closes the sinks.
+func (a *Application) loggerComponent() Component {
+	var closer io.Closer
+	var l, previous *logger.Logger
+	return Component{
+		Name: ComponentLogger,
+		Start: func(ctx context.Context, app *Application) (any, error) {
+			if a.env.Logger == nil && a.env.LogSinks == nil {
+				return logger.Default(), nil
+			}
+			var w io.Writer = os.Stderr
+			if a.env.LogSinks != nil {
+				sinks, err := sink.New(a.env.LogSinks)
+				if err != nil {
+					return nil, err
+				}
+				w, closer = sinks, sinks
+			}
+			var err error
+			l, err = logger.NewLogger(a.env.Logger, w)
+			if err != nil {
+				if closer != nil {
+					_ = closer.Close()
+				}
+				return nil, err
+			}
+			previous = logger.Default()
+			logger.SetDefault(l)
+			return l, nil
+		},
+		Stop: func(ctx context.Context, app *Application) error {
+			// The logs after the stop, e.g. of the shutdown of main, go to the previous logger instead of the closed sinks.
+			if previous != nil && logger.Default() == l {
+				logger.SetDefault(previous)
+			}
+			if closer == nil {
+				return nil
+			}
+			return closer.Close()
+		},
+	}
+}
+
+// tracingComponent installs the global tracer provider. Stopping it flushes the pending spans.
+func (a *Application) tracingComponent() Component {
+	var shutdown func(context.Context) error
+	return Component{
+		Name:      ComponentTracing,
+		DependsOn: []string{ComponentLogger},
+		Start: func(ctx context.Context, app *Application) (any, error) {
+			var err error
+			shutdown, err = tracing.Setup(ctx, a.env.Tracing)
+			return shutdown, err
+		},
+		Stop: func(ctx context.Context, app *Application) error {
+			return shutdown(ctx)
+		},
+	}
+}
+
+// redisComponent creates the Redis pool, traced if tracing is configured.
+func (a *Application) redisComponent() Component {
+	dependencies := []string{ComponentLogger}
+	if a.env.Tracing != nil {
+		dependencies = append(dependencies, ComponentTracing)
+	}
+	return Component{
+		Name:      ComponentRedis,
+		DependsOn: dependencies,
+		Start: func(ctx context.Context, app *Application) (any, error) {
+			pool := &redis.ClientPool{}
+			if a.env.Tracing != nil {
+				pool.AddHook(tracing.NewRedisHook())
+			}
+			pool.InitRedisClientPool(&a.env.Redis)
+			GlobalRedisClientPool = pool
+			return pool, nil
+		},
+		Stop: func(ctx context.Context, app *Application) error {
+			pool := app.Redis()
+			if GlobalRedisClientPool == pool {
+				GlobalRedisClientPool = nil
+			}
+			return pool.Close()
+		},
+	}
+}
+
+// mysqlComponent opens the MySQL cluster, waiting for the primary to answer.
+func (a *Application) mysqlComponent() Component {
+	return Component{
+		Name:      ComponentMySQL,
+		DependsOn: []string{ComponentLogger},
+		Start: func(ctx context.Context, app *Application) (any, error) {
+			cluster, err := mysql.OpenCluster(ctx, a.env.MySQL)
+			if err != nil {
+				return nil, err
+			}
+			for _, status := range cluster.Health() {
+				app.Logger().InfoContext(ctx, "mysql connected", "addr", status.Addr, "role", status.Role, "valid", status.Valid)
+			}
+			return cluster, nil
+		},
+		Stop: func(ctx context.Context, app *Application) error {
+			return app.MySQL().Close()
+		},
+	}
+}
+
+func (a *Application) activitiesComponent() Component {
+	return Component{
+		Name:      ComponentActivities,
+		DependsOn: []string{ComponentLogger},
+		Start: func(ctx context.Context, app *Application) (any, error) {
+			pools := make(map[string]*activity.Pool, len(a.env.Activities))
+			for name, e := range a.env.Activities {
+				pools[name] = activity.NewActivityPool(e.Limit, nil)
+			}
+			return pools, nil
+		},
+	}
+}
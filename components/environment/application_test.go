@@ -0,0 +1,313 @@
+package environment
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rhosocial/go-rush-common/components/config"
+	"github.com/rhosocial/go-rush-common/components/logger"
+	"github.com/rhosocial/go-rush-common/components/logger/sink"
+	"github.com/rhosocial/go-rush-common/components/mysql"
+	"github.com/rhosocial/go-rush-common/components/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder records the start and stop of components.
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) component(name string, dependsOn ...string) Component {
+	return Component{
+		Name:      name,
+		DependsOn: dependsOn,
+		Start: func(ctx context.Context, app *Application) (any, error) {
+			r.events = append(r.events, "start "+name)
+			return name, nil
+		},
+		Stop: func(ctx context.Context, app *Application) error {
+			r.events = append(r.events, "stop "+name)
+			return nil
+		},
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	env, err := LoadEnv([]byte(`
+Logger:
+  Level: debug
+Redis:
+  - Host: 127.0.0.1
+    Weight: 2
+Activities:
+  rooms:
+    Limit: 10
+  lobbies: {}
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, "debug", env.Logger.Level)
+	assert.NotNil(t, env.Redis[0].Dialer)
+	assert.Equal(t, uint32(10), env.Activities["rooms"].Limit)
+	assert.Equal(t, uint32(1024), env.Activities["lobbies"].Limit)
+	assert.Equal(t, "json", env.Logger.Format)
+	assert.Equal(t, uint8(5), env.Redis[0].Dialer.KeepAlive)
+
+	_, err = LoadEnv([]byte("Logger:\n  Level: verbose\n"))
+	assert.ErrorContains(t, err, "logger")
+	_, err = LoadEnv([]byte("Redis:\n  - Weight: 11\n"))
+	assert.ErrorContains(t, err, "redis server 0")
+}
+
+func TestApplication_Start(t *testing.T) {
+	t.Run("dependency order", func(t *testing.T) {
+		r := recorder{}
+		app, err := New(nil)
+		assert.NoError(t, err)
+		app.Provide(r.component("api", "cache", "db"), r.component("cache", ComponentLogger), r.component("db"))
+		app.OnStart(func(ctx context.Context) error {
+			r.events = append(r.events, "hook start")
+			return nil
+		}).OnStop(func(ctx context.Context) error {
+			r.events = append(r.events, "hook stop")
+			return nil
+		})
+		assert.NoError(t, app.Start(context.Background()))
+		assert.ErrorIs(t, app.Start(context.Background()), ErrAlreadyStarted)
+		db, err := Get[string](app, "db")
+		assert.NoError(t, err)
+		assert.Equal(t, "db", db)
+		_, err = Get[int](app, "db")
+		assert.Error(t, err)
+		assert.Same(t, logger.Default(), app.Logger())
+
+		assert.NoError(t, app.Stop(context.Background()))
+		assert.Equal(t, []string{
+			"start cache", "start db", "start api", "hook start",
+			"hook stop", "stop api", "stop db", "stop cache",
+		}, r.events)
+		_, err = Get[string](app, "db")
+		assert.ErrorIs(t, err, ErrComponentNotFound)
+	})
+
+	t.Run("default logger", func(t *testing.T) {
+		previous := logger.Default()
+		filename := filepath.Join(t.TempDir(), "app.log")
+		app, err := New(&Env{LogSinks: &sink.EnvSinks{
+			Sinks: []sink.EnvSink{{Type: "file", File: &sink.EnvFile{Filename: filename}}},
+			Async: &sink.EnvAsync{QueueSize: 16},
+		}})
+		assert.NoError(t, err)
+		assert.NoError(t, app.Start(context.Background()))
+		assert.NotSame(t, previous, logger.Default())
+		app.Logger().Info("started")
+		assert.NoError(t, app.Stop(context.Background()))
+		// The logs after the stop are not written to the closed sinks.
+		assert.Same(t, previous, logger.Default())
+		content, err := os.ReadFile(filename)
+		assert.NoError(t, err)
+		assert.Contains(t, string(content), "started")
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		r := recorder{}
+		app, _ := New(nil)
+		failing := Component{Name: "failing", DependsOn: []string{"db"}, Start: func(ctx context.Context, app *Application) (any, error) {
+			return nil, errors.New("unreachable")
+		}}
+		app.Provide(r.component("db"), failing)
+		assert.ErrorContains(t, app.Start(context.Background()), "start failing: unreachable")
+		assert.Equal(t, []string{"start db", "stop db"}, r.events)
+	})
+
+	t.Run("invalid dependencies", func(t *testing.T) {
+		r := recorder{}
+		app, _ := New(nil)
+		app.Provide(r.component("a", "b"), r.component("b", "a"))
+		assert.ErrorContains(t, app.Start(context.Background()), "dependency cycle")
+
+		app, _ = New(nil)
+		app.Provide(r.component("a", "missing"))
+		assert.ErrorIs(t, app.Start(context.Background()), ErrComponentNotFound)
+
+		app, _ = New(nil)
+		app.Provide(r.component("a"), r.component("a"))
+		assert.ErrorContains(t, app.Start(context.Background()), "provided twice")
+		assert.Empty(t, r.events)
+	})
+
+	t.Run("built-in components", func(t *testing.T) {
+		previous := logger.Default()
+		defer logger.SetDefault(previous)
+		app, err := New(&Env{
+			Logger:     &logger.EnvLogger{Level: "warn"},
+			Redis:      []redis.EnvRedisServer{{Host: "127.0.0.1", Port: 1, Weight: 1}},
+			Activities: map[string]EnvActivityPool{"rooms": {Limit: 2}},
+		})
+		assert.NoError(t, err)
+		assert.Nil(t, app.Redis())
+		assert.NoError(t, app.Start(context.Background()))
+		assert.NotSame(t, previous, app.Logger())
+		assert.Same(t, app.Logger(), logger.Default())
+		assert.Equal(t, 1, app.Redis().Len())
+		assert.Same(t, app.Redis(), GlobalRedisClientPool)
+		assert.Equal(t, uint32(2), app.ActivityPool("rooms").Limit())
+		assert.Nil(t, app.ActivityPool("missing"))
+
+		assert.NoError(t, app.Stop(context.Background()))
+		assert.Nil(t, GlobalRedisClientPool)
+		assert.Nil(t, app.Redis())
+	})
+}
+
+func TestApplication_MySQL(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	assert.NoError(t, listener.Close())
+
+	app, err := New(&Env{MySQL: []mysql.EnvMySQLServer{{
+		Host: "127.0.0.1", Port: uint16(port), Username: "root", DB: "node", Charset: "utf8mb4", Location: "Local",
+		Startup: &mysql.EnvMySQLServerStartup{Retries: 0, Interval: 100},
+	}}})
+	assert.NoError(t, err)
+	assert.Nil(t, app.MySQL())
+	assert.ErrorContains(t, app.Start(context.Background()), "start mysql")
+	assert.Nil(t, app.MySQL())
+
+	_, err = New(&Env{MySQL: []mysql.EnvMySQLServer{{Pool: &mysql.EnvMySQLServerPool{MaxOpenConns: 0}}}})
+	assert.ErrorContains(t, err, "mysql server 0")
+	_, err = New(&Env{MySQL: []mysql.EnvMySQLServer{{Role: mysql.RoleReplica}}})
+	assert.ErrorIs(t, err, mysql.ErrNoPrimary)
+}
+
+func TestApplication_ErrorHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for profile, stack := range map[Profile]bool{ProfileDev: true, ProfileProd: false, "": false} {
+		t.Run(string(profile), func(t *testing.T) {
+			app, err := New(&Env{Profile: profile})
+			assert.NoError(t, err)
+			r := gin.New()
+			r.Use(app.ErrorHandler())
+			r.GET("/panic", func(c *gin.Context) {
+				panic("boom")
+			})
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/panic", nil)
+			r.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+			assert.Equal(t, stack, strings.Contains(w.Body.String(), "goroutine"))
+		})
+	}
+}
+
+func TestApplication_Run(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := listener.Addr().String()
+	assert.NoError(t, listener.Close())
+
+	r := recorder{}
+	app, _ := New(&Env{ShutdownTimeout: 1})
+	app.Provide(r.component("db"))
+	app.AddServer(&http.Server{Addr: addr, Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- app.Run(ctx)
+	}()
+	assert.Eventually(t, func() bool {
+		resp, err := http.Get("http://" + addr)
+		if err != nil {
+			return false
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode == http.StatusNoContent
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return")
+	}
+	assert.Equal(t, []string{"start db", "stop db"}, r.events)
+	_, err = http.Get("http://" + addr)
+	assert.Error(t, err)
+}
+
+func TestApplication_Reload(t *testing.T) {
+	previous := logger.Default()
+	defer logger.SetDefault(previous)
+	old := &Env{
+		Logger:          &logger.EnvLogger{Level: "warn"},
+		Redis:           []redis.EnvRedisServer{{Host: "127.0.0.1", Port: 1, Weight: 1}},
+		ShutdownTimeout: 1,
+	}
+	app, err := New(old)
+	assert.NoError(t, err)
+	assert.NoError(t, app.Start(context.Background()))
+	defer func() {
+		assert.NoError(t, app.Stop(context.Background()))
+	}()
+	// The pool does not share the servers of the configuration: the client of the unchanged server is kept.
+	client := app.Redis().GetClient(nil)
+	old.Redis[0].Port = 4
+	assert.NoError(t, app.Redis().Reconfigure([]redis.EnvRedisServer{{Host: "127.0.0.1", Port: 1, Weight: 1}}))
+	assert.Same(t, client, app.Redis().GetClient(nil))
+	old.Redis[0].Port = 1
+
+	t.Run("applied", func(t *testing.T) {
+		new := &Env{
+			Logger:          &logger.EnvLogger{Level: "debug"},
+			Redis:           []redis.EnvRedisServer{{Host: "127.0.0.1", Port: 1, Weight: 1}, {Host: "127.0.0.1", Port: 2, Weight: 1}},
+			ShutdownTimeout: 2,
+		}
+		assert.NoError(t, app.Reload(old, new, config.Diff(old, new)))
+		assert.Equal(t, slog.LevelDebug, app.Logger().Level().Level())
+		assert.Equal(t, 2, app.Redis().Len())
+		assert.Same(t, new, app.Env())
+		old = new
+	})
+
+	t.Run("reverted", func(t *testing.T) {
+		new := &Env{
+			Logger:          &logger.EnvLogger{Level: "verbose"},
+			Redis:           []redis.EnvRedisServer{{Host: "127.0.0.1", Port: 3, Weight: 1}},
+			ShutdownTimeout: 2,
+		}
+		assert.Error(t, app.Reload(old, new, config.Diff(old, new)))
+		assert.Equal(t, slog.LevelDebug, app.Logger().Level().Level())
+		assert.Equal(t, 2, app.Redis().Len())
+		assert.Same(t, old, app.Env())
+	})
+
+	t.Run("redis not configured", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "app.log")
+		old := &Env{LogSinks: &sink.EnvSinks{Sinks: []sink.EnvSink{{Type: "file", File: &sink.EnvFile{Filename: filename}}}}}
+		app, err := New(old)
+		assert.NoError(t, err)
+		assert.NoError(t, app.Start(context.Background()))
+		new := &Env{LogSinks: old.LogSinks, Redis: []redis.EnvRedisServer{{Host: "127.0.0.1", Port: 1, Weight: 1}}}
+		assert.NoError(t, app.Reload(old, new, config.Diff(old, new)))
+		assert.Nil(t, app.Redis())
+		assert.NoError(t, app.Stop(context.Background()))
+		content, err := os.ReadFile(filename)
+		assert.NoError(t, err)
+		assert.Contains(t, string(content), "configuration changes need a restart")
+		assert.Contains(t, string(content), "Redis")
+	})
+}
@@ -1,7 +1,98 @@
+// Package environment assembles the components of an application from its configuration: the logger, the tracer
+// provider, the Redis pool, the MySQL cluster and the activity pools, along with the components of the application itself. They are
+// started in dependency order, and stopped in reverse order on graceful shutdown.
+//
+// The configuration is loaded for a Profile, dev, test, staging or prod, which selects the configuration files, the gin
+// mode, the default log format and the safety checks, see Load.
 package environment
 
 import (
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rhosocial/go-rush-common/components/config"
+	"github.com/rhosocial/go-rush-common/components/logger"
+	"github.com/rhosocial/go-rush-common/components/logger/sink"
+	"github.com/rhosocial/go-rush-common/components/mysql"
 	"github.com/rhosocial/go-rush-common/components/redis"
+	"github.com/rhosocial/go-rush-common/components/tracing"
 )
 
+// GlobalRedisClientPool is the Redis pool of the application.
+//
+// Deprecated: use Application.Redis. It is still set while the Redis component of an Application is started.
 var GlobalRedisClientPool *redis.ClientPool
+
+const DefaultShutdownTimeout uint16 = 30
+
+// EnvActivityPool defines an activity pool.
+type EnvActivityPool struct {
+	Limit uint32 `yaml:"Limit" default:"1024" validate:"min=1"`
+}
+
+// Env is the configuration of an application. Every section is optional: the components of the absent ones are not
+// created, except the logger, which falls back to logger.Default.
+type Env struct {
+	// Profile is the profile the configuration is loaded for, set by Load. Empty leaves the gin mode untouched.
+	Profile Profile           `yaml:"Profile,omitempty" validate:"omitempty,oneof=dev test staging prod"`
+	Logger  *logger.EnvLogger `yaml:"Logger,omitempty"`
+	// LogSinks are where the application logs are written to. Absent means stderr.
+	LogSinks *sink.EnvSinks         `yaml:"LogSinks,omitempty"`
+	Tracing  *tracing.EnvTracing    `yaml:"Tracing,omitempty"`
+	Redis    []redis.EnvRedisServer `yaml:"Redis,omitempty"`
+	// MySQL are a primary and its replicas, see mysql.Cluster.
+	MySQL []mysql.EnvMySQLServer `yaml:"MySQL,omitempty"`
+	// Activities are the activity pools, by name.
+	Activities map[string]EnvActivityPool `yaml:"Activities,omitempty" validate:"dive"`
+	// ShutdownTimeout is the time in seconds given to the graceful shutdown. Zero means DefaultShutdownTimeout.
+	ShutdownTimeout uint16 `yaml:"ShutdownTimeout,omitempty" default:"30"`
+}
+
+func (e *Env) Validate() error {
+	if e.Logger != nil {
+		if err := e.Logger.Validate(); err != nil {
+			return fmt.Errorf("logger: %w", err)
+		}
+	}
+	if e.LogSinks != nil {
+		if err := e.LogSinks.Validate(); err != nil {
+			return fmt.Errorf("log sinks: %w", err)
+		}
+	}
+	if e.Tracing != nil {
+		if err := e.Tracing.Validate(); err != nil {
+			return fmt.Errorf("tracing: %w", err)
+		}
+	}
+	for i := range e.Redis {
+		if err := e.Redis[i].Validate(); err != nil {
+			return fmt.Errorf("redis server %d: %w", i, err)
+		}
+	}
+	if len(e.MySQL) > 0 {
+		if err := mysql.ValidateServers(e.MySQL); err != nil {
+			return err
+		}
+	}
+	validate := validator.New()
+	return validate.Struct(e)
+}
+
+// LoadEnv parses the YAML application configuration, applies the defaults, resolves the secrets and validates it. See
+// config.Loader to load it from files, environment variables and flags.
+func LoadEnv(data []byte) (*Env, error) {
+	var e Env
+	if err := config.Decode(data, config.FormatYAML, &e); err != nil {
+		return nil, err
+	}
+	if err := config.ApplyDefaults(&e); err != nil {
+		return nil, err
+	}
+	if err := config.ResolveSecrets(&e); err != nil {
+		return nil, err
+	}
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
@@ -0,0 +1,90 @@
+package environment
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rhosocial/go-rush-common/components/config"
+	"github.com/rhosocial/go-rush-common/components/logger"
+)
+
+// ProfileVariable is the environment variable selecting the profile when none is given to Load.
+const ProfileVariable = "APP_PROFILE"
+
+// Profile is the kind of environment an application runs in. It selects the profile layers of the configuration files,
+// the gin mode, the default log format and the safety checks.
+type Profile string
+
+const (
+	ProfileDev     Profile = "dev"
+	ProfileTest    Profile = "test"
+	ProfileStaging Profile = "staging"
+	ProfileProd    Profile = "prod"
+)
+
+// ParseProfile returns the profile named s, ProfileDev if empty.
+func ParseProfile(s string) (Profile, error) {
+	switch p := Profile(s); p {
+	case "":
+		return ProfileDev, nil
+	case ProfileDev, ProfileTest, ProfileStaging, ProfileProd:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid profile %q", s)
+}
+
+// GinMode returns the gin mode of the profile: debug in dev, test in test, release otherwise.
+func (p Profile) GinMode() string {
+	switch p {
+	case ProfileDev:
+		return gin.DebugMode
+	case ProfileTest:
+		return gin.TestMode
+	}
+	return gin.ReleaseMode
+}
+
+// LogFormat returns the default log format of the profile: console in dev, json otherwise.
+func (p Profile) LogFormat() string {
+	if p == ProfileDev {
+		return logger.FormatConsole
+	}
+	return logger.FormatJSON
+}
+
+// Production reports whether the profile serves real traffic, staging or prod. Weak secrets are rejected then.
+func (p Profile) Production() bool {
+	return p == ProfileStaging || p == ProfileProd
+}
+
+// DebugStack reports whether the stack traces of the errors may be exposed in responses, in dev only.
+func (p Profile) DebugStack() bool {
+	return p == ProfileDev
+}
+
+// Load loads the configuration of the profile, ProfileVariable or dev if empty, with the loader: the defaults of the
+// profile are overlaid by the files and their profile and local layers (see config.Layers), then by the environment
+// variables and overrides of the loader. In production profiles, weak secrets are rejected.
+func Load(loader *config.Loader, profile Profile) (*Env, error) {
+	if profile == "" {
+		var err error
+		if profile, err = ParseProfile(os.Getenv(ProfileVariable)); err != nil {
+			return nil, fmt.Errorf("%s: %w", ProfileVariable, err)
+		}
+	} else if _, err := ParseProfile(string(profile)); err != nil {
+		return nil, err
+	}
+	l := *loader
+	l.Profile = string(profile)
+	l.Production = l.Production || profile.Production()
+	e := Env{Logger: &logger.EnvLogger{Format: profile.LogFormat()}}
+	if err := l.Load(&e); err != nil {
+		return nil, err
+	}
+	e.Profile = profile
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
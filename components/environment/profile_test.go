@@ -0,0 +1,86 @@
+package environment
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rhosocial/go-rush-common/components/config"
+	"github.com/rhosocial/go-rush-common/components/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProfile(t *testing.T) {
+	p, err := ParseProfile("")
+	assert.NoError(t, err)
+	assert.Equal(t, ProfileDev, p)
+	_, err = ParseProfile("production")
+	assert.Error(t, err)
+
+	assert.Equal(t, gin.DebugMode, ProfileDev.GinMode())
+	assert.Equal(t, gin.TestMode, ProfileTest.GinMode())
+	assert.Equal(t, gin.ReleaseMode, ProfileStaging.GinMode())
+	assert.Equal(t, logger.FormatConsole, ProfileDev.LogFormat())
+	assert.Equal(t, logger.FormatJSON, ProfileProd.LogFormat())
+	assert.True(t, ProfileDev.DebugStack())
+	assert.False(t, ProfileProd.DebugStack())
+	assert.False(t, ProfileTest.Production())
+	assert.True(t, ProfileStaging.Production())
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "config.yaml")
+	assert.NoError(t, os.WriteFile(filename, []byte("Redis:\n  - Host: 127.0.0.1\n    Password: ${env:TEST_REDIS_PASSWORD}\n"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "config.prod.yaml"), []byte("ShutdownTimeout: 60\n"), 0o600))
+	t.Setenv("TEST_REDIS_PASSWORD", "123456")
+	loader := &config.Loader{Files: []string{filename}}
+
+	t.Run("dev", func(t *testing.T) {
+		t.Setenv(ProfileVariable, "")
+		env, err := Load(loader, "")
+		assert.NoError(t, err)
+		assert.Equal(t, ProfileDev, env.Profile)
+		assert.Equal(t, logger.FormatConsole, env.Logger.Format)
+		assert.Equal(t, uint16(DefaultShutdownTimeout), env.ShutdownTimeout)
+		assert.Equal(t, "123456", env.Redis[0].Password)
+		assert.Empty(t, loader.Profile, "the loader is left untouched")
+
+		dump, err := config.Dump(env)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(dump), "123456")
+		assert.Contains(t, string(dump), "Profile: dev")
+
+		previous := gin.Mode()
+		defer gin.SetMode(previous)
+		gin.SetMode(gin.ReleaseMode)
+		env.Logger = nil
+		app, _ := New(env)
+		assert.NoError(t, app.Start(context.Background()))
+		assert.Equal(t, gin.DebugMode, gin.Mode())
+		assert.NoError(t, app.Stop(context.Background()))
+	})
+
+	t.Run("prod", func(t *testing.T) {
+		t.Setenv(ProfileVariable, "prod")
+		_, err := Load(loader, "")
+		assert.ErrorContains(t, err, "Redis[0].Password: is a weak secret")
+
+		t.Setenv("TEST_REDIS_PASSWORD", "k8Jq-2mZ")
+		env, err := Load(loader, "")
+		assert.NoError(t, err)
+		assert.Equal(t, ProfileProd, env.Profile)
+		assert.Equal(t, logger.FormatJSON, env.Logger.Format)
+		assert.Equal(t, uint16(60), env.ShutdownTimeout)
+	})
+
+	t.Run("invalid profile", func(t *testing.T) {
+		_, err := Load(loader, "production")
+		assert.Error(t, err)
+		t.Setenv(ProfileVariable, "production")
+		_, err = Load(loader, "")
+		assert.ErrorContains(t, err, ProfileVariable)
+	})
+}
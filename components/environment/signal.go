@@ -0,0 +1,10 @@
+//go:build !plan9
+
+package environment
+
+import (
+	"os"
+	"syscall"
+)
+
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
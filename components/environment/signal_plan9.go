@@ -0,0 +1,5 @@
+package environment
+
+import "os"
+
+var shutdownSignals = []os.Signal{os.Interrupt}
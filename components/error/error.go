@@ -1,14 +1,34 @@
 package error
 
 import (
+	"fmt"
 	"net/http"
+	"runtime/debug"
 
 	"github.com/gin-gonic/gin"
 	response2 "github.com/rhosocial/go-rush-common/components/response"
 )
 
+// ErrorHandlerConfig defines the ErrorHandler middleware.
+type ErrorHandlerConfig struct {
+	// Stack adds the panic and its stack trace to the extension of the response. Only meant for development, see
+	// environment.Application.ErrorHandler, which sets it for the dev profile.
+	Stack bool
+}
+
+// PanicExtension is the extension of the responses to panics when ErrorHandlerConfig.Stack is set.
+type PanicExtension struct {
+	Panic string `json:"panic"`
+	Stack string `json:"stack"`
+}
+
 // ErrorHandler 定义一个中间件，用于捕获错误并统一返回
 func ErrorHandler() gin.HandlerFunc {
+	return ErrorHandlerWithConfig(ErrorHandlerConfig{})
+}
+
+// ErrorHandlerWithConfig returns the ErrorHandler middleware with the configuration.
+func ErrorHandlerWithConfig(config ErrorHandlerConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 使用 defer 来捕获 panic
 		defer func() {
@@ -20,6 +40,9 @@ func ErrorHandler() gin.HandlerFunc {
 						Message: http.StatusText(http.StatusInternalServerError),
 					},
 				}
+				if config.Stack {
+					response.Extension = PanicExtension{Panic: fmt.Sprint(err), Stack: string(debug.Stack())}
+				}
 
 				// 返回错误响应
 				c.JSON(http.StatusInternalServerError, response)
@@ -1,7 +1,9 @@
 package error
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -54,3 +56,31 @@ func TestErrorHandler(t *testing.T) {
 		assert.Equal(t, true, useNext)
 	})
 }
+
+func TestErrorHandlerWithConfig(t *testing.T) {
+	for _, stack := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stack %v", stack), func(t *testing.T) {
+			r := gin.New()
+			r.Use(ErrorHandlerWithConfig(ErrorHandlerConfig{Stack: stack}))
+			r.GET("/ping_error", func(c *gin.Context) {
+				panic(errors.New("ping error occurred"))
+			})
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/ping_error", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+			var body struct {
+				Extension *PanicExtension `json:"ext"`
+			}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			if !stack {
+				assert.Nil(t, body.Extension)
+				return
+			}
+			assert.Equal(t, "ping error occurred", body.Extension.Panic)
+			assert.Contains(t, body.Extension.Stack, "TestErrorHandlerWithConfig")
+		})
+	}
+}
@@ -0,0 +1,145 @@
+// Package admin provides the routes adjusting the levels of a logger.Logger at runtime.
+//
+// The routes change what the whole process logs, so they must be registered on a group protected by an
+// authentication middleware:
+//
+//	admin.RegisterRoutes(r.Group("/admin", auth.AuthRequired()), logger.Default())
+package admin
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rhosocial/go-rush-common/components/logger"
+	"github.com/rhosocial/go-rush-common/components/response"
+)
+
+const (
+	// PathLevels is the route of the levels, relative to the group the routes are registered on.
+	PathLevels = "/log/levels"
+	// PathOverrides is the route of the per-request overrides.
+	PathOverrides = "/log/overrides"
+)
+
+// DefaultOverrideTTL is the TTL of an override requested without one.
+const DefaultOverrideTTL = 10 * time.Minute
+
+var errNegativeTTL = errors.New("ttl must not be negative")
+
+const (
+	CodeSuccess        = 0
+	CodeInvalidRequest = 1
+)
+
+// LevelRequest changes the global level, or the level of Package if set.
+type LevelRequest struct {
+	Level   string `json:"level" binding:"required,oneof=debug info warn error"`
+	Package string `json:"package"`
+	// TTL, e.g. `15m`, restores the previous level once elapsed. Empty makes the change permanent.
+	TTL string `json:"ttl"`
+}
+
+// OverrideRequest raises the verbosity of the requests with RequestID or from ClientIP.
+type OverrideRequest struct {
+	RequestID string `json:"request_id" binding:"required_without=ClientIP"`
+	ClientIP  string `json:"client_ip" binding:"omitempty,ip"`
+	// Level defaults to debug.
+	Level string `json:"level" binding:"omitempty,oneof=debug info warn error"`
+	// TTL, e.g. `15m`, defaults to DefaultOverrideTTL.
+	TTL string `json:"ttl"`
+}
+
+// RegisterRoutes registers on r:
+//
+//   - GET PathLevels, returning the logger.LevelStatus;
+//   - PUT PathLevels, changing a level with a LevelRequest;
+//   - DELETE PathLevels, resetting the levels to the configured ones and removing the overrides;
+//   - POST PathOverrides, adding an override with an OverrideRequest;
+//   - DELETE PathOverrides, removing the overrides.
+func RegisterRoutes(r gin.IRouter, l *logger.Logger) {
+	r.GET(PathLevels, func(c *gin.Context) {
+		c.JSON(http.StatusOK, response.NewGeneric[logger.LevelStatus, any](c, CodeSuccess, "ok", l.LevelStatus(), nil))
+	})
+	r.PUT(PathLevels, func(c *gin.Context) {
+		var req LevelRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			invalid(c, err)
+			return
+		}
+		level, err := logger.ParseLevel(req.Level)
+		if err != nil {
+			invalid(c, err)
+			return
+		}
+		ttl, err := parseTTL(req.TTL, 0)
+		if err != nil {
+			invalid(c, err)
+			return
+		}
+		if req.Package == "" {
+			l.SetLevel(level, ttl)
+		} else {
+			l.SetPackageLevel(req.Package, level, ttl)
+		}
+		l.WarnContext(c, "log level changed", "level", level, "package", req.Package, "ttl", ttl)
+		c.JSON(http.StatusOK, response.NewGeneric[logger.LevelStatus, any](c, CodeSuccess, "ok", l.LevelStatus(), nil))
+	})
+	r.DELETE(PathLevels, func(c *gin.Context) {
+		l.Reset()
+		l.WarnContext(c, "log levels reset")
+		c.JSON(http.StatusOK, response.NewGeneric[logger.LevelStatus, any](c, CodeSuccess, "ok", l.LevelStatus(), nil))
+	})
+	r.POST(PathOverrides, func(c *gin.Context) {
+		var req OverrideRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			invalid(c, err)
+			return
+		}
+		level := slog.LevelDebug
+		if req.Level != "" {
+			var err error
+			if level, err = logger.ParseLevel(req.Level); err != nil {
+				invalid(c, err)
+				return
+			}
+		}
+		ttl, err := parseTTL(req.TTL, DefaultOverrideTTL)
+		if err != nil {
+			invalid(c, err)
+			return
+		}
+		override := l.AddOverride(logger.Override{
+			RequestID: logger.RequestID(req.RequestID),
+			ClientIP:  req.ClientIP,
+			Level:     level,
+		}, ttl)
+		l.WarnContext(c, "log level override added", "override", override)
+		c.JSON(http.StatusCreated, response.NewGeneric[logger.Override, any](c, CodeSuccess, "ok", override, nil))
+	})
+	r.DELETE(PathOverrides, func(c *gin.Context) {
+		l.ClearOverrides()
+		l.WarnContext(c, "log level overrides removed")
+		c.JSON(http.StatusOK, response.NewGeneric[logger.LevelStatus, any](c, CodeSuccess, "ok", l.LevelStatus(), nil))
+	})
+}
+
+func parseTTL(s string, defaultTTL time.Duration) (time.Duration, error) {
+	if s == "" {
+		return defaultTTL, nil
+	}
+	ttl, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, errNegativeTTL
+	}
+	return ttl, nil
+}
+
+func invalid(c *gin.Context, err error) {
+	c.AbortWithStatusJSON(http.StatusBadRequest, response.NewGeneric[any, any](c, CodeInvalidRequest, err.Error(), nil, nil))
+}
@@ -0,0 +1,94 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rhosocial/go-rush-common/components/logger"
+	"github.com/rhosocial/go-rush-common/components/response"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupRouterAdmin(t *testing.T) (*gin.Engine, *logger.Logger) {
+	gin.SetMode(gin.TestMode)
+	l, err := logger.NewLogger(nil, &bytes.Buffer{})
+	assert.NoError(t, err)
+	r := gin.New()
+	r.Use(logger.AppendRequestID())
+	RegisterRoutes(r.Group("/admin"), l)
+	return r, l
+}
+
+func request(r *gin.Engine, method string, path string, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRegisterRoutes(t *testing.T) {
+	t.Run("levels", func(t *testing.T) {
+		r, l := setupRouterAdmin(t)
+
+		w := request(r, http.MethodPut, "/admin"+PathLevels, `{"level":"debug","package":"redis","ttl":"1h"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, slog.LevelDebug, l.LevelStatus().Packages["redis"])
+
+		w = request(r, http.MethodPut, "/admin"+PathLevels, `{"level":"error"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, slog.LevelError, l.Level().Level())
+
+		w = request(r, http.MethodGet, "/admin"+PathLevels, "")
+		var resp response.Generic[logger.LevelStatus, any]
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, slog.LevelError, resp.Data.Level)
+		assert.Equal(t, slog.LevelDebug, resp.Data.Packages["redis"])
+
+		w = request(r, http.MethodDelete, "/admin"+PathLevels, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, slog.LevelInfo, l.Level().Level())
+		assert.Empty(t, l.LevelStatus().Packages)
+	})
+
+	t.Run("invalid level requests", func(t *testing.T) {
+		r, _ := setupRouterAdmin(t)
+		for _, body := range []string{`{}`, `{"level":"verbose"}`, `{"level":"debug","ttl":"soon"}`, `{"level":"debug","ttl":"-1m"}`} {
+			w := request(r, http.MethodPut, "/admin"+PathLevels, body)
+			assert.Equal(t, http.StatusBadRequest, w.Code, body)
+			var resp response.Base
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, uint32(CodeInvalidRequest), resp.Code)
+		}
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		r, l := setupRouterAdmin(t)
+
+		w := request(r, http.MethodPost, "/admin"+PathOverrides, `{"request_id":"traced"}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var resp response.Generic[logger.Override, any]
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, logger.RequestID("traced"), resp.Data.RequestID)
+		assert.Equal(t, slog.LevelDebug, resp.Data.Level)
+
+		w = request(r, http.MethodPost, "/admin"+PathOverrides, `{"client_ip":"10.0.0.1","level":"info","ttl":"5m"}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Len(t, l.LevelStatus().Overrides, 2)
+
+		for _, body := range []string{`{}`, `{"client_ip":"localhost"}`, `{"request_id":"x","ttl":"soon"}`} {
+			w = request(r, http.MethodPost, "/admin"+PathOverrides, body)
+			assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+
+		w = request(r, http.MethodDelete, "/admin"+PathOverrides, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, l.LevelStatus().Overrides)
+	})
+}
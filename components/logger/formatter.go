@@ -0,0 +1,190 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/rhosocial/go-rush-common/components/redact"
+)
+
+const (
+	AccessLogFormatText   = "text"
+	AccessLogFormatJSON   = "json"
+	AccessLogFormatLogfmt = "logfmt"
+)
+
+// The default field names of the structured access log formats.
+const (
+	FieldTime      = "time"
+	FieldRequestID = "request_id"
+	FieldStatus    = "status"
+	FieldLatency   = "latency_ms"
+	FieldClientIP  = "client_ip"
+	FieldMethod    = "method"
+	FieldPath      = "path"
+	FieldRoute     = "route"
+	FieldUserAgent = "user_agent"
+	FieldBytesIn   = "bytes_in"
+	FieldBytesOut  = "bytes_out"
+	FieldError     = "error"
+)
+
+// EnvAccessLog defines the access log format.
+type EnvAccessLog struct {
+	Format string `yaml:"Format,omitempty" default:"text" validate:"omitempty,oneof=text json logfmt"`
+	// Fields renames the fields of the json and logfmt formats, e.g. `latency_ms: duration`.
+	// Renaming a field to "-" omits it.
+	Fields map[string]string `yaml:"Fields,omitempty"`
+	// Redact lists the query parameters to mask in the path in addition to the default ones.
+	Redact *redact.EnvRedact `yaml:"Redact,omitempty"`
+}
+
+func (e *EnvAccessLog) Validate() error {
+	validate := validator.New()
+	return validate.Struct(e)
+}
+
+// NewLogFormatter returns the access log formatter selected by the configuration.
+// A nil configuration selects the default [GO-RUSH] text format.
+func NewLogFormatter(e *EnvAccessLog) (gin.LogFormatter, error) {
+	if e == nil {
+		return LogFormatter, nil
+	}
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	redactor := redact.NewFromEnv(e.Redact)
+	names := make(map[string]string, len(e.Fields))
+	for field, name := range e.Fields {
+		names[field] = name
+	}
+	switch e.Format {
+	case "", AccessLogFormatText:
+		return LogFormatter, nil
+	case AccessLogFormatJSON:
+		return func(param gin.LogFormatterParams) string {
+			return formatJSON(accessLogFields(param, names, redactor))
+		}, nil
+	case AccessLogFormatLogfmt:
+		return func(param gin.LogFormatterParams) string {
+			return formatLogfmt(accessLogFields(param, names, redactor))
+		}, nil
+	}
+	return nil, fmt.Errorf("invalid access log format %q", e.Format)
+}
+
+// JSONLogFormatter formats access log entries as JSON objects with the default field names.
+func JSONLogFormatter(param gin.LogFormatterParams) string {
+	return formatJSON(accessLogFields(param, nil, redact.Default))
+}
+
+// LogfmtLogFormatter formats access log entries as logfmt lines with the default field names.
+func LogfmtLogFormatter(param gin.LogFormatterParams) string {
+	return formatLogfmt(accessLogFields(param, nil, redact.Default))
+}
+
+type accessLogField struct {
+	key   string
+	value any
+}
+
+func accessLogFields(param gin.LogFormatterParams, names map[string]string, redactor *redact.Redactor) []accessLogField {
+	fields := make([]accessLogField, 0, 12)
+	add := func(key string, value any) {
+		if name, ok := names[key]; ok {
+			if name == "-" || name == "" {
+				return
+			}
+			key = name
+		}
+		fields = append(fields, accessLogField{key, value})
+	}
+	add(FieldTime, param.TimeStamp.Format(time.RFC3339Nano))
+	add(FieldRequestID, param.Keys[ContextRequestID])
+	add(FieldStatus, param.StatusCode)
+	add(FieldLatency, float64(param.Latency.Microseconds())/1000)
+	add(FieldClientIP, param.ClientIP)
+	add(FieldMethod, param.Method)
+	add(FieldPath, redactor.URL(param.Path))
+	route, _ := param.Keys[ContextRoute].(string)
+	add(FieldRoute, route)
+	userAgent, bytesIn := "", int64(0)
+	if param.Request != nil {
+		userAgent = param.Request.UserAgent()
+		if param.Request.ContentLength > 0 {
+			bytesIn = param.Request.ContentLength
+		}
+	}
+	add(FieldUserAgent, userAgent)
+	add(FieldBytesIn, bytesIn)
+	add(FieldBytesOut, param.BodySize)
+	add(FieldError, strings.TrimSpace(param.ErrorMessage))
+	return fields
+}
+
+func formatJSON(fields []accessLogField) string {
+	buffer := bytes.Buffer{}
+	buffer.WriteByte('{')
+	for i, field := range fields {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		key, _ := json.Marshal(field.key)
+		buffer.Write(key)
+		buffer.WriteByte(':')
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			value, _ = json.Marshal(fmt.Sprint(field.value))
+		}
+		buffer.Write(value)
+	}
+	buffer.WriteString("}\n")
+	return buffer.String()
+}
+
+func formatLogfmt(fields []accessLogField) string {
+	buffer := bytes.Buffer{}
+	for i, field := range fields {
+		if i > 0 {
+			buffer.WriteByte(' ')
+		}
+		buffer.WriteString(field.key)
+		buffer.WriteByte('=')
+		var value string
+		switch v := field.value.(type) {
+		case nil:
+			value = ""
+		case string:
+			value = v
+		case float64:
+			value = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			value = fmt.Sprint(v)
+		}
+		if logfmtNeedsQuoting(value) {
+			value = strconv.Quote(value)
+		}
+		buffer.WriteString(value)
+	}
+	buffer.WriteByte('\n')
+	return buffer.String()
+}
+
+func logfmtNeedsQuoting(value string) bool {
+	if value == "" {
+		return true
+	}
+	for _, r := range value {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			return true
+		}
+	}
+	return false
+}
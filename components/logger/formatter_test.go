@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupRouterAccessLog(t *testing.T, e *EnvAccessLog, buffer *bytes.Buffer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	formatter, err := NewLogFormatter(e)
+	assert.NoError(t, err)
+	r := gin.New()
+	r.Use(AppendRequestID(), gin.LoggerWithConfig(gin.LoggerConfig{Formatter: formatter, Output: buffer}))
+	r.POST("/users/:id", func(c *gin.Context) {
+		c.String(http.StatusCreated, "created")
+	})
+	return r
+}
+
+func serveAccessLog(r *gin.Engine) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/users/1?verbose=1", strings.NewReader("body"))
+	req.Header.Set("User-Agent", "go-rush test")
+	r.ServeHTTP(w, req)
+}
+
+func TestNewLogFormatter(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		buffer := bytes.Buffer{}
+		serveAccessLog(setupRouterAccessLog(t, &EnvAccessLog{Format: AccessLogFormatJSON}, &buffer))
+
+		entry := make(map[string]any)
+		assert.NoError(t, json.Unmarshal(buffer.Bytes(), &entry))
+		assert.NotEmpty(t, entry[FieldRequestID])
+		assert.Equal(t, float64(http.StatusCreated), entry[FieldStatus])
+		assert.Equal(t, http.MethodPost, entry[FieldMethod])
+		assert.Equal(t, "/users/1?verbose=1", entry[FieldPath])
+		assert.Equal(t, "/users/:id", entry[FieldRoute])
+		assert.Equal(t, "go-rush test", entry[FieldUserAgent])
+		assert.Equal(t, float64(4), entry[FieldBytesIn])
+		assert.Equal(t, float64(7), entry[FieldBytesOut])
+		assert.Contains(t, entry, FieldLatency)
+		assert.Contains(t, entry, FieldClientIP)
+		assert.Equal(t, "", entry[FieldError])
+	})
+
+	t.Run("json with renamed and omitted fields", func(t *testing.T) {
+		buffer := bytes.Buffer{}
+		e := EnvAccessLog{Format: AccessLogFormatJSON, Fields: map[string]string{FieldLatency: "duration", FieldUserAgent: "-"}}
+		serveAccessLog(setupRouterAccessLog(t, &e, &buffer))
+
+		entry := make(map[string]any)
+		assert.NoError(t, json.Unmarshal(buffer.Bytes(), &entry))
+		assert.Contains(t, entry, "duration")
+		assert.NotContains(t, entry, FieldLatency)
+		assert.NotContains(t, entry, FieldUserAgent)
+	})
+
+	t.Run("logfmt", func(t *testing.T) {
+		buffer := bytes.Buffer{}
+		serveAccessLog(setupRouterAccessLog(t, &EnvAccessLog{Format: AccessLogFormatLogfmt}, &buffer))
+
+		line := buffer.String()
+		assert.True(t, strings.HasSuffix(line, "\n"))
+		assert.Contains(t, line, " status=201 ")
+		assert.Contains(t, line, " method=POST ")
+		assert.Contains(t, line, ` path="/users/1?verbose=1" `)
+		assert.Contains(t, line, ` user_agent="go-rush test" `)
+		assert.Contains(t, line, ` error=""`)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		buffer := bytes.Buffer{}
+		serveAccessLog(setupRouterAccessLog(t, nil, &buffer))
+		assert.True(t, strings.HasPrefix(buffer.String(), "[GO-RUSH] "))
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		_, err := NewLogFormatter(&EnvAccessLog{Format: "xml"})
+		assert.Error(t, err)
+	})
+}
+
+func TestLogFormatter_Redact(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, e := range []*EnvAccessLog{nil, {Format: AccessLogFormatJSON}, {Format: AccessLogFormatLogfmt}} {
+		buffer := bytes.Buffer{}
+		r := setupRouterAccessLog(t, e, &buffer)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/users/1?token=secret-token&page=2", nil)
+		r.ServeHTTP(w, req)
+		assert.NotContains(t, buffer.String(), "secret-token")
+		assert.Contains(t, buffer.String(), "page=2")
+	}
+}
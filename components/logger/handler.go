@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handler filters entries by global or package level, unless an override matches the request, samples them, and appends the request-scoped fields.
+type handler struct {
+	inner       slog.Handler
+	level       *slog.LevelVar
+	sampler     *sampler
+	packageName string
+	packages    *sync.Map
+	overrides   *overrides
+}
+
+var _ slog.Handler = (*handler)(nil)
+
+func (h *handler) minLevel() slog.Level {
+	if h.packages != nil {
+		if level, ok := h.packages.Load(h.packageName); ok {
+			return level.(*slog.LevelVar).Level()
+		}
+	}
+	return h.level.Level()
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.minLevel() || h.overrides.match(ctx, level)
+}
+
+func (h *handler) Handle(ctx context.Context, record slog.Record) error {
+	if h.sampler != nil && !h.sampler.allow(record.Time, record.Level, record.Message) {
+		return nil
+	}
+	record.AddAttrs(RequestAttrs(ctx)...)
+	return h.inner.Handle(ctx, record)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.inner = h.inner.WithAttrs(attrs)
+	return &c
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.inner = h.inner.WithGroup(name)
+	return &c
+}
+
+// RequestAttrs returns the request ID, principal and route carried by the context.
+// The context must be a *gin.Context, or derived from one.
+func RequestAttrs(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	c, ok := ctx.Value(gin.ContextKey).(*gin.Context)
+	if !ok || c == nil {
+		return nil
+	}
+	attrs := make([]slog.Attr, 0, 3)
+	if requestID, exists := c.Get(ContextRequestID); exists {
+		attrs = append(attrs, slog.Any(AttrRequestID, requestID))
+	}
+	if principal, exists := c.Get(ContextPrincipal); exists {
+		attrs = append(attrs, slog.Any(AttrPrincipal, principal))
+	}
+	if route := c.FullPath(); route != "" {
+		attrs = append(attrs, slog.String(AttrRoute, route))
+	}
+	return attrs
+}
@@ -0,0 +1,284 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Override raises the verbosity of the requests with the given request ID or from the given client IP until it
+// expires. At least one of RequestID and ClientIP must be set.
+type Override struct {
+	RequestID RequestID  `json:"request_id,omitempty"`
+	ClientIP  string     `json:"client_ip,omitempty"`
+	Level     slog.Level `json:"level"`
+	ExpiresAt time.Time  `json:"expires_at"`
+}
+
+// LevelStatus describes the current levels of a Logger.
+type LevelStatus struct {
+	Level     slog.Level            `json:"level"`
+	Packages  map[string]slog.Level `json:"packages,omitempty"`
+	Overrides []Override            `json:"overrides,omitempty"`
+}
+
+// overrides holds the active overrides. Expired ones are ignored, and removed when their TTL elapses.
+type overrides struct {
+	mu     sync.RWMutex
+	active atomic.Int32
+	list   []Override
+}
+
+func (o *overrides) add(override Override) {
+	o.mu.Lock()
+	o.list = append(o.list, override)
+	o.active.Store(int32(len(o.list)))
+	o.mu.Unlock()
+	time.AfterFunc(time.Until(override.ExpiresAt), o.prune)
+}
+
+func (o *overrides) prune() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	now := time.Now()
+	list := o.list[:0]
+	for _, override := range o.list {
+		if override.ExpiresAt.After(now) {
+			list = append(list, override)
+		}
+	}
+	clear(o.list[len(list):])
+	o.list = list
+	o.active.Store(int32(len(o.list)))
+}
+
+func (o *overrides) clear() {
+	o.mu.Lock()
+	o.list = nil
+	o.active.Store(0)
+	o.mu.Unlock()
+}
+
+func (o *overrides) snapshot() []Override {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	now := time.Now()
+	result := make([]Override, 0, len(o.list))
+	for _, override := range o.list {
+		if override.ExpiresAt.After(now) {
+			result = append(result, override)
+		}
+	}
+	return result
+}
+
+// match reports whether an active override of the request the context belongs to enables the level.
+func (o *overrides) match(ctx context.Context, level slog.Level) bool {
+	if o == nil || o.active.Load() == 0 || ctx == nil {
+		return false
+	}
+	c, ok := ctx.Value(gin.ContextKey).(*gin.Context)
+	if !ok || c == nil || c.Request == nil {
+		return false
+	}
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	now := time.Now()
+	requestID := GetRequestID(c)
+	clientIP := ""
+	for _, override := range o.list {
+		if level < override.Level || !override.ExpiresAt.After(now) {
+			continue
+		}
+		if override.RequestID != "" && override.RequestID == requestID {
+			return true
+		}
+		if override.ClientIP != "" {
+			if clientIP == "" {
+				clientIP = c.ClientIP()
+			}
+			if override.ClientIP == clientIP {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// revert restores a level changed temporarily. A package level that did not exist before is removed instead, so that
+// the package follows the global level again.
+type revert struct {
+	timer    *time.Timer
+	previous slog.Level
+	existed  bool
+}
+
+// SetLevel changes the global level. A positive ttl restores the previous level once it elapses; changing the level
+// again before then keeps restoring the level preceding the first temporary change.
+func (l *Logger) SetLevel(level slog.Level, ttl time.Duration) {
+	l.setLevel("", false, level, ttl)
+}
+
+// SetPackageLevel changes the level of the named package, like SetLevel.
+func (l *Logger) SetPackageLevel(name string, level slog.Level, ttl time.Duration) {
+	l.setLevel(name, true, level, ttl)
+}
+
+func (l *Logger) setLevel(name string, isPackage bool, level slog.Level, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := "global"
+	v, existed := l.level, true
+	if isPackage {
+		key = "package:" + name
+		_, existed = l.packages.Load(name)
+		v = l.packageOverride(name)
+	}
+	r := &revert{previous: v.Level(), existed: existed}
+	if current, ok := l.reverts[key]; ok {
+		current.timer.Stop()
+		r.previous, r.existed = current.previous, current.existed
+		delete(l.reverts, key)
+	}
+	v.Set(level)
+	if ttl <= 0 {
+		return
+	}
+	r.timer = time.AfterFunc(ttl, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.reverts[key] != r {
+			return
+		}
+		delete(l.reverts, key)
+		if !r.existed {
+			l.packages.Delete(name)
+			return
+		}
+		v.Set(r.previous)
+	})
+	l.reverts[key] = r
+}
+
+// AddOverride logs the entries of the requests matching the override from its level on, whatever the global and
+// package levels, for ttl. The entries must be logged with the *gin.Context of the request, or a context derived from
+// it.
+func (l *Logger) AddOverride(override Override, ttl time.Duration) Override {
+	override.ExpiresAt = time.Now().Add(ttl)
+	l.overrides.add(override)
+	return override
+}
+
+// ClearOverrides removes all overrides.
+func (l *Logger) ClearOverrides() {
+	l.overrides.clear()
+}
+
+// LevelStatus returns the global level, the package levels and the active overrides.
+func (l *Logger) LevelStatus() LevelStatus {
+	status := LevelStatus{Level: l.level.Level(), Packages: make(map[string]slog.Level), Overrides: l.overrides.snapshot()}
+	l.packages.Range(func(name, level any) bool {
+		status.Packages[name.(string)] = level.(*slog.LevelVar).Level()
+		return true
+	})
+	sort.Slice(status.Overrides, func(i, j int) bool {
+		return status.Overrides[i].ExpiresAt.Before(status.Overrides[j].ExpiresAt)
+	})
+	return status
+}
+
+// ApplyEnv makes the levels of the configuration the configured ones, and resets to them.
+func (l *Logger) ApplyEnv(e *EnvLogger) error {
+	level, err := ParseLevel(e.Level)
+	if err != nil {
+		return err
+	}
+	packages := make(map[string]slog.Level, len(e.Packages))
+	for name, value := range e.Packages {
+		if packages[name], err = ParseLevel(value); err != nil {
+			return err
+		}
+	}
+	l.mu.Lock()
+	l.configured, l.configuredPackages = level, packages
+	l.mu.Unlock()
+	l.Reset()
+	return nil
+}
+
+// Reset cancels all temporary and runtime changes: the configured levels are restored, the packages without a
+// configured level follow the global level again, and the overrides are removed.
+func (l *Logger) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, r := range l.reverts {
+		r.timer.Stop()
+		delete(l.reverts, key)
+	}
+	l.level.Set(l.configured)
+	l.packages.Range(func(name, level any) bool {
+		if configured, ok := l.configuredPackages[name.(string)]; ok {
+			level.(*slog.LevelVar).Set(configured)
+		} else {
+			l.packages.Delete(name)
+		}
+		return true
+	})
+	for name, configured := range l.configuredPackages {
+		if _, ok := l.packages.Load(name); !ok {
+			level := new(slog.LevelVar)
+			level.Set(configured)
+			l.packages.Store(name, level)
+		}
+	}
+	l.overrides.clear()
+}
+
+// DefaultSignalTTL is how long SIGUSR1 lowers the global level when SignalConfig.TTL is zero.
+const DefaultSignalTTL = 10 * time.Minute
+
+// SignalConfig defines the behavior of WatchSignals.
+type SignalConfig struct {
+	// Level is the global level set on SIGUSR1. Nil means debug.
+	Level slog.Leveler
+	// TTL is how long the level set on SIGUSR1 lasts. Zero means DefaultSignalTTL.
+	TTL time.Duration
+	// Reload, if present, returns the configuration to apply on SIGHUP, e.g. re-read from the configuration file.
+	Reload func() (*EnvLogger, error)
+}
+
+func (config *SignalConfig) defaults() {
+	if config.Level == nil {
+		config.Level = slog.LevelDebug
+	}
+	if config.TTL == 0 {
+		config.TTL = DefaultSignalTTL
+	}
+}
+
+func (config *SignalConfig) raise(l *Logger) {
+	l.SetLevel(config.Level.Level(), config.TTL)
+	l.Warn("log level changed by signal", "level", config.Level.Level(), "ttl", config.TTL)
+}
+
+func (config *SignalConfig) reset(l *Logger) {
+	if config.Reload == nil {
+		l.Reset()
+		l.Warn("log levels reset by signal")
+		return
+	}
+	e, err := config.Reload()
+	if err == nil {
+		err = l.ApplyEnv(e)
+	}
+	if err != nil {
+		l.Error("failed to reload log levels", "error", err)
+		return
+	}
+	l.Warn("log levels reloaded by signal", "level", l.Level().Level())
+}
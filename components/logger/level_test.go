@@ -0,0 +1,135 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger_SetLevel(t *testing.T) {
+	t.Run("global with ttl", func(t *testing.T) {
+		l, err := NewLogger(nil, &bytes.Buffer{})
+		assert.NoError(t, err)
+		l.SetLevel(slog.LevelDebug, 50*time.Millisecond)
+		l.SetLevel(slog.LevelWarn, 50*time.Millisecond)
+		assert.Equal(t, slog.LevelWarn, l.Level().Level())
+		assert.Eventually(t, func() bool {
+			return l.Level().Level() == slog.LevelInfo
+		}, time.Second, 10*time.Millisecond, "the level preceding the first temporary change should be restored")
+	})
+
+	t.Run("global permanent", func(t *testing.T) {
+		l, err := NewLogger(nil, &bytes.Buffer{})
+		assert.NoError(t, err)
+		l.SetLevel(slog.LevelDebug, 20*time.Millisecond)
+		l.SetLevel(slog.LevelError, 0)
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, slog.LevelError, l.Level().Level())
+	})
+
+	t.Run("package with ttl", func(t *testing.T) {
+		buffer := bytes.Buffer{}
+		l, err := NewLogger(&EnvLogger{Packages: map[string]string{"redis": "warn"}}, &buffer)
+		assert.NoError(t, err)
+		l.SetPackageLevel("redis", slog.LevelDebug, 50*time.Millisecond)
+		l.SetPackageLevel("mysql", slog.LevelDebug, 50*time.Millisecond)
+		l.Package("mysql").Debug("kept")
+		assert.Len(t, decodeEntries(t, &buffer), 1)
+
+		assert.Eventually(t, func() bool {
+			return l.LevelStatus().Packages["redis"] == slog.LevelWarn
+		}, time.Second, 10*time.Millisecond)
+		assert.NotContains(t, l.LevelStatus().Packages, "mysql")
+		l.Level().Set(slog.LevelDebug)
+		l.Package("mysql").Debug("follows the global level")
+		assert.Len(t, decodeEntries(t, &buffer), 2)
+	})
+}
+
+func TestLogger_AddOverride(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	buffer := bytes.Buffer{}
+	l, err := NewLogger(&EnvLogger{Level: "warn"}, &buffer)
+	assert.NoError(t, err)
+
+	r := gin.New()
+	r.Use(AppendRequestID())
+	r.GET("/", func(c *gin.Context) {
+		l.DebugContext(c, "debug")
+		l.InfoContext(c, "info")
+		c.Status(http.StatusNoContent)
+	})
+	serve := func(requestID string, clientIP string) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(HeaderXRequestID, requestID)
+		req.RemoteAddr = clientIP + ":1234"
+		r.ServeHTTP(w, req)
+	}
+
+	l.AddOverride(Override{RequestID: "traced", Level: slog.LevelDebug}, time.Minute)
+	l.AddOverride(Override{ClientIP: "10.0.0.1", Level: slog.LevelInfo}, time.Minute)
+	l.AddOverride(Override{ClientIP: "10.0.0.2", Level: slog.LevelDebug}, 20*time.Millisecond)
+	assert.Len(t, l.LevelStatus().Overrides, 3)
+
+	serve("traced", "192.168.0.1")
+	assert.Len(t, decodeEntries(t, &buffer), 2)
+	buffer.Reset()
+	serve("other", "10.0.0.1")
+	assert.Len(t, decodeEntries(t, &buffer), 1)
+	buffer.Reset()
+	serve("other", "192.168.0.1")
+	assert.Len(t, decodeEntries(t, &buffer), 0)
+
+	assert.Eventually(t, func() bool {
+		return len(l.LevelStatus().Overrides) == 2
+	}, time.Second, 10*time.Millisecond)
+	serve("other", "10.0.0.2")
+	assert.Len(t, decodeEntries(t, &buffer), 0)
+
+	l.ClearOverrides()
+	serve("traced", "10.0.0.1")
+	assert.Len(t, decodeEntries(t, &buffer), 0)
+}
+
+func TestLogger_Reset(t *testing.T) {
+	l, err := NewLogger(&EnvLogger{Level: "warn", Packages: map[string]string{"redis": "error"}}, &bytes.Buffer{})
+	assert.NoError(t, err)
+	l.SetLevel(slog.LevelDebug, time.Hour)
+	l.SetPackageLevel("redis", slog.LevelDebug, 0)
+	l.SetPackageLevel("mysql", slog.LevelDebug, 0)
+	l.AddOverride(Override{RequestID: "traced"}, time.Hour)
+
+	l.Reset()
+	status := l.LevelStatus()
+	assert.Equal(t, slog.LevelWarn, status.Level)
+	assert.Equal(t, map[string]slog.Level{"redis": slog.LevelError}, status.Packages)
+	assert.Empty(t, status.Overrides)
+
+	assert.NoError(t, l.ApplyEnv(&EnvLogger{Level: "error", Packages: map[string]string{"mysql": "info"}}))
+	status = l.LevelStatus()
+	assert.Equal(t, slog.LevelError, status.Level)
+	assert.Equal(t, map[string]slog.Level{"mysql": slog.LevelInfo}, status.Packages)
+
+	assert.Error(t, l.ApplyEnv(&EnvLogger{Level: "verbose"}))
+	assert.Equal(t, slog.LevelError, l.Level().Level())
+}
+
+func TestLogger_PackageLevel(t *testing.T) {
+	l, err := NewLogger(&EnvLogger{Level: "warn", Packages: map[string]string{"redis": "error"}}, &bytes.Buffer{})
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelError, l.PackageLevel("redis").Level())
+	// Reading the level of a package without one does not pin it.
+	assert.Same(t, l.Level(), l.PackageLevel("mysql"))
+	assert.Equal(t, map[string]slog.Level{"redis": slog.LevelError}, l.LevelStatus().Packages)
+	l.SetLevel(slog.LevelDebug, 0)
+	assert.Equal(t, slog.LevelDebug, l.PackageLevel("mysql").Level())
+	assert.NoError(t, l.ApplyEnv(&EnvLogger{Level: "info"}))
+	assert.Equal(t, slog.LevelInfo, l.PackageLevel("mysql").Level())
+}
@@ -2,10 +2,10 @@ package logger
 
 import (
 	"fmt"
-	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rhosocial/go-rush-common/components/redact"
 )
 
 // defaultLogFormatter is the default log format function Logger middleware uses.
@@ -21,14 +21,14 @@ var defaultLogFormatter = func(param gin.LogFormatterParams) string {
 		param.Latency = param.Latency.Truncate(time.Second)
 	}
 
-	return fmt.Sprintf("[GO-RUSH] %v - %10d |%s %3d %s| %13v | %39s |%s %-7s %s %#v\n%s",
+	return fmt.Sprintf("[GO-RUSH] %v - %s |%s %3d %s| %13v | %39s |%s %-7s %s %#v\n%s",
 		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
 		param.Keys[ContextRequestID],
 		statusColor, param.StatusCode, resetColor,
 		param.Latency,
 		param.ClientIP,
 		methodColor, param.Method, resetColor,
-		param.Path,
+		redact.Default.URL(param.Path),
 		param.ErrorMessage,
 	)
 }
@@ -37,21 +37,73 @@ func LogFormatter(param gin.LogFormatterParams) string {
 	return defaultLogFormatter(param)
 }
 
-var requestIDIndex atomic.Int64
-
 const (
 	ContextRequestID = "RequestID"
+	// ContextRoute is the key under which the matched route template is stored.
+	ContextRoute = "Route"
 )
 
+// HeaderXRequestID is the header the request ID is accepted from and echoed in.
+const HeaderXRequestID = "X-Request-ID"
+
+// DefaultRequestIDGenerator is used by NewRequestID and AppendRequestID.
+var DefaultRequestIDGenerator RequestIDGenerator = &UUIDv7Generator{}
+
 // NewRequestID 返回一个新的请求ID。
-func NewRequestID() uint32 {
-	usec := time.Now().UnixNano() + requestIDIndex.Add(1)
-	return uint32(usec&0x7FFFFFFF | 0x80000000)
+func NewRequestID() RequestID {
+	return DefaultRequestIDGenerator.NewRequestID()
+}
+
+// GetRequestID returns the request ID of the context, or an empty RequestID if AppendRequestID has not run.
+func GetRequestID(c *gin.Context) RequestID {
+	if c == nil {
+		return ""
+	}
+	id, _ := c.Value(ContextRequestID).(RequestID)
+	return id
 }
 
+// RequestIDConfig defines the behavior of AppendRequestIDWithConfig.
+type RequestIDConfig struct {
+	// Generator generates the IDs of requests without an acceptable incoming one. Nil means DefaultRequestIDGenerator.
+	Generator RequestIDGenerator
+	// Header is the header the ID is accepted from and echoed in. Empty means HeaderXRequestID.
+	Header string
+	// IgnoreIncoming always generates a new ID, e.g. when the upstream is not trusted.
+	IgnoreIncoming bool
+	// Validate decides whether an incoming ID is accepted. Nil means ValidRequestID.
+	Validate func(string) bool
+	// DisableEcho does not set the ID in the response header.
+	DisableEcho bool
+}
+
+// AppendRequestID stores the request ID in the context, accepting a valid incoming X-Request-ID and echoing it in the
+// response.
 func AppendRequestID() gin.HandlerFunc {
+	return AppendRequestIDWithConfig(RequestIDConfig{})
+}
+
+func AppendRequestIDWithConfig(config RequestIDConfig) gin.HandlerFunc {
+	if config.Header == "" {
+		config.Header = HeaderXRequestID
+	}
+	if config.Validate == nil {
+		config.Validate = ValidRequestID
+	}
 	return func(c *gin.Context) {
-		c.Set(ContextRequestID, NewRequestID())
+		var id RequestID
+		if incoming := c.GetHeader(config.Header); !config.IgnoreIncoming && incoming != "" && config.Validate(incoming) {
+			id = RequestID(incoming)
+		} else if config.Generator != nil {
+			id = config.Generator.NewRequestID()
+		} else {
+			id = NewRequestID()
+		}
+		c.Set(ContextRequestID, id)
+		c.Set(ContextRoute, c.FullPath())
+		if !config.DisableEcho {
+			c.Header(config.Header, id.String())
+		}
 		c.Next()
 	}
 }
@@ -0,0 +1,212 @@
+package logger
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RequestID identifies a request. It is serialized as a JSON string, so that it is not limited to any integer width.
+type RequestID string
+
+func (r RequestID) String() string {
+	return string(r)
+}
+
+// RequestIDGenerator generates request IDs. Implementations must be safe for concurrent use.
+type RequestIDGenerator interface {
+	NewRequestID() RequestID
+}
+
+// RequestIDGeneratorFunc adapts an ordinary function to a RequestIDGenerator.
+type RequestIDGeneratorFunc func() RequestID
+
+func (f RequestIDGeneratorFunc) NewRequestID() RequestID {
+	return f()
+}
+
+// UUIDv7Generator generates RFC 9562 version 7 UUIDs: a millisecond timestamp followed by random bits.
+// IDs generated within the same millisecond by the same generator are monotonic.
+type UUIDv7Generator struct {
+	mu       sync.Mutex
+	lastMS   int64
+	sequence uint16
+}
+
+func (g *UUIDv7Generator) NewRequestID() RequestID {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	ms := time.Now().UnixMilli()
+
+	g.mu.Lock()
+	if ms <= g.lastMS {
+		// The 12-bit rand_a field is used as a counter; borrow the next millisecond when it overflows.
+		ms = g.lastMS
+		g.sequence++
+		if g.sequence > 0xFFF {
+			ms++
+			g.sequence = binary.BigEndian.Uint16(id[6:8]) & 0x7FF
+		}
+	} else {
+		// Leave headroom for the counter by clearing the top bit of the random start.
+		g.sequence = binary.BigEndian.Uint16(id[6:8]) & 0x7FF
+	}
+	g.lastMS = ms
+	sequence := g.sequence
+	g.mu.Unlock()
+
+	id[0] = byte(ms >> 40)
+	id[1] = byte(ms >> 32)
+	id[2] = byte(ms >> 24)
+	id[3] = byte(ms >> 16)
+	id[4] = byte(ms >> 8)
+	id[5] = byte(ms)
+	id[6] = 0x70 | byte(sequence>>8)
+	id[7] = byte(sequence)
+	id[8] = 0x80 | id[8]&0x3F
+
+	var s [36]byte
+	hex.Encode(s[0:8], id[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], id[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], id[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], id[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], id[10:])
+	return RequestID(s[:])
+}
+
+// crockford is the Crockford base32 alphabet used by ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULIDGenerator generates ULIDs: a 48-bit millisecond timestamp and 80 random bits, encoded in Crockford base32.
+// IDs generated within the same millisecond by the same generator are monotonic.
+type ULIDGenerator struct {
+	mu     sync.Mutex
+	lastMS int64
+	last   [10]byte
+}
+
+func (g *ULIDGenerator) NewRequestID() RequestID {
+	var id [16]byte
+	ms := time.Now().UnixMilli()
+
+	g.mu.Lock()
+	if ms <= g.lastMS {
+		ms = g.lastMS
+		// Increment the 80-bit random part; on overflow, move to the next millisecond.
+		i := len(g.last) - 1
+		for ; i >= 0; i-- {
+			g.last[i]++
+			if g.last[i] != 0 {
+				break
+			}
+		}
+		if i < 0 {
+			ms++
+		}
+	} else {
+		_, _ = rand.Read(g.last[:])
+	}
+	g.lastMS = ms
+	copy(id[6:], g.last[:])
+	g.mu.Unlock()
+
+	id[0] = byte(ms >> 40)
+	id[1] = byte(ms >> 32)
+	id[2] = byte(ms >> 24)
+	id[3] = byte(ms >> 16)
+	id[4] = byte(ms >> 8)
+	id[5] = byte(ms)
+
+	// 128 bits are encoded as 26 characters of 5 bits each, the first one holding only 3 bits.
+	hi := binary.BigEndian.Uint64(id[0:8])
+	lo := binary.BigEndian.Uint64(id[8:16])
+	var s [26]byte
+	for i := 25; i >= 0; i-- {
+		s[i] = crockford[lo&0x1F]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return RequestID(s[:])
+}
+
+// SnowflakeEpoch is the origin of Snowflake timestamps: 2023-01-01T00:00:00Z.
+var SnowflakeEpoch = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const (
+	snowflakeMachineBits  = 10
+	snowflakeSequenceBits = 12
+	// SnowflakeMaxMachineID is the largest machine ID a SnowflakeGenerator accepts.
+	SnowflakeMaxMachineID = 1<<snowflakeMachineBits - 1
+)
+
+var ErrSnowflakeMachineID = errors.New("snowflake machine ID out of range")
+
+// SnowflakeGenerator generates 63-bit Snowflake IDs, rendered in decimal: 41 bits of milliseconds since
+// SnowflakeEpoch, 10 bits of machine ID and a 12-bit sequence.
+// Each instance must be given a distinct machine ID for the IDs to be unique across instances.
+type SnowflakeGenerator struct {
+	machineID uint16
+	mu        sync.Mutex
+	lastMS    int64
+	sequence  uint16
+}
+
+// NewSnowflakeGenerator creates a Snowflake generator for the machine ID (0 to SnowflakeMaxMachineID).
+func NewSnowflakeGenerator(machineID uint16) (*SnowflakeGenerator, error) {
+	if machineID > SnowflakeMaxMachineID {
+		return nil, ErrSnowflakeMachineID
+	}
+	return &SnowflakeGenerator{machineID: machineID}, nil
+}
+
+func (g *SnowflakeGenerator) NewRequestID() RequestID {
+	ms := time.Since(SnowflakeEpoch).Milliseconds()
+
+	g.mu.Lock()
+	if ms <= g.lastMS {
+		// Also covers the clock moving backwards: keep counting on the last timestamp.
+		ms = g.lastMS
+		g.sequence = (g.sequence + 1) & (1<<snowflakeSequenceBits - 1)
+		if g.sequence == 0 {
+			ms++
+		}
+	} else {
+		g.sequence = 0
+	}
+	g.lastMS = ms
+	id := ms<<(snowflakeMachineBits+snowflakeSequenceBits) |
+		int64(g.machineID)<<snowflakeSequenceBits |
+		int64(g.sequence)
+	g.mu.Unlock()
+
+	return RequestID(strconv.FormatInt(id, 10))
+}
+
+// MaxRequestIDLength is the maximum length of a request ID accepted from upstream.
+const MaxRequestIDLength = 128
+
+// ValidRequestID reports whether an incoming request ID is acceptable: 1 to MaxRequestIDLength characters among
+// letters, digits, '-', '_', '.' and ':'.
+func ValidRequestID(s string) bool {
+	if len(s) == 0 || len(s) > MaxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
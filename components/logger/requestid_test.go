@@ -0,0 +1,153 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func assertUniqueAndSorted(t *testing.T, generator RequestIDGenerator, n int) []RequestID {
+	ids := make([]RequestID, n)
+	for i := range ids {
+		ids[i] = generator.NewRequestID()
+	}
+	seen := make(map[RequestID]struct{}, n)
+	for i, id := range ids {
+		_, existed := seen[id]
+		assert.False(t, existed, "duplicated request ID %s", id)
+		seen[id] = struct{}{}
+		if i > 0 {
+			assert.Less(t, string(ids[i-1]), string(id))
+		}
+	}
+	return ids
+}
+
+func TestUUIDv7Generator(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	for _, id := range assertUniqueAndSorted(t, &UUIDv7Generator{}, 10000) {
+		assert.Regexp(t, pattern, id.String())
+	}
+}
+
+func TestULIDGenerator(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+	for _, id := range assertUniqueAndSorted(t, &ULIDGenerator{}, 10000) {
+		assert.Regexp(t, pattern, id.String())
+	}
+}
+
+func TestSnowflakeGenerator(t *testing.T) {
+	_, err := NewSnowflakeGenerator(SnowflakeMaxMachineID + 1)
+	assert.ErrorIs(t, err, ErrSnowflakeMachineID)
+
+	g, err := NewSnowflakeGenerator(42)
+	assert.NoError(t, err)
+	previous := int64(0)
+	for i := 0; i < 10000; i++ {
+		id, err := strconv.ParseInt(g.NewRequestID().String(), 10, 64)
+		assert.NoError(t, err)
+		assert.Greater(t, id, previous)
+		assert.Equal(t, int64(42), id>>12&SnowflakeMaxMachineID)
+		previous = id
+	}
+}
+
+func TestUUIDv7Generator_Concurrent(t *testing.T) {
+	g := UUIDv7Generator{}
+	const N, M = 8, 1000
+	results := make(chan RequestID, N*M)
+	var wg sync.WaitGroup
+	wg.Add(N)
+	for i := 0; i < N; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < M; j++ {
+				results <- g.NewRequestID()
+			}
+		}()
+	}
+	wg.Wait()
+	close(results)
+	seen := make(map[RequestID]struct{}, N*M)
+	for id := range results {
+		seen[id] = struct{}{}
+	}
+	assert.Len(t, seen, N*M)
+}
+
+func TestValidRequestID(t *testing.T) {
+	assert.True(t, ValidRequestID("0188a7b0-7c1e-7d2a-9b3c-4d5e6f708192"))
+	assert.True(t, ValidRequestID("01H2X3Y4Z5"))
+	assert.True(t, ValidRequestID("svc:abc_123.4"))
+	assert.False(t, ValidRequestID(""))
+	assert.False(t, ValidRequestID("has space"))
+	assert.False(t, ValidRequestID("inject\nheader"))
+	assert.False(t, ValidRequestID(strings.Repeat("a", MaxRequestIDLength+1)))
+}
+
+func TestRequestID_MarshalJSON(t *testing.T) {
+	body, err := json.Marshal(struct {
+		RequestID RequestID `json:"request_id"`
+	}{"abc"})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"request_id":"abc"}`, string(body))
+}
+
+func TestAppendRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setup := func(config RequestIDConfig) *gin.Engine {
+		r := gin.New()
+		r.Use(AppendRequestIDWithConfig(config))
+		r.GET("/ping", func(c *gin.Context) {
+			c.String(http.StatusOK, GetRequestID(c).String())
+		})
+		return r
+	}
+	serve := func(r *gin.Engine, incoming string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/ping", nil)
+		if incoming != "" {
+			req.Header.Set(HeaderXRequestID, incoming)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("generated and echoed", func(t *testing.T) {
+		w := serve(setup(RequestIDConfig{}), "")
+		assert.NotEmpty(t, w.Body.String())
+		assert.Equal(t, w.Body.String(), w.Header().Get(HeaderXRequestID))
+	})
+
+	t.Run("valid incoming accepted", func(t *testing.T) {
+		w := serve(setup(RequestIDConfig{}), "upstream-1")
+		assert.Equal(t, "upstream-1", w.Body.String())
+		assert.Equal(t, "upstream-1", w.Header().Get(HeaderXRequestID))
+	})
+
+	t.Run("invalid incoming replaced", func(t *testing.T) {
+		w := serve(setup(RequestIDConfig{}), "bad id")
+		assert.NotEqual(t, "bad id", w.Body.String())
+		assert.NotEmpty(t, w.Body.String())
+	})
+
+	t.Run("incoming ignored with custom generator", func(t *testing.T) {
+		r := setup(RequestIDConfig{
+			IgnoreIncoming: true,
+			DisableEcho:    true,
+			Generator:      RequestIDGeneratorFunc(func() RequestID { return "fixed" }),
+		})
+		w := serve(r, "upstream-1")
+		assert.Equal(t, "fixed", w.Body.String())
+		assert.Empty(t, w.Header().Get(HeaderXRequestID))
+	})
+}
@@ -0,0 +1,38 @@
+//go:build !windows && !plan9
+
+package logger
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// WatchSignals adjusts the levels of the logger on signals, until stop is called:
+//
+//   - SIGUSR1 lowers the global level to debug for config.TTL;
+//   - SIGHUP resets the levels to the configured ones, or to the ones returned by config.Reload if set.
+func WatchSignals(l *Logger, config SignalConfig) (stop func()) {
+	config.defaults()
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-ch:
+				if sig == syscall.SIGUSR1 {
+					config.raise(l)
+				} else {
+					config.reset(l)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
@@ -0,0 +1,33 @@
+//go:build !windows && !plan9
+
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchSignals(t *testing.T) {
+	l, err := NewLogger(&EnvLogger{Level: "warn"}, &bytes.Buffer{})
+	assert.NoError(t, err)
+	reloaded := &EnvLogger{Level: "error"}
+	stop := WatchSignals(l, SignalConfig{TTL: time.Hour, Reload: func() (*EnvLogger, error) {
+		return reloaded, nil
+	}})
+	defer stop()
+
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	assert.Eventually(t, func() bool {
+		return l.Level().Level() == slog.LevelDebug
+	}, time.Second, 10*time.Millisecond)
+
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGHUP))
+	assert.Eventually(t, func() bool {
+		return l.Level().Level() == slog.LevelError
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,8 @@
+//go:build windows || plan9
+
+package logger
+
+// WatchSignals does nothing on this platform: SIGUSR1 and SIGHUP are not available.
+func WatchSignals(l *Logger, config SignalConfig) (stop func()) {
+	return func() {}
+}
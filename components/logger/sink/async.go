@@ -0,0 +1,162 @@
+package sink
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// DropPolicy decides what happens to a write when the queue of an Async writer is full.
+type DropPolicy uint8
+
+const (
+	// Block waits until the queue has room.
+	Block DropPolicy = iota
+	// DropNewest discards the write being made.
+	DropNewest
+	// DropOldest discards the oldest queued write to make room.
+	DropOldest
+)
+
+// ParseDropPolicy converts a policy name (block, drop_newest, drop_oldest) into a DropPolicy.
+func ParseDropPolicy(name string) (DropPolicy, error) {
+	switch name {
+	case "", "block":
+		return Block, nil
+	case "drop_newest":
+		return DropNewest, nil
+	case "drop_oldest":
+		return DropOldest, nil
+	}
+	return Block, errors.New("sink: invalid drop policy " + name)
+}
+
+var ErrAsyncClosed = errors.New("sink: async writer closed")
+
+// Async is an io.WriteCloser that queues writes and performs them on a background goroutine.
+//
+// Each write is copied, so callers may reuse their buffers. Writes are never split or merged.
+type Async struct {
+	out    io.Writer
+	queue  chan []byte
+	policy DropPolicy
+	// queued counts the writes made and completed those performed or dropped, so that Flush waits for the writes
+	// made before it only.
+	queued    atomic.Uint64
+	completed uint64
+	flushMu   sync.Mutex
+	flushed   *sync.Cond
+	done      chan struct{}
+	closed    atomic.Bool
+	mu        sync.RWMutex
+	dropped   atomic.Uint64
+	failed    atomic.Uint64
+}
+
+// NewAsync creates an Async writer over out with a queue of size entries.
+func NewAsync(out io.Writer, size int, policy DropPolicy) *Async {
+	if size <= 0 {
+		size = 1
+	}
+	a := Async{
+		out:    out,
+		queue:  make(chan []byte, size),
+		policy: policy,
+		done:   make(chan struct{}),
+	}
+	a.flushed = sync.NewCond(&a.flushMu)
+	go a.run()
+	return &a
+}
+
+func (a *Async) run() {
+	defer close(a.done)
+	for p := range a.queue {
+		if _, err := a.out.Write(p); err != nil {
+			a.failed.Add(1)
+		}
+		a.complete()
+	}
+}
+
+func (a *Async) complete() {
+	a.flushMu.Lock()
+	a.completed++
+	a.flushMu.Unlock()
+	a.flushed.Broadcast()
+}
+
+// Write queues p. It only fails once the writer is closed; dropped writes are counted instead.
+func (a *Async) Write(p []byte) (int, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed.Load() {
+		return 0, ErrAsyncClosed
+	}
+	entry := make([]byte, len(p))
+	copy(entry, p)
+	a.queued.Add(1)
+	switch a.policy {
+	case DropNewest:
+		select {
+		case a.queue <- entry:
+		default:
+			a.complete()
+			a.dropped.Add(1)
+		}
+	case DropOldest:
+		for {
+			select {
+			case a.queue <- entry:
+				return len(p), nil
+			default:
+			}
+			select {
+			case <-a.queue:
+				a.complete()
+				a.dropped.Add(1)
+			default:
+			}
+		}
+	default:
+		a.queue <- entry
+	}
+	return len(p), nil
+}
+
+// Flush waits until the writes made before it have been performed or dropped.
+func (a *Async) Flush() {
+	target := a.queued.Load()
+	a.flushMu.Lock()
+	defer a.flushMu.Unlock()
+	for a.completed < target {
+		a.flushed.Wait()
+	}
+}
+
+// Dropped returns the number of writes discarded because the queue was full.
+func (a *Async) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// Failed returns the number of writes the underlying writer reported an error for.
+func (a *Async) Failed() uint64 {
+	return a.failed.Load()
+}
+
+// Close drains the queue, then closes the underlying writer if it is an io.Closer.
+func (a *Async) Close() error {
+	a.mu.Lock()
+	if a.closed.Swap(true) {
+		a.mu.Unlock()
+		return ErrAsyncClosed
+	}
+	close(a.queue)
+	a.mu.Unlock()
+	<-a.done
+	if closer, ok := a.out.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
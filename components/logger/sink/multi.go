@@ -0,0 +1,48 @@
+package sink
+
+import (
+	"errors"
+	"io"
+)
+
+// Multi is an io.WriteCloser that duplicates writes to all its writers.
+//
+// Unlike io.MultiWriter, a failing writer does not stop the others from receiving the write; all errors are joined.
+type Multi struct {
+	writers []io.Writer
+}
+
+// NewMulti creates a Multi writer over writers.
+func NewMulti(writers ...io.Writer) *Multi {
+	w := make([]io.Writer, len(writers))
+	copy(w, writers)
+	return &Multi{writers: w}
+}
+
+func (m *Multi) Write(p []byte) (int, error) {
+	var errs []error
+	for _, w := range m.writers {
+		n, err := w.Write(p)
+		if err == nil && n < len(p) {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes every writer that is an io.Closer, except the standard streams.
+func (m *Multi) Close() error {
+	var errs []error
+	for _, w := range m.writers {
+		if closer, ok := w.(io.Closer); ok && !isStandardStream(w) {
+			errs = append(errs, closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
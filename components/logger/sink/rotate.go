@@ -0,0 +1,226 @@
+package sink
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the timestamp embedded in the names of rotated files.
+const backupTimeFormat = "20060102T150405.000"
+
+const compressSuffix = ".gz"
+
+// RotatingFile is an io.WriteCloser that writes to a file and rotates it by size and/or time.
+//
+// Rotated files are renamed to `<name>-<timestamp><ext>`, optionally gzipped, and the oldest ones are removed once
+// there are more than MaxBackups of them.
+// Please do not modify any member after the first write.
+type RotatingFile struct {
+	// Filename is the file to write to. Its directory is created if absent.
+	Filename string
+	// MaxBytes rotates the file before a write would make it exceed this size. Zero disables size-based rotation.
+	MaxBytes int64
+	// Interval rotates the file when the current time crosses an interval boundary. Zero disables time-based rotation.
+	Interval time.Duration
+	// MaxBackups is the number of rotated files to keep. Zero keeps all of them.
+	MaxBackups int
+	// Compress gzips the rotated files.
+	Compress bool
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	wg       sync.WaitGroup
+	jobs     sync.Mutex
+	now      func() time.Time
+}
+
+var ErrWriteTooLarge = errors.New("sink: write exceeds the maximum file size")
+
+func (f *RotatingFile) currentTime() time.Time {
+	if f.now != nil {
+		return f.now()
+	}
+	return time.Now()
+}
+
+// Write writes p to the current file, rotating it first if necessary.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.MaxBytes > 0 && int64(len(p)) > f.MaxBytes {
+		return 0, ErrWriteTooLarge
+	}
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	if f.shouldRotate(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *RotatingFile) shouldRotate(n int64) bool {
+	if f.MaxBytes > 0 && f.size+n > f.MaxBytes {
+		return true
+	}
+	if f.Interval > 0 && !f.currentTime().Truncate(f.Interval).Equal(f.openedAt.Truncate(f.Interval)) {
+		return true
+	}
+	return false
+}
+
+func (f *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.Filename), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = info.ModTime()
+	if f.size == 0 {
+		f.openedAt = f.currentTime()
+	}
+	return nil
+}
+
+// Rotate closes the current file, renames it to a backup and opens a new one.
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rotate()
+}
+
+func (f *RotatingFile) rotate() error {
+	if f.file != nil {
+		if err := f.file.Close(); err != nil {
+			return err
+		}
+		f.file = nil
+	}
+	if _, err := os.Stat(f.Filename); err == nil {
+		backup := f.backupName(f.currentTime())
+		if err := os.Rename(f.Filename, backup); err != nil {
+			return err
+		}
+		f.wg.Add(1)
+		go func() {
+			defer f.wg.Done()
+			f.jobs.Lock()
+			defer f.jobs.Unlock()
+			if f.Compress {
+				_ = compressFile(backup)
+			}
+			_ = f.removeOldBackups()
+		}()
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	f.openedAt = f.currentTime()
+	return nil
+}
+
+func (f *RotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(f.Filename)
+	prefix := strings.TrimSuffix(f.Filename, ext)
+	return prefix + "-" + t.Format(backupTimeFormat) + ext
+}
+
+// Backups returns the rotated files, oldest first.
+func (f *RotatingFile) Backups() ([]string, error) {
+	ext := filepath.Ext(f.Filename)
+	prefix := strings.TrimSuffix(f.Filename, ext) + "-"
+	entries, err := os.ReadDir(filepath.Dir(f.Filename))
+	if err != nil {
+		return nil, err
+	}
+	backups := make([]string, 0)
+	for _, entry := range entries {
+		name := filepath.Join(filepath.Dir(f.Filename), entry.Name())
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, prefix), compressSuffix), ext)
+		if _, err := time.Parse(backupTimeFormat, stamp); err != nil {
+			continue
+		}
+		backups = append(backups, name)
+	}
+	// The timestamp format sorts lexicographically.
+	sort.Strings(backups)
+	return backups, nil
+}
+
+func (f *RotatingFile) removeOldBackups() error {
+	if f.MaxBackups <= 0 {
+		return nil
+	}
+	backups, err := f.Backups()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i := 0; i < len(backups)-f.MaxBackups; i++ {
+		errs = append(errs, os.Remove(backups[i]))
+	}
+	return errors.Join(errs...)
+}
+
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(name+compressSuffix, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(name + compressSuffix)
+		return err
+	}
+	if err := errors.Join(gz.Close(), dst.Close()); err != nil {
+		_ = os.Remove(name + compressSuffix)
+		return err
+	}
+	return os.Remove(name)
+}
+
+// Close closes the current file and waits for pending compression and cleanup.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.wg.Wait()
+	return err
+}
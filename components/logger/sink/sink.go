@@ -0,0 +1,137 @@
+// Package sink provides the writers that access and application logs are written to: rotating files, asynchronous
+// buffering, fan-out to multiple destinations and syslog.
+//
+// Every sink is an io.Writer, so it can be passed to gin.LoggerConfig.Output as well as logger.NewLogger.
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	TypeStdout = "stdout"
+	TypeStderr = "stderr"
+	TypeFile   = "file"
+	TypeSyslog = "syslog"
+)
+
+// EnvFile defines a rotating file sink.
+type EnvFile struct {
+	Filename string `yaml:"Filename" validate:"required"`
+	// MaxSize is the size in megabytes at which the file is rotated. Zero disables size-based rotation.
+	MaxSize uint32 `yaml:"MaxSize,omitempty" default:"100"`
+	// Interval rotates the file every hour or every day (UTC). Empty disables time-based rotation.
+	Interval   string `yaml:"Interval,omitempty" default:"" validate:"omitempty,oneof=hourly daily"`
+	MaxBackups uint16 `yaml:"MaxBackups,omitempty" default:"7"`
+	Compress   bool   `yaml:"Compress,omitempty" default:"false"`
+}
+
+// EnvSyslog defines a syslog sink. Empty Network and Address mean the local socket.
+type EnvSyslog struct {
+	Network string `yaml:"Network,omitempty" default:"" validate:"omitempty,oneof=unix unixgram tcp udp"`
+	Address string `yaml:"Address,omitempty" default:""`
+	Tag     string `yaml:"Tag,omitempty" default:""`
+}
+
+// EnvSink defines one destination.
+type EnvSink struct {
+	Type   string     `yaml:"Type" validate:"required,oneof=stdout stderr file syslog"`
+	File   *EnvFile   `yaml:"File,omitempty" validate:"required_if=Type file"`
+	Syslog *EnvSyslog `yaml:"Syslog,omitempty"`
+}
+
+// EnvAsync defines the asynchronous buffering in front of the sinks.
+type EnvAsync struct {
+	QueueSize  uint32 `yaml:"QueueSize,omitempty" default:"1024" validate:"min=1"`
+	DropPolicy string `yaml:"DropPolicy,omitempty" default:"block" validate:"omitempty,oneof=block drop_newest drop_oldest"`
+}
+
+// EnvSinks defines where logs are written to.
+type EnvSinks struct {
+	Sinks []EnvSink `yaml:"Sinks" validate:"required,min=1,dive"`
+	// Async, if present, buffers writes in a bounded queue in front of all sinks.
+	Async *EnvAsync `yaml:"Async,omitempty"`
+}
+
+func (e *EnvSinks) Validate() error {
+	validate := validator.New()
+	return validate.Struct(e)
+}
+
+// New opens the sinks described by the configuration.
+// The returned writer must be closed to flush buffered entries and release files and connections.
+func New(e *EnvSinks) (io.WriteCloser, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	writers := make([]io.Writer, 0, len(e.Sinks))
+	for i := range e.Sinks {
+		w, err := newSink(&e.Sinks[i])
+		if err != nil {
+			_ = NewMulti(writers...).Close()
+			return nil, fmt.Errorf("sink %d (%s): %w", i, e.Sinks[i].Type, err)
+		}
+		writers = append(writers, w)
+	}
+	var w io.WriteCloser = NewMulti(writers...)
+	if e.Async != nil {
+		policy, err := ParseDropPolicy(e.Async.DropPolicy)
+		if err != nil {
+			_ = w.Close()
+			return nil, err
+		}
+		w = NewAsync(w, int(e.Async.QueueSize), policy)
+	}
+	return w, nil
+}
+
+func newSink(e *EnvSink) (io.Writer, error) {
+	switch e.Type {
+	case TypeStdout:
+		return os.Stdout, nil
+	case TypeStderr:
+		return os.Stderr, nil
+	case TypeFile:
+		return NewRotatingFile(e.File)
+	case TypeSyslog:
+		config := e.Syslog
+		if config == nil {
+			config = &EnvSyslog{}
+		}
+		return newSyslogFromEnv(config)
+	}
+	return nil, errors.New("unknown sink type " + e.Type)
+}
+
+// NewRotatingFile creates a RotatingFile from the configuration. The file is opened on the first write.
+func NewRotatingFile(e *EnvFile) (*RotatingFile, error) {
+	if e == nil || e.Filename == "" {
+		return nil, errors.New("sink: missing file name")
+	}
+	f := RotatingFile{
+		Filename:   e.Filename,
+		MaxBytes:   int64(e.MaxSize) * 1024 * 1024,
+		MaxBackups: int(e.MaxBackups),
+		Compress:   e.Compress,
+	}
+	switch e.Interval {
+	case "":
+	case "hourly":
+		f.Interval = time.Hour
+	case "daily":
+		f.Interval = 24 * time.Hour
+	default:
+		return nil, errors.New("sink: invalid rotation interval " + e.Interval)
+	}
+	return &f, nil
+}
+
+func isStandardStream(w io.Writer) bool {
+	return w == os.Stdout || w == os.Stderr
+}
@@ -0,0 +1,233 @@
+package sink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rhosocial/go-rush-common/components/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotatingFile(t *testing.T) {
+	t.Run("rotate by size and keep max backups", func(t *testing.T) {
+		dir := t.TempDir()
+		f := RotatingFile{Filename: filepath.Join(dir, "access.log"), MaxBytes: 10, MaxBackups: 2}
+		for i := 0; i < 5; i++ {
+			n, err := f.Write([]byte("0123456789"))
+			assert.NoError(t, err)
+			assert.Equal(t, 10, n)
+			// Backup names have a millisecond resolution.
+			time.Sleep(2 * time.Millisecond)
+		}
+		assert.NoError(t, f.Close())
+
+		backups, err := f.Backups()
+		assert.NoError(t, err)
+		assert.Len(t, backups, 2)
+		content, err := os.ReadFile(f.Filename)
+		assert.NoError(t, err)
+		assert.Equal(t, "0123456789", string(content))
+	})
+
+	t.Run("write larger than max size", func(t *testing.T) {
+		f := RotatingFile{Filename: filepath.Join(t.TempDir(), "access.log"), MaxBytes: 4}
+		_, err := f.Write([]byte("01234"))
+		assert.ErrorIs(t, err, ErrWriteTooLarge)
+		assert.NoError(t, f.Close())
+	})
+
+	t.Run("rotate by time and compress", func(t *testing.T) {
+		now := time.Date(2023, 4, 1, 10, 59, 0, 0, time.UTC)
+		f := RotatingFile{
+			Filename: filepath.Join(t.TempDir(), "app.log"),
+			Interval: time.Hour,
+			Compress: true,
+			now:      func() time.Time { return now },
+		}
+		_, err := f.Write([]byte("before\n"))
+		assert.NoError(t, err)
+		now = now.Add(2 * time.Minute)
+		_, err = f.Write([]byte("after\n"))
+		assert.NoError(t, err)
+		assert.NoError(t, f.Close())
+
+		backups, err := f.Backups()
+		assert.NoError(t, err)
+		assert.Len(t, backups, 1)
+		assert.True(t, strings.HasSuffix(backups[0], "app-20230401T110100.000.log.gz"))
+		file, err := os.Open(backups[0])
+		assert.NoError(t, err)
+		defer file.Close()
+		reader, err := gzip.NewReader(file)
+		assert.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.Equal(t, "before\n", string(content))
+	})
+}
+
+type slowWriter struct {
+	mu      sync.Mutex
+	buffer  bytes.Buffer
+	release chan struct{}
+	closed  bool
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	if w.release != nil {
+		<-w.release
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buffer.Write(p)
+}
+
+func (w *slowWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestAsync(t *testing.T) {
+	t.Run("block", func(t *testing.T) {
+		out := slowWriter{}
+		a := NewAsync(&out, 2, Block)
+		for i := 0; i < 10; i++ {
+			_, err := a.Write([]byte("x"))
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, a.Close())
+		assert.Equal(t, strings.Repeat("x", 10), out.buffer.String())
+		assert.True(t, out.closed)
+		_, err := a.Write([]byte("x"))
+		assert.ErrorIs(t, err, ErrAsyncClosed)
+	})
+
+	for _, policy := range []DropPolicy{DropNewest, DropOldest} {
+		t.Run("drop", func(t *testing.T) {
+			out := slowWriter{release: make(chan struct{})}
+			a := NewAsync(&out, 2, policy)
+			// The first write may be taken by the background goroutine, which then blocks.
+			for i := 0; i < 10; i++ {
+				_, err := a.Write([]byte{byte('0' + i)})
+				assert.NoError(t, err)
+			}
+			close(out.release)
+			a.Flush()
+			assert.NoError(t, a.Close())
+			assert.GreaterOrEqual(t, a.Dropped(), uint64(7))
+			assert.Equal(t, uint64(10), a.Dropped()+uint64(out.buffer.Len()))
+			if policy == DropOldest {
+				assert.True(t, strings.HasSuffix(out.buffer.String(), "89"))
+			}
+		})
+	}
+
+	t.Run("flush under load", func(t *testing.T) {
+		out := &countingWriter{}
+		a := NewAsync(out, 4, Block)
+		stop := make(chan struct{})
+		var writers sync.WaitGroup
+		var written atomic.Uint64
+		for i := 0; i < 4; i++ {
+			writers.Add(1)
+			go func() {
+				defer writers.Done()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					_, _ = a.Write([]byte("x"))
+					written.Add(1)
+				}
+			}()
+		}
+		// Flush returns while the writers keep writing, once the writes made before it are performed.
+		for i := 0; i < 20; i++ {
+			before := written.Load()
+			a.Flush()
+			assert.GreaterOrEqual(t, out.n.Load(), before)
+		}
+		close(stop)
+		writers.Wait()
+		a.Flush()
+		assert.Equal(t, written.Load(), out.n.Load())
+		assert.NoError(t, a.Close())
+		a.Flush()
+	})
+}
+
+type countingWriter struct {
+	n atomic.Uint64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n.Add(uint64(len(p)))
+	return len(p), nil
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("failed")
+}
+
+func TestMulti(t *testing.T) {
+	first, second := bytes.Buffer{}, bytes.Buffer{}
+	m := NewMulti(&first, failingWriter{}, &second)
+	_, err := m.Write([]byte("entry"))
+	assert.Error(t, err)
+	assert.Equal(t, "entry", first.String())
+	assert.Equal(t, "entry", second.String())
+	assert.NoError(t, m.Close())
+}
+
+func TestNew(t *testing.T) {
+	t.Run("invalid", func(t *testing.T) {
+		_, err := New(&EnvSinks{})
+		assert.Error(t, err)
+		_, err = New(&EnvSinks{Sinks: []EnvSink{{Type: TypeFile}}})
+		assert.Error(t, err)
+	})
+
+	t.Run("file sink with async buffering used by gin and the logger", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "logs", "app.log")
+		w, err := New(&EnvSinks{
+			Sinks: []EnvSink{{Type: TypeFile, File: &EnvFile{Filename: filename, MaxSize: 1}}},
+			Async: &EnvAsync{QueueSize: 16, DropPolicy: "block"},
+		})
+		assert.NoError(t, err)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(logger.AppendRequestID(), gin.LoggerWithConfig(gin.LoggerConfig{Formatter: logger.JSONLogFormatter, Output: w}))
+		r.GET("/ping", func(c *gin.Context) {
+			c.String(http.StatusOK, "pong")
+		})
+		req, _ := http.NewRequest(http.MethodGet, "/ping", nil)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		l, err := logger.NewLogger(nil, w)
+		assert.NoError(t, err)
+		l.Info("application entry")
+
+		assert.NoError(t, w.Close())
+		content, err := os.ReadFile(filename)
+		assert.NoError(t, err)
+		assert.Contains(t, string(content), `"path":"/ping"`)
+		assert.Contains(t, string(content), `"msg":"application entry"`)
+	})
+}
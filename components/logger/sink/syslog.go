@@ -0,0 +1,18 @@
+//go:build !windows && !plan9
+
+package sink
+
+import (
+	"io"
+	"log/syslog"
+)
+
+// NewSyslog connects to a syslog daemon. An empty network and address connect to the local socket (/dev/log).
+// Each write becomes one message with the given priority and tag.
+func NewSyslog(network, address string, priority syslog.Priority, tag string) (io.WriteCloser, error) {
+	return syslog.Dial(network, address, priority, tag)
+}
+
+func newSyslogFromEnv(e *EnvSyslog) (io.WriteCloser, error) {
+	return NewSyslog(e.Network, e.Address, syslog.LOG_INFO|syslog.LOG_LOCAL0, e.Tag)
+}
@@ -0,0 +1,14 @@
+//go:build windows || plan9
+
+package sink
+
+import (
+	"errors"
+	"io"
+)
+
+var ErrSyslogUnsupported = errors.New("sink: syslog is not supported on this platform")
+
+func newSyslogFromEnv(e *EnvSyslog) (io.WriteCloser, error) {
+	return nil, ErrSyslogUnsupported
+}
@@ -0,0 +1,225 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// ContextTiming is the key under which SlowRequest stores the Timing of the request.
+const ContextTiming = "Timing"
+
+// TimingRedis is the Timing category of Redis commands, recorded by TimingRedisHook.
+const TimingRedis = "redis"
+
+// DefaultSlowRequestThreshold is the threshold used when SlowRequestConfig.Threshold is zero.
+const DefaultSlowRequestThreshold = time.Second
+
+// Timing accumulates the durations spent in the phases of a request. It is safe for concurrent use.
+type Timing struct {
+	mu           sync.Mutex
+	start        time.Time
+	handlerStart time.Time
+	handlerEnd   time.Time
+	categories   map[string]*TimingCategory
+}
+
+// TimingCategory is the total duration and the number of calls of one category, e.g. Redis commands.
+type TimingCategory struct {
+	Calls    int
+	Duration time.Duration
+}
+
+type timingKey struct{}
+
+// GetTiming returns the Timing of the request the context belongs to, or nil if SlowRequest has not run.
+// The context may be the *gin.Context or the context of its Request.
+func GetTiming(ctx context.Context) *Timing {
+	if ctx == nil {
+		return nil
+	}
+	if t, ok := ctx.Value(timingKey{}).(*Timing); ok {
+		return t
+	}
+	if c, ok := ctx.Value(gin.ContextKey).(*gin.Context); ok && c != nil {
+		t, _ := c.Value(ContextTiming).(*Timing)
+		return t
+	}
+	return nil
+}
+
+// Add records a call of the category. A nil Timing ignores it.
+func (t *Timing) Add(category string, d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c, ok := t.categories[category]
+	if !ok {
+		c = &TimingCategory{}
+		t.categories[category] = c
+	}
+	c.Calls++
+	c.Duration += d
+}
+
+// Track records the time from now until the returned function is called under the category of the request's Timing.
+//
+//	defer logger.Track(c, "geocoding")()
+func Track(ctx context.Context, category string) func() {
+	t := GetTiming(ctx)
+	if t == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		t.Add(category, time.Since(start))
+	}
+}
+
+// Handler returns the time spent in the handlers after HandlerTiming, or zero if it did not run.
+func (t *Timing) Handler() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.handlerStart.IsZero() || t.handlerEnd.IsZero() {
+		return 0
+	}
+	return t.handlerEnd.Sub(t.handlerStart)
+}
+
+// Category returns the recorded calls of the category.
+func (t *Timing) Category(name string) TimingCategory {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if c, ok := t.categories[name]; ok {
+		return *c
+	}
+	return TimingCategory{}
+}
+
+// attrs returns the breakdown of a request that took total.
+func (t *Timing) attrs(total time.Duration) []any {
+	handler := t.Handler()
+	attrs := []any{slog.Float64("total_ms", milliseconds(total))}
+	if handler > 0 {
+		attrs = append(attrs,
+			slog.Float64("middleware_ms", milliseconds(total-handler)),
+			slog.Float64("handler_ms", milliseconds(handler)),
+		)
+	}
+	t.mu.Lock()
+	names := make([]string, 0, len(t.categories))
+	for name := range t.categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c := t.categories[name]
+		attrs = append(attrs, slog.Group(name, slog.Int("calls", c.Calls), slog.Float64("ms", milliseconds(c.Duration))))
+	}
+	t.mu.Unlock()
+	return attrs
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// SlowRequestConfig defines the behavior of SlowRequestWithConfig.
+type SlowRequestConfig struct {
+	// Threshold is the latency above which a request is logged. Zero means DefaultSlowRequestThreshold.
+	Threshold time.Duration
+	// Logger receives the entries, at warning level. Nil means slog.Default().
+	Logger *slog.Logger
+}
+
+// SlowRequest logs the requests slower than DefaultSlowRequestThreshold.
+func SlowRequest() gin.HandlerFunc {
+	return SlowRequestWithConfig(SlowRequestConfig{})
+}
+
+// SlowRequestWithConfig returns a middleware that logs the timing breakdown of the requests slower than the threshold:
+// the time spent in middlewares and in the handler, and the calls of every category recorded with Timing.Add, such as
+// Redis commands when TimingRedisHook is added to the client pool.
+//
+// It should be registered first. The handler time is only measured if HandlerTiming is registered after the other
+// middlewares.
+func SlowRequestWithConfig(config SlowRequestConfig) gin.HandlerFunc {
+	if config.Threshold == 0 {
+		config.Threshold = DefaultSlowRequestThreshold
+	}
+	return func(c *gin.Context) {
+		t := &Timing{start: time.Now(), categories: make(map[string]*TimingCategory)}
+		c.Set(ContextTiming, t)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), timingKey{}, t))
+
+		c.Next()
+
+		total := time.Since(t.start)
+		if total < config.Threshold {
+			return
+		}
+		l := config.Logger
+		if l == nil {
+			l = slog.Default()
+		}
+		l.WarnContext(c, "slow request",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", c.Writer.Status(),
+			"threshold_ms", milliseconds(config.Threshold),
+			slog.Group("timing", t.attrs(total)...),
+		)
+	}
+}
+
+// HandlerTiming marks the start and the end of the handler in the request's Timing.
+// Register it after all other middlewares.
+func HandlerTiming() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t := GetTiming(c)
+		if t == nil {
+			c.Next()
+			return
+		}
+		start := time.Now()
+		c.Next()
+		end := time.Now()
+		t.mu.Lock()
+		t.handlerStart, t.handlerEnd = start, end
+		t.mu.Unlock()
+	}
+}
+
+// TimingRedisHook records the duration of Redis commands and pipelines under TimingRedis in the Timing of the
+// request the command context belongs to. Add it to a pool with redis.ClientPool.AddHook.
+type TimingRedisHook struct{}
+
+var _ redis.Hook = TimingRedisHook{}
+
+func (TimingRedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (TimingRedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		defer Track(ctx, TimingRedis)()
+		return next(ctx, cmd)
+	}
+}
+
+func (TimingRedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		defer Track(ctx, TimingRedis)()
+		return next(ctx, cmds)
+	}
+}
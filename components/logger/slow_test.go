@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlowRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	buffer := bytes.Buffer{}
+	l, err := NewLogger(nil, &buffer)
+	assert.NoError(t, err)
+
+	r := gin.New()
+	r.Use(SlowRequestWithConfig(SlowRequestConfig{Threshold: 20 * time.Millisecond, Logger: l.Logger}), AppendRequestID(),
+		func(c *gin.Context) {
+			time.Sleep(5 * time.Millisecond)
+			c.Next()
+		}, HandlerTiming())
+	r.GET("/slow", func(c *gin.Context) {
+		for i := 0; i < 2; i++ {
+			done := Track(c.Request.Context(), TimingRedis)
+			time.Sleep(10 * time.Millisecond)
+			done()
+		}
+		c.Status(http.StatusNoContent)
+	})
+	r.GET("/fast", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	for _, path := range []string{"/fast", "/slow"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		r.ServeHTTP(w, req)
+	}
+
+	entries := decodeEntries(t, &buffer)
+	assert.Len(t, entries, 1)
+	entry := entries[0]
+	assert.Equal(t, "slow request", entry["msg"])
+	assert.Equal(t, "/slow", entry["path"])
+	assert.NotEmpty(t, entry[AttrRequestID])
+	timing := entry["timing"].(map[string]any)
+	assert.GreaterOrEqual(t, timing["total_ms"], 25.0)
+	assert.GreaterOrEqual(t, timing["handler_ms"], 20.0)
+	assert.GreaterOrEqual(t, timing["middleware_ms"], 5.0)
+	redis := timing[TimingRedis].(map[string]any)
+	assert.Equal(t, 2.0, redis["calls"])
+	assert.GreaterOrEqual(t, redis["ms"], 20.0)
+}
+
+func TestTiming(t *testing.T) {
+	var missing *Timing
+	missing.Add(TimingRedis, time.Second)
+	Track(nil, TimingRedis)()
+
+	timing := &Timing{categories: make(map[string]*TimingCategory)}
+	timing.Add(TimingRedis, time.Second)
+	timing.Add(TimingRedis, time.Second)
+	assert.Equal(t, TimingCategory{Calls: 2, Duration: 2 * time.Second}, timing.Category(TimingRedis))
+	assert.Equal(t, TimingCategory{}, timing.Category("mysql"))
+	assert.Equal(t, time.Duration(0), timing.Handler())
+}
@@ -0,0 +1,263 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rhosocial/go-rush-common/components/redact"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// ContextPrincipal is the key under which authentication middlewares store the authenticated principal.
+const ContextPrincipal = "Principal"
+
+const (
+	AttrRequestID = "request_id"
+	AttrPrincipal = "principal"
+	AttrRoute     = "route"
+	AttrPackage   = "package"
+)
+
+// EnvLoggerSampling limits the number of identical entries (same level and message) logged per tick.
+// The first Initial entries are logged, then every Thereafter-th entry.
+type EnvLoggerSampling struct {
+	Initial    uint32 `yaml:"Initial,omitempty" default:"100" validate:"min=1"`
+	Thereafter uint32 `yaml:"Thereafter,omitempty" default:"100" validate:"min=1"`
+	Tick       uint16 `yaml:"Tick,omitempty" default:"1" validate:"min=1,max=3600"`
+}
+
+// EnvLogger defines the application logger.
+type EnvLogger struct {
+	Level     string             `yaml:"Level,omitempty" default:"info" validate:"omitempty,oneof=debug info warn error"`
+	Format    string             `yaml:"Format,omitempty" default:"json" validate:"omitempty,oneof=json console"`
+	AddSource bool               `yaml:"AddSource,omitempty" default:"false"`
+	Sampling  *EnvLoggerSampling `yaml:"Sampling,omitempty"`
+	// Packages overrides the level for specific packages, e.g. `redis: debug`.
+	Packages map[string]string `yaml:"Packages,omitempty" validate:"dive,oneof=debug info warn error"`
+	// Redact lists the attribute keys to mask in addition to the default sensitive headers and query parameters.
+	Redact *redact.EnvRedact `yaml:"Redact,omitempty"`
+}
+
+func (e *EnvLogger) Validate() error {
+	validate := validator.New()
+	if e.Sampling != nil {
+		if err := validate.Struct(e.Sampling); err != nil {
+			return err
+		}
+	}
+	return validate.Struct(e)
+}
+
+// LoadEnvLogger parses the YAML logger configuration and validates it.
+func LoadEnvLogger(data []byte) (*EnvLogger, error) {
+	var e EnvLogger
+	if err := yaml.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// ParseLevel converts a level name (debug, info, warn, error) into a slog.Level.
+// An empty name means info.
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if name == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q: %w", name, err)
+	}
+	return level, nil
+}
+
+// Logger is the structured application logger.
+//
+// It is a *slog.Logger whose entries automatically carry the request ID, principal and route when logged with a
+// *gin.Context (or a context derived from it), and whose level can be overridden per package.
+type Logger struct {
+	*slog.Logger
+	level     *slog.LevelVar
+	packages  sync.Map // map[string]*slog.LevelVar
+	handler   *handler
+	overrides *overrides
+
+	mu                 sync.Mutex
+	reverts            map[string]*revert
+	configured         slog.Level
+	configuredPackages map[string]slog.Level
+}
+
+// NewLogger creates a Logger writing to w. A nil config means the defaults.
+func NewLogger(config *EnvLogger, w io.Writer) (*Logger, error) {
+	if config == nil {
+		config = &EnvLogger{}
+	}
+	level, err := ParseLevel(config.Level)
+	if err != nil {
+		return nil, err
+	}
+	options := &slog.HandlerOptions{
+		AddSource: config.AddSource,
+		// The handler filters entries itself so that per-package levels may be lower than the global one.
+		Level:       slog.Level(-1 << 30),
+		ReplaceAttr: redact.NewFromEnv(config.Redact).Attr,
+	}
+	var encoder slog.Handler
+	switch config.Format {
+	case "", FormatJSON:
+		encoder = slog.NewJSONHandler(w, options)
+	case FormatConsole:
+		encoder = slog.NewTextHandler(w, options)
+	default:
+		return nil, fmt.Errorf("invalid log format %q", config.Format)
+	}
+	l := Logger{
+		level:              new(slog.LevelVar),
+		overrides:          &overrides{},
+		reverts:            make(map[string]*revert),
+		configured:         level,
+		configuredPackages: make(map[string]slog.Level, len(config.Packages)),
+	}
+	l.level.Set(level)
+	for name, value := range config.Packages {
+		packageLevel, err := ParseLevel(value)
+		if err != nil {
+			return nil, err
+		}
+		l.configuredPackages[name] = packageLevel
+		l.packageOverride(name).Set(packageLevel)
+	}
+	l.handler = &handler{inner: encoder, level: l.level, overrides: l.overrides}
+	if config.Sampling != nil {
+		l.handler.sampler = newSampler(config.Sampling)
+	}
+	l.Logger = slog.New(l.handler)
+	return &l, nil
+}
+
+// Level returns the global level. It may be changed at any time; see also SetLevel.
+func (l *Logger) Level() *slog.LevelVar {
+	return l.level
+}
+
+// PackageLevel returns the level of the named package: its override if one is set, otherwise the global level, which
+// the package follows. Use SetPackageLevel to set an override.
+func (l *Logger) PackageLevel(name string) *slog.LevelVar {
+	if level, ok := l.packages.Load(name); ok {
+		return level.(*slog.LevelVar)
+	}
+	return l.level
+}
+
+// packageOverride returns the level override of the named package, creating it from the global level if absent.
+func (l *Logger) packageOverride(name string) *slog.LevelVar {
+	if level, ok := l.packages.Load(name); ok {
+		return level.(*slog.LevelVar)
+	}
+	level := new(slog.LevelVar)
+	level.Set(l.level.Level())
+	actual, _ := l.packages.LoadOrStore(name, level)
+	return actual.(*slog.LevelVar)
+}
+
+// Package returns a logger for the named package. Its entries carry the package name and obey the package level,
+// if one is configured, otherwise the global level.
+func (l *Logger) Package(name string) *slog.Logger {
+	h := *l.handler
+	h.packageName = name
+	h.packages = &l.packages
+	return slog.New(h.WithAttrs([]slog.Attr{slog.String(AttrPackage, name)}))
+}
+
+var defaultLogger atomic.Pointer[Logger]
+
+func init() {
+	l, _ := NewLogger(nil, os.Stderr)
+	defaultLogger.Store(l)
+}
+
+// Default returns the process-wide application logger.
+func Default() *Logger {
+	return defaultLogger.Load()
+}
+
+// SetDefault replaces the process-wide application logger, and makes it the slog default as well.
+func SetDefault(l *Logger) {
+	defaultLogger.Store(l)
+	slog.SetDefault(l.Logger)
+}
+
+// sampler counts entries by level and message in a fixed number of buckets, like zap does.
+type sampler struct {
+	tick       int64
+	initial    uint64
+	thereafter uint64
+	counters   [4096]samplerCounter
+}
+
+type samplerCounter struct {
+	resetAt atomic.Int64
+	count   atomic.Uint64
+}
+
+func newSampler(e *EnvLoggerSampling) *sampler {
+	s := sampler{
+		tick:       int64(time.Duration(e.Tick) * time.Second),
+		initial:    uint64(e.Initial),
+		thereafter: uint64(e.Thereafter),
+	}
+	if s.tick <= 0 {
+		s.tick = int64(time.Second)
+	}
+	if s.initial == 0 {
+		s.initial = 100
+	}
+	if s.thereafter == 0 {
+		s.thereafter = 100
+	}
+	return &s
+}
+
+// allow reports whether the entry should be logged.
+func (s *sampler) allow(t time.Time, level slog.Level, message string) bool {
+	h := fnv32a(message) + uint32(level)
+	counter := &s.counters[h%uint32(len(s.counters))]
+	now := t.UnixNano()
+	resetAt := counter.resetAt.Load()
+	var n uint64
+	if resetAt > now {
+		n = counter.count.Add(1)
+	} else {
+		counter.count.Store(1)
+		counter.resetAt.Store(now + s.tick)
+		n = 1
+	}
+	return n <= s.initial || (n-s.initial)%s.thereafter == 0
+}
+
+func fnv32a(s string) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	hash := uint32(offset32)
+	for i := 0; i < len(s); i++ {
+		hash ^= uint32(s[i])
+		hash *= prime32
+	}
+	return hash
+}
@@ -0,0 +1,143 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rhosocial/go-rush-common/components/redact"
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeEntries(t *testing.T, buffer *bytes.Buffer) []map[string]any {
+	entries := make([]map[string]any, 0)
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		entry := make(map[string]any)
+		assert.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestLoadEnvLogger(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		e, err := LoadEnvLogger([]byte(`
+Level: warn
+Format: console
+Sampling:
+  Initial: 10
+  Thereafter: 5
+  Tick: 1
+Packages:
+  redis: debug
+`))
+		assert.NoError(t, err)
+		assert.Equal(t, "warn", e.Level)
+		assert.Equal(t, FormatConsole, e.Format)
+		assert.Equal(t, uint32(10), e.Sampling.Initial)
+		assert.Equal(t, "debug", e.Packages["redis"])
+	})
+	t.Run("invalid level", func(t *testing.T) {
+		_, err := LoadEnvLogger([]byte("Level: verbose"))
+		assert.Error(t, err)
+	})
+	t.Run("invalid package level", func(t *testing.T) {
+		_, err := LoadEnvLogger([]byte("Packages:\n  redis: verbose"))
+		assert.Error(t, err)
+	})
+}
+
+func TestNewLogger(t *testing.T) {
+	t.Run("levels", func(t *testing.T) {
+		buffer := bytes.Buffer{}
+		l, err := NewLogger(&EnvLogger{Level: "warn", Packages: map[string]string{"redis": "debug"}}, &buffer)
+		assert.NoError(t, err)
+
+		l.Info("dropped")
+		l.Warn("kept")
+		l.Package("redis").Debug("package kept")
+		l.Package("mysql").Info("package dropped")
+
+		entries := decodeEntries(t, &buffer)
+		assert.Len(t, entries, 2)
+		assert.Equal(t, "kept", entries[0]["msg"])
+		assert.Equal(t, "package kept", entries[1]["msg"])
+		assert.Equal(t, "redis", entries[1][AttrPackage])
+
+		l.Level().Set(slog.LevelInfo)
+		buffer.Reset()
+		l.Info("kept after change")
+		assert.Len(t, decodeEntries(t, &buffer), 1)
+	})
+
+	t.Run("console format", func(t *testing.T) {
+		buffer := bytes.Buffer{}
+		l, err := NewLogger(&EnvLogger{Format: FormatConsole}, &buffer)
+		assert.NoError(t, err)
+		l.Info("hello", "key", "value")
+		assert.Contains(t, buffer.String(), "msg=hello key=value")
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		_, err := NewLogger(&EnvLogger{Format: "xml"}, &bytes.Buffer{})
+		assert.Error(t, err)
+	})
+
+	t.Run("sampling", func(t *testing.T) {
+		buffer := bytes.Buffer{}
+		l, err := NewLogger(&EnvLogger{Sampling: &EnvLoggerSampling{Initial: 2, Thereafter: 3, Tick: 60}}, &buffer)
+		assert.NoError(t, err)
+		for i := 0; i < 10; i++ {
+			l.Info("repeated")
+		}
+		l.Info("other")
+		// 2 initial entries, then the 5th and the 8th, plus the different message.
+		assert.Len(t, decodeEntries(t, &buffer), 5)
+	})
+}
+
+func TestLogger_RequestAttrs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	buffer := bytes.Buffer{}
+	l, err := NewLogger(nil, &buffer)
+	assert.NoError(t, err)
+
+	r := gin.New()
+	r.Use(AppendRequestID(), func(c *gin.Context) {
+		c.Set(ContextPrincipal, "alice")
+	})
+	r.GET("/users/:id", func(c *gin.Context) {
+		l.InfoContext(c, "handled")
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/users/1", nil)
+	r.ServeHTTP(w, req)
+
+	entries := decodeEntries(t, &buffer)
+	assert.Len(t, entries, 1)
+	assert.NotEmpty(t, entries[0][AttrRequestID])
+	assert.Equal(t, "alice", entries[0][AttrPrincipal])
+	assert.Equal(t, "/users/:id", entries[0][AttrRoute])
+}
+
+func TestNewLogger_Redact(t *testing.T) {
+	buffer := bytes.Buffer{}
+	l, err := NewLogger(&EnvLogger{Redact: &redact.EnvRedact{QueryParams: []string{"sign"}}}, &buffer)
+	assert.NoError(t, err)
+	l.Info("login", "password", "secret-password", "sign", "secret-sign", "user", "alice")
+
+	entries := decodeEntries(t, &buffer)
+	assert.Equal(t, redact.Mask, entries[0]["password"])
+	assert.Equal(t, redact.Mask, entries[0]["sign"])
+	assert.Equal(t, "alice", entries[0]["user"])
+}
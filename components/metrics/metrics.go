@@ -0,0 +1,207 @@
+// Package metrics exposes Prometheus metrics for HTTP requests, the Redis client pool and activity pools.
+package metrics
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rhosocial/go-rush-common/components/redis"
+	"github.com/rhosocial/go-rush-common/models/activity"
+)
+
+const (
+	DefaultPath      = "/metrics"
+	DefaultNamespace = "go_rush"
+)
+
+// RouteUnmatched is the route label of requests that matched no route.
+const RouteUnmatched = "unmatched"
+
+// Config defines what Setup registers.
+type Config struct {
+	// Registry receives all collectors. Nil means a new registry with the Go and process collectors.
+	Registry *prometheus.Registry
+	// Path is the route serving the metrics. Empty means DefaultPath.
+	Path string
+	// Namespace prefixes every metric name. Empty means DefaultNamespace.
+	Namespace string
+	// Buckets are the latency histogram buckets, in seconds. Nil means prometheus.DefBuckets.
+	Buckets []float64
+	// RedisClientPool, if present, has its per-server pool statistics and health exported.
+	RedisClientPool *redis.ClientPool
+	// ActivityPools, if present, have their size and limit exported, labelled by map key.
+	ActivityPools map[string]*activity.Pool
+}
+
+// Metrics holds the registered collectors.
+type Metrics struct {
+	Registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+}
+
+// Setup registers the HTTP middleware, the metrics route and the collectors of the configured components on r.
+// It must be called before the routes to be measured are registered, since gin only applies middlewares to the routes
+// registered after them.
+func Setup(r *gin.Engine, config Config) (*Metrics, error) {
+	if config.Path == "" {
+		config.Path = DefaultPath
+	}
+	if config.Namespace == "" {
+		config.Namespace = DefaultNamespace
+	}
+	if config.Buckets == nil {
+		config.Buckets = prometheus.DefBuckets
+	}
+	m := Metrics{Registry: config.Registry}
+	if m.Registry == nil {
+		m.Registry = prometheus.NewRegistry()
+		m.Registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	}
+	m.requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+	m.latency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: config.Namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of HTTP requests by method, route and status.",
+		Buckets:   config.Buckets,
+	}, []string{"method", "route", "status"})
+	cs := []prometheus.Collector{m.requests, m.latency}
+	if config.RedisClientPool != nil {
+		cs = append(cs, NewRedisCollector(config.Namespace, config.RedisClientPool))
+	}
+	if len(config.ActivityPools) > 0 {
+		cs = append(cs, NewActivityCollector(config.Namespace, config.ActivityPools))
+	}
+	for _, c := range cs {
+		if err := m.Registry.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	r.Use(m.Middleware())
+	r.GET(config.Path, gin.WrapH(promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})))
+	return &m, nil
+}
+
+// Middleware records the count and latency of each request.
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = RouteUnmatched
+		}
+		labels := prometheus.Labels{
+			"method": c.Request.Method,
+			"route":  route,
+			"status": strconv.Itoa(c.Writer.Status()),
+		}
+		m.requests.With(labels).Inc()
+		m.latency.With(labels).Observe(time.Since(start).Seconds())
+	}
+}
+
+// DefaultRedisCollectTimeout bounds the pings of a scrape of a RedisCollector.
+const DefaultRedisCollectTimeout = 2 * time.Second
+
+// RedisCollector exports the pool statistics and health of every server of a redis.ClientPool.
+type RedisCollector struct {
+	// Timeout bounds the pings of every server on a scrape.
+	Timeout    time.Duration
+	pool       *redis.ClientPool
+	hits       *prometheus.Desc
+	misses     *prometheus.Desc
+	timeouts   *prometheus.Desc
+	totalConns *prometheus.Desc
+	idleConns  *prometheus.Desc
+	staleConns *prometheus.Desc
+	up         *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*RedisCollector)(nil)
+
+// NewRedisCollector creates a collector for the pool.
+func NewRedisCollector(namespace string, pool *redis.ClientPool) *RedisCollector {
+	labels := []string{"server", "addr"}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "redis", name), help, labels, nil)
+	}
+	return &RedisCollector{
+		Timeout:    DefaultRedisCollectTimeout,
+		pool:       pool,
+		hits:       desc("pool_hits_total", "Number of times a free connection was found in the pool."),
+		misses:     desc("pool_misses_total", "Number of times a free connection was not found in the pool."),
+		timeouts:   desc("pool_timeouts_total", "Number of times a wait timeout occurred."),
+		totalConns: desc("pool_total_conns", "Number of total connections in the pool."),
+		idleConns:  desc("pool_idle_conns", "Number of idle connections in the pool."),
+		staleConns: desc("pool_stale_conns_total", "Number of stale connections removed from the pool."),
+		up:         desc("server_up", "Whether the server answered a PING (1) or not (0)."),
+	}
+}
+
+func (c *RedisCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{c.hits, c.misses, c.timeouts, c.totalConns, c.idleConns, c.staleConns, c.up} {
+		ch <- d
+	}
+}
+
+func (c *RedisCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+	for i, server := range c.pool.Snapshot(ctx) {
+		stats := server.Stats
+		labels := []string{strconv.Itoa(i), server.Addr}
+		ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits), labels...)
+		ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses), labels...)
+		ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(stats.Timeouts), labels...)
+		ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stats.TotalConns), labels...)
+		ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stats.IdleConns), labels...)
+		ch <- prometheus.MustNewConstMetric(c.staleConns, prometheus.CounterValue, float64(stats.StaleConns), labels...)
+		up := 0.0
+		if server.Valid {
+			up = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, up, labels...)
+	}
+}
+
+// ActivityCollector exports the size and the limit of activity pools.
+type ActivityCollector struct {
+	pools map[string]*activity.Pool
+	size  *prometheus.Desc
+	limit *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*ActivityCollector)(nil)
+
+// NewActivityCollector creates a collector for the pools, labelled by map key.
+func NewActivityCollector(namespace string, pools map[string]*activity.Pool) *ActivityCollector {
+	return &ActivityCollector{
+		pools: pools,
+		size:  prometheus.NewDesc(prometheus.BuildFQName(namespace, "activity", "pool_size"), "Number of activities in the pool.", []string{"pool"}, nil),
+		limit: prometheus.NewDesc(prometheus.BuildFQName(namespace, "activity", "pool_limit"), "Capacity of the pool.", []string{"pool"}, nil),
+	}
+}
+
+func (c *ActivityCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.size
+	ch <- c.limit
+}
+
+func (c *ActivityCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, pool := range c.pools {
+		ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(pool.Len()), name)
+		ch <- prometheus.MustNewConstMetric(c.limit, prometheus.GaugeValue, float64(pool.Limit()), name)
+	}
+}
@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rhosocial/go-rush-common/components/redis"
+	"github.com/rhosocial/go-rush-common/models/activity"
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(r *gin.Engine) string {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, DefaultPath, nil)
+	r.ServeHTTP(w, req)
+	return w.Body.String()
+}
+
+func TestSetup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pool := redis.ClientPool{}
+	pool.InitRedisClientPool(&[]redis.EnvRedisServer{{Host: "127.0.0.1", Port: 1, Weight: 1}})
+	activities := activity.NewActivityPool(5, nil)
+	_, err := activities.NewActivity("test-activity", false)
+	assert.NoError(t, err)
+
+	r := gin.New()
+	_, err = Setup(r, Config{
+		RedisClientPool: &pool,
+		ActivityPools:   map[string]*activity.Pool{"default": activities},
+	})
+	assert.NoError(t, err)
+	r.GET("/users/:id", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for _, path := range []string{"/users/1", "/users/2", "/missing"} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	body := scrape(r)
+	assert.Contains(t, body, `go_rush_http_requests_total{method="GET",route="/users/:id",status="200"} 2`)
+	assert.Contains(t, body, `go_rush_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, body, `go_rush_http_request_duration_seconds_count{method="GET",route="/users/:id",status="200"} 2`)
+	assert.Contains(t, body, `go_rush_redis_server_up{addr="127.0.0.1:1",server="0"} 0`)
+	assert.Contains(t, body, `go_rush_redis_pool_total_conns{addr="127.0.0.1:1",server="0"}`)
+	assert.Contains(t, body, `go_rush_activity_pool_size{pool="default"} 1`)
+	assert.Contains(t, body, `go_rush_activity_pool_limit{pool="default"} 5`)
+	assert.Contains(t, body, `go_goroutines`)
+}
+
+func TestSetup_CustomRegistry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := prometheus.NewRegistry()
+	r := gin.New()
+	m, err := Setup(r, Config{Registry: registry, Namespace: "svc", Path: "/internal/metrics"})
+	assert.NoError(t, err)
+	assert.Same(t, registry, m.Registry)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/internal/metrics", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "go_goroutines")
+
+	_, err = Setup(gin.New(), Config{Registry: registry, Namespace: "svc"})
+	assert.Error(t, err, "registering the same metrics twice should fail")
+}
+
+func TestRedisCollector(t *testing.T) {
+	// The server accepts the connections but never answers.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	addr := listener.Addr().(*net.TCPAddr)
+
+	pool := redis.ClientPool{}
+	pool.InitRedisClientPool(&[]redis.EnvRedisServer{{Host: "127.0.0.1", Port: uint16(addr.Port), Weight: 1}})
+	defer pool.Close()
+	collector := NewRedisCollector(DefaultNamespace, &pool)
+	collector.Timeout = 100 * time.Millisecond
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+
+	t.Run("bounded pings", func(t *testing.T) {
+		start := time.Now()
+		families, err := registry.Gather()
+		assert.NoError(t, err)
+		assert.Less(t, time.Since(start), time.Second)
+		assert.NotEmpty(t, families)
+	})
+
+	t.Run("reconfigured pool", func(t *testing.T) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 20; i++ {
+				servers := []redis.EnvRedisServer{{Host: "127.0.0.1", Port: 1, Weight: 1}}
+				if i%2 == 0 {
+					servers = append(servers, redis.EnvRedisServer{Host: "127.0.0.1", Port: 2, Weight: 1})
+				}
+				assert.NoError(t, pool.Reconfigure(servers))
+			}
+		}()
+		for i := 0; i < 20; i++ {
+			_, err := registry.Gather()
+			assert.NoError(t, err)
+		}
+		<-done
+	})
+}
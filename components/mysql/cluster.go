@@ -0,0 +1,295 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrNoPrimary = errors.New("mysql servers need exactly one primary")
+
+// ValidateServers validates the servers of a cluster, and checks that exactly one of them is the primary.
+func ValidateServers(servers []EnvMySQLServer) error {
+	primaries := 0
+	for i := range servers {
+		if err := servers[i].Validate(); err != nil {
+			return fmt.Errorf("mysql server %d: %w", i, err)
+		}
+		if !servers[i].IsReplica() {
+			primaries++
+		}
+	}
+	if primaries != 1 {
+		return fmt.Errorf("%w, got %d", ErrNoPrimary, primaries)
+	}
+	return nil
+}
+
+// NodeStatus is the health of a server of a Cluster.
+type NodeStatus struct {
+	Addr   string `json:"addr"`
+	Role   string `json:"role"`
+	Weight uint8  `json:"weight"`
+	ServerStatus
+	// Lag is the replication lag of a replica in seconds, -1 if unknown or if the replication is stopped.
+	Lag       int64     `json:"lag"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Node is a server of a Cluster.
+type Node struct {
+	*Pool
+	status atomic.Pointer[NodeStatus]
+}
+
+// Health returns the health of the node as of its last check.
+func (n *Node) Health() NodeStatus {
+	if status := n.status.Load(); status != nil {
+		return *status
+	}
+	status := n.newStatus()
+	status.Message = "not checked yet"
+	return status
+}
+
+func (n *Node) newStatus() NodeStatus {
+	role := RolePrimary
+	if n.server.IsReplica() {
+		role = RoleReplica
+	}
+	return NodeStatus{Addr: n.server.Addr(), Role: role, Weight: n.server.Weight, Lag: -1}
+}
+
+// Healthy reports whether the last check of the node succeeded, within the lag limit for a replica.
+func (n *Node) Healthy() bool {
+	status := n.status.Load()
+	return status != nil && status.Valid
+}
+
+// Cluster routes the queries to the servers of a primary and its replicas: the reads go to the healthy replicas in
+// turn, each one as often as its weight like redis.ClientPool, and the writes and transactions go to the primary. The
+// reads fall back to the primary when no replica is healthy, i.e. reachable and lagging at most Health.MaxLag behind.
+type Cluster struct {
+	primary  *Node
+	replicas []*Node
+	turnMap  []uint8
+	turn     atomic.Uint32
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// OpenCluster opens the pools of the servers, waits for the primary to answer like Open, checks the replicas and
+// keeps checking every server at its Health.Interval until closed. Replicas down at startup do not prevent it from
+// opening; they receive reads once healthy.
+func OpenCluster(ctx context.Context, servers []EnvMySQLServer) (*Cluster, error) {
+	c, err := newCluster(servers)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.primary.ping(ctx); err != nil {
+		_ = c.closePools()
+		return nil, fmt.Errorf("mysql %s: %w", c.primary.server.GetRedactedDSN(), err)
+	}
+	for _, node := range c.Nodes() {
+		c.check(ctx, node)
+	}
+	checks, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	for _, node := range c.Nodes() {
+		c.wg.Add(1)
+		go c.run(checks, node)
+	}
+	return c, nil
+}
+
+// newCluster creates the pools of the servers without connecting to them.
+func newCluster(servers []EnvMySQLServer) (*Cluster, error) {
+	servers = append([]EnvMySQLServer(nil), servers...)
+	if err := ValidateServers(servers); err != nil {
+		return nil, err
+	}
+	c := Cluster{turnMap: make([]uint8, 0)}
+	for _, server := range servers {
+		pool, err := newPool(server)
+		if err != nil {
+			_ = c.closePools()
+			return nil, err
+		}
+		node := &Node{Pool: pool}
+		if !server.IsReplica() {
+			c.primary = node
+			continue
+		}
+		c.replicas = append(c.replicas, node)
+		for i := 0; i < int(max(server.Weight, 1)); i++ {
+			c.turnMap = append(c.turnMap, uint8(len(c.replicas)-1))
+		}
+	}
+	return &c, nil
+}
+
+// Primary returns the pool of the primary, for the writes and the transactions.
+func (c *Cluster) Primary() *sql.DB {
+	return c.primary.DB()
+}
+
+// Replica returns the pool of the next healthy replica for the reads, the pool of the primary if there is none.
+func (c *Cluster) Replica() *sql.DB {
+	return c.replica().DB()
+}
+
+func (c *Cluster) replica() *Node {
+	n := uint32(len(c.turnMap))
+	for i := uint32(0); i < n; i++ {
+		if node := c.replicas[c.turnMap[c.turn.Add(1)%n]]; node.Healthy() {
+			return node
+		}
+	}
+	return c.primary
+}
+
+// QueryContext runs a read on a replica in a client span, see Replica.
+func (c *Cluster) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return c.replica().QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext runs a read returning a row on a replica in a client span, see Replica.
+func (c *Cluster) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return c.replica().QueryRowContext(ctx, query, args...)
+}
+
+// ExecContext runs a write on the primary in a client span.
+func (c *Cluster) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return c.primary.ExecContext(ctx, query, args...)
+}
+
+// BeginTx starts a transaction on the primary in a client span, read-only ones included, so that they see the latest
+// writes.
+func (c *Cluster) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return c.primary.BeginTx(ctx, opts)
+}
+
+// Nodes returns the primary, then the replicas.
+func (c *Cluster) Nodes() []*Node {
+	return append([]*Node{c.primary}, c.replicas...)
+}
+
+// Health returns the health of every node as of its last check, the primary first.
+func (c *Cluster) Health() []NodeStatus {
+	nodes := c.Nodes()
+	result := make([]NodeStatus, len(nodes))
+	for i, node := range nodes {
+		result[i] = node.Health()
+	}
+	return result
+}
+
+// Check checks every node now, and returns their health.
+func (c *Cluster) Check(ctx context.Context) []NodeStatus {
+	for _, node := range c.Nodes() {
+		c.check(ctx, node)
+	}
+	return c.Health()
+}
+
+func (c *Cluster) run(ctx context.Context, node *Node) {
+	defer c.wg.Done()
+	ticker := time.NewTicker(time.Duration(node.server.Health.Interval) * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.check(ctx, node)
+		}
+	}
+}
+
+// check pings the node and, for a replica, measures its replication lag.
+func (c *Cluster) check(ctx context.Context, node *Node) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(node.server.Dialer.Timeout)*time.Second)
+	defer cancel()
+	status := node.newStatus()
+	status.CheckedAt = time.Now()
+	status.ServerStatus = *node.Pool.Status(ctx)
+	if status.Valid && node.server.IsReplica() {
+		lag, err := replicaLag(ctx, node.DB())
+		status.Lag = lag
+		maxLag := int64(node.server.Health.MaxLag)
+		switch {
+		case err != nil:
+			status.Valid, status.Message = false, err.Error()
+		case lag < 0:
+			status.Valid, status.Message = false, "replication stopped"
+		case maxLag > 0 && lag > maxLag:
+			status.Valid, status.Message = false, fmt.Sprintf("replication lag %ds beyond %ds", lag, maxLag)
+		}
+	}
+	node.status.Store(&status)
+}
+
+// replicaLag returns the replication lag of the server in seconds, -1 if its replication is stopped, 0 if it
+// replicates nothing.
+func replicaLag(ctx context.Context, db *sql.DB) (int64, error) {
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		// Before MySQL 8.0.22.
+		if rows, err = db.QueryContext(ctx, "SHOW SLAVE STATUS"); err != nil {
+			return -1, err
+		}
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return -1, err
+	}
+	if !rows.Next() {
+		return 0, rows.Err()
+	}
+	values := make([]sql.NullString, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		return -1, err
+	}
+	for i, column := range columns {
+		if column == "Seconds_Behind_Source" || column == "Seconds_Behind_Master" {
+			if !values[i].Valid {
+				return -1, nil
+			}
+			return strconv.ParseInt(values[i].String, 10, 64)
+		}
+	}
+	return -1, errors.New("no replication lag in the replica status")
+}
+
+// Close stops the health checks and closes the pools.
+func (c *Cluster) Close() error {
+	if c == nil {
+		return nil
+	}
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.wg.Wait()
+	return c.closePools()
+}
+
+func (c *Cluster) closePools() error {
+	errs := make([]error, 0, len(c.replicas)+1)
+	if c.primary != nil {
+		errs = append(errs, c.primary.Close())
+	}
+	for _, node := range c.replicas {
+		errs = append(errs, node.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,92 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testServers(port uint16) []EnvMySQLServer {
+	server := EnvMySQLServer{Host: "127.0.0.1", Port: port, Username: "root", DB: "node", Charset: "utf8mb4", Location: "Local",
+		Startup: &EnvMySQLServerStartup{Retries: 0, Interval: 100}}
+	replica1, replica2 := server, server
+	replica1.Role, replica1.Weight = RoleReplica, 1
+	replica2.Role, replica2.Weight, replica2.DB = RoleReplica, 3, "replica"
+	return []EnvMySQLServer{replica1, server, replica2}
+}
+
+func TestValidateServers(t *testing.T) {
+	servers := testServers(3306)
+	assert.NoError(t, ValidateServers(servers))
+	assert.ErrorIs(t, ValidateServers(servers[:1]), ErrNoPrimary)
+	assert.ErrorIs(t, ValidateServers(append(servers, EnvMySQLServer{})), ErrNoPrimary)
+	servers[2].Weight = 11
+	assert.ErrorContains(t, ValidateServers(servers), "mysql server 2")
+}
+
+func TestCluster(t *testing.T) {
+	addr, _ := refusingServer(t)
+	c, err := newCluster(testServers(uint16(addr.Port)))
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, c.Close())
+	}()
+	primary, replica1, replica2 := c.Nodes()[0].DB(), c.Nodes()[1].DB(), c.Nodes()[2].DB()
+	assert.Same(t, primary, c.Primary())
+	assert.Equal(t, []uint8{0, 1, 1, 1}, c.turnMap)
+
+	healthy := func(node *Node, valid bool) {
+		node.status.Store(&NodeStatus{ServerStatus: ServerStatus{Valid: valid}})
+	}
+	reads := func() map[*sql.DB]int {
+		counts := make(map[*sql.DB]int)
+		for i := 0; i < 8; i++ {
+			counts[c.Replica()]++
+		}
+		return counts
+	}
+
+	t.Run("unchecked replicas", func(t *testing.T) {
+		assert.Equal(t, map[*sql.DB]int{primary: 8}, reads())
+		assert.Equal(t, "not checked yet", c.Health()[1].Message)
+	})
+	t.Run("weighted replicas", func(t *testing.T) {
+		healthy(c.replicas[0], true)
+		healthy(c.replicas[1], true)
+		assert.Equal(t, map[*sql.DB]int{replica1: 2, replica2: 6}, reads())
+	})
+	t.Run("unhealthy replica", func(t *testing.T) {
+		healthy(c.replicas[1], false)
+		assert.Equal(t, map[*sql.DB]int{replica1: 8}, reads())
+		healthy(c.replicas[0], false)
+		assert.Equal(t, map[*sql.DB]int{primary: 8}, reads())
+	})
+	t.Run("check", func(t *testing.T) {
+		healthy(c.replicas[0], true)
+		health := c.Check(context.Background())
+		assert.Len(t, health, 3)
+		assert.Equal(t, RolePrimary, health[0].Role)
+		assert.Equal(t, RoleReplica, health[2].Role)
+		assert.Equal(t, uint8(3), health[2].Weight)
+		for _, status := range health {
+			assert.False(t, status.Valid)
+			assert.NotEmpty(t, status.Message)
+			assert.Equal(t, int64(-1), status.Lag)
+			assert.False(t, status.CheckedAt.IsZero())
+		}
+		assert.Same(t, primary, c.Replica())
+	})
+}
+
+func TestOpenCluster(t *testing.T) {
+	addr, _ := refusingServer(t)
+	_, err := OpenCluster(context.Background(), testServers(uint16(addr.Port)))
+	assert.ErrorContains(t, err, "mysql root@tcp(127.0.0.1")
+	_, err = OpenCluster(context.Background(), nil)
+	assert.ErrorIs(t, err, ErrNoPrimary)
+
+	var empty *Cluster
+	assert.NoError(t, empty.Close())
+}
@@ -0,0 +1,280 @@
+package mysql
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/rhosocial/go-rush-common/components/redact"
+)
+
+// The TLS modes of EnvMySQLServerTLS.
+const (
+	TLSDisabled   = "disabled"
+	TLSPreferred  = "preferred"
+	TLSSkipVerify = "skip-verify"
+	TLSVerify     = "verify"
+)
+
+// EnvMySQLServerTLS defines the TLS connections to a server.
+type EnvMySQLServerTLS struct {
+	// Mode is disabled; preferred, using TLS without verifying the server if it supports it; skip-verify, always using
+	// TLS without verifying the server; or verify, always using TLS and verifying the server. Empty means verify.
+	Mode string `yaml:"Mode,omitempty" default:"verify" validate:"omitempty,oneof=disabled preferred skip-verify verify"`
+	// CA is the PEM file of the certificate authorities verifying the server, the system ones if empty.
+	CA string `yaml:"CA,omitempty"`
+	// Cert and Key are the PEM files of the client certificate and its private key, if the server requires one.
+	Cert string `yaml:"Cert,omitempty" validate:"required_with=Key"`
+	Key  string `yaml:"Key,omitempty" validate:"required_with=Cert"`
+	// ServerName is the name verified in the server certificate. Empty means the host.
+	ServerName string `yaml:"ServerName,omitempty"`
+}
+
+func (e *EnvMySQLServerTLS) Validate() error {
+	validate := validator.New()
+	return validate.Struct(e)
+}
+
+func (e *EnvMySQLServerTLS) mode() string {
+	if e == nil {
+		return TLSDisabled
+	}
+	if e.Mode == "" {
+		return TLSVerify
+	}
+	return e.Mode
+}
+
+// custom reports whether the driver needs a registered configuration, i.e. files or a server name in the modes using
+// TLS always.
+func (e *EnvMySQLServerTLS) custom() bool {
+	mode := e.mode()
+	return (mode == TLSVerify || mode == TLSSkipVerify) && (e.CA != "" || e.Cert != "" || e.ServerName != "")
+}
+
+// name returns the name of the configuration in the DSN: one of the names known to the driver, or a name derived from
+// the settings for a custom configuration.
+func (e *EnvMySQLServerTLS) name() string {
+	mode := e.mode()
+	switch {
+	case e.custom():
+		sum := sha256.Sum256([]byte(strings.Join([]string{mode, e.CA, e.Cert, e.Key, e.ServerName}, "\x00")))
+		return "env-" + hex.EncodeToString(sum[:8])
+	case mode == TLSVerify:
+		return "true"
+	case mode == TLSDisabled:
+		return ""
+	}
+	return mode
+}
+
+// tlsConfigs are the custom configurations named in the DSNs built, so that ParseDSN recovers their settings.
+var tlsConfigs sync.Map
+
+// RegisterTLS reads the certificate files of the server, if any, and registers their configuration with the driver
+// under the name used by GetDSN. It is done by GetConfig, and needed before passing the DSN to sql.Open.
+func (e *EnvMySQLServer) RegisterTLS() error {
+	if e.TLS == nil || !e.TLS.custom() {
+		return nil
+	}
+	config := tls.Config{ServerName: e.TLS.ServerName, InsecureSkipVerify: e.TLS.mode() == TLSSkipVerify}
+	if e.TLS.CA != "" {
+		pem, err := os.ReadFile(e.TLS.CA)
+		if err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("tls: no certificate in %s", e.TLS.CA)
+		}
+	}
+	if e.TLS.Cert != "" {
+		certificate, err := tls.LoadX509KeyPair(e.TLS.Cert, e.TLS.Key)
+		if err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	return mysqldriver.RegisterTLSConfig(e.TLS.name(), &config)
+}
+
+// driverConfig returns the driver configuration of the fields, the location being left to the parameters so that no
+// time zone is loaded.
+func (e *EnvMySQLServer) driverConfig() *mysqldriver.Config {
+	cfg := mysqldriver.NewConfig()
+	cfg.User = e.Username
+	cfg.Passwd = e.Password
+	cfg.Net, cfg.Addr = "tcp", e.Addr()
+	if e.Socket != "" {
+		cfg.Net = "unix"
+	}
+	cfg.DBName = e.DB
+	cfg.ParseTime = true
+	cfg.Collation = e.Collation
+	cfg.InterpolateParams = e.InterpolateParams
+	cfg.TLSConfig = e.TLS.name()
+	if e.Dialer != nil {
+		cfg.Timeout = time.Duration(e.Dialer.Timeout) * time.Second
+		cfg.ReadTimeout = time.Duration(e.Dialer.ReadTimeout) * time.Second
+		cfg.WriteTimeout = time.Duration(e.Dialer.WriteTimeout) * time.Second
+	}
+	if e.Charset != "" {
+		_ = cfg.Apply(mysqldriver.Charset(e.Charset, e.Collation))
+	}
+	cfg.Params = make(map[string]string, len(e.Params)+1)
+	for name, value := range e.Params {
+		cfg.Params[name] = value
+	}
+	if e.Location != "" {
+		cfg.Params["loc"] = e.Location
+	}
+	return cfg
+}
+
+// GetDSN returns the DSN of the server, its values escaped as the driver expects them. A custom TLS configuration must
+// be registered by RegisterTLS before the DSN is used.
+func (e EnvMySQLServer) GetDSN() string {
+	if e.TLS.custom() {
+		tlsConfigs.Store(e.TLS.name(), *e.TLS)
+	}
+	return e.driverConfig().FormatDSN()
+}
+
+// GetRedactedDSN returns the DSN with the password masked, for logs and error messages.
+func (e EnvMySQLServer) GetRedactedDSN() string {
+	if e.Password == "" {
+		return e.GetDSN()
+	}
+	e.Password = redact.Mask
+	return e.GetDSN()
+}
+
+// GetConfig registers the TLS configuration of the server, if any, and returns its driver configuration.
+func (e *EnvMySQLServer) GetConfig() (*mysqldriver.Config, error) {
+	if err := e.RegisterTLS(); err != nil {
+		return nil, err
+	}
+	return mysqldriver.ParseDSN(e.GetDSN())
+}
+
+// ParseDSN returns the server of a DSN, the reverse of GetDSN. The sections absent from DSNs, such as Pool, are left
+// nil. The timeouts must be whole seconds, as the fields of the dialer are. A custom TLS configuration is only known
+// by name in the DSN: it is recovered from the DSNs built by GetDSN in the same process, and unknown otherwise.
+func ParseDSN(dsn string) (*EnvMySQLServer, error) {
+	base, query := dsn, ""
+	if slash := strings.LastIndexByte(dsn, '/'); slash >= 0 {
+		if question := strings.IndexByte(dsn[slash:], '?'); question >= 0 {
+			base, query = dsn[:slash+question], dsn[slash+question+1:]
+		}
+	}
+	// The parameters the driver keeps to itself, or could not parse, are handled here.
+	var charset, tlsName string
+	params := make([]string, 0)
+	for _, param := range strings.Split(query, "&") {
+		name, value, _ := strings.Cut(param, "=")
+		switch name {
+		case "charset":
+			charset = value
+		case "tls":
+			tlsName = value
+		case "":
+		default:
+			params = append(params, param)
+		}
+	}
+	if len(params) > 0 {
+		base += "?" + strings.Join(params, "&")
+	}
+	cfg, err := mysqldriver.ParseDSN(base)
+	if err != nil {
+		return nil, err
+	}
+	e := EnvMySQLServer{
+		Username:          cfg.User,
+		Password:          cfg.Passwd,
+		DB:                cfg.DBName,
+		Charset:           charset,
+		Location:          cfg.Loc.String(),
+		Collation:         cfg.Collation,
+		InterpolateParams: cfg.InterpolateParams,
+		Params:            cfg.Params,
+	}
+	switch cfg.Net {
+	case "unix":
+		e.Socket = cfg.Addr
+	case "tcp":
+		host, port, err := net.SplitHostPort(cfg.Addr)
+		if err != nil {
+			return nil, err
+		}
+		p, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q", port)
+		}
+		e.Host, e.Port = host, uint16(p)
+	default:
+		return nil, fmt.Errorf("unsupported network %q", cfg.Net)
+	}
+	if cfg.Timeout > 0 || cfg.ReadTimeout > 0 || cfg.WriteTimeout > 0 {
+		timeout, err := seconds("timeout", cfg.Timeout, 8)
+		if err != nil {
+			return nil, err
+		}
+		readTimeout, err := seconds("readTimeout", cfg.ReadTimeout, 16)
+		if err != nil {
+			return nil, err
+		}
+		writeTimeout, err := seconds("writeTimeout", cfg.WriteTimeout, 16)
+		if err != nil {
+			return nil, err
+		}
+		e.Dialer = &EnvMySQLServerDialer{
+			Timeout:      uint8(timeout),
+			ReadTimeout:  uint16(readTimeout),
+			WriteTimeout: uint16(writeTimeout),
+		}
+	}
+	if e.TLS, err = parseTLS(tlsName); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// seconds returns the whole seconds of the timeout of a parameter, fitting in an unsigned integer of the bit size.
+func seconds(name string, timeout time.Duration, bitSize int) (uint64, error) {
+	if timeout%time.Second != 0 {
+		return 0, fmt.Errorf("%s %s is not a whole number of seconds", name, timeout)
+	}
+	s := uint64(timeout / time.Second)
+	if s >= 1<<bitSize {
+		return 0, fmt.Errorf("%s %s is out of range", name, timeout)
+	}
+	return s, nil
+}
+
+func parseTLS(name string) (*EnvMySQLServerTLS, error) {
+	switch strings.ToLower(name) {
+	case "", "false", "0":
+		return nil, nil
+	case "true", "1":
+		return &EnvMySQLServerTLS{Mode: TLSVerify}, nil
+	case TLSSkipVerify, TLSPreferred:
+		return &EnvMySQLServerTLS{Mode: strings.ToLower(name)}, nil
+	}
+	if config, ok := tlsConfigs.Load(name); ok {
+		e := config.(EnvMySQLServerTLS)
+		return &e, nil
+	}
+	return nil, errors.New("unknown TLS configuration " + name)
+}
@@ -0,0 +1,138 @@
+package mysql
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/rhosocial/go-rush-common/components/redact"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeCertificate writes a self-signed certificate and its key as PEM files.
+func writeCertificate(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "mysql"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestEnvMySQLServer_GetDSN_Escaping(t *testing.T) {
+	e := EnvMySQLServer{
+		Host: "db.internal", Port: 3307, Username: "app", Password: "p@ss/w?rd:)", DB: "node", Charset: "utf8mb4",
+		Location: "Europe/Paris", Collation: "utf8mb4_unicode_ci", InterpolateParams: true,
+		Dialer: &EnvMySQLServerDialer{Timeout: 3, ReadTimeout: 20},
+		Params: map[string]string{"sql_mode": "'STRICT_ALL_TABLES,NO_ZERO_DATE'"},
+	}
+	dsn := e.GetDSN()
+	assert.Equal(t, "app:p@ss/w?rd:)@tcp(db.internal:3307)/node?charset=utf8mb4&collation=utf8mb4_unicode_ci&"+
+		"interpolateParams=true&parseTime=true&readTimeout=20s&timeout=3s&loc=Europe%2FParis&"+
+		"sql_mode=%27STRICT_ALL_TABLES%2CNO_ZERO_DATE%27", dsn)
+	assert.NotContains(t, e.GetRedactedDSN(), "p@ss")
+	assert.Contains(t, e.GetRedactedDSN(), "app:"+redact.Mask+"@tcp(db.internal:3307)")
+
+	cfg, err := e.GetConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "p@ss/w?rd:)", cfg.Passwd)
+	assert.Equal(t, "Europe/Paris", cfg.Loc.String())
+	assert.True(t, cfg.ParseTime)
+
+	parsed, err := ParseDSN(dsn)
+	assert.NoError(t, err)
+	assert.Equal(t, e, *parsed)
+	assert.Equal(t, dsn, parsed.GetDSN())
+
+	_, err = (&EnvMySQLServer{Host: "db", Port: 3306, Location: "Nowhere/City"}).GetConfig()
+	assert.Error(t, err)
+}
+
+func TestParseDSN_Timeouts(t *testing.T) {
+	parsed, err := ParseDSN("root@tcp(db:3306)/node?timeout=1m&writeTimeout=90s")
+	assert.NoError(t, err)
+	assert.Equal(t, &EnvMySQLServerDialer{Timeout: 60, WriteTimeout: 90}, parsed.Dialer)
+
+	_, err = ParseDSN("root@tcp(db:3306)/node?timeout=500ms")
+	assert.ErrorContains(t, err, "timeout 500ms is not a whole number of seconds")
+	_, err = ParseDSN("root@tcp(db:3306)/node?readTimeout=1.5s")
+	assert.ErrorContains(t, err, "readTimeout 1.5s is not a whole number of seconds")
+	_, err = ParseDSN("root@tcp(db:3306)/node?timeout=256s")
+	assert.ErrorContains(t, err, "timeout 4m16s is out of range")
+}
+
+func TestEnvMySQLServer_GetDSN_Socket(t *testing.T) {
+	e := EnvMySQLServer{Socket: "/var/run/mysqld/mysqld.sock", Username: "root", DB: "node", Charset: "utf8mb4", Location: "UTC"}
+	assert.Equal(t, "root@unix(/var/run/mysqld/mysqld.sock)/node?charset=utf8mb4&parseTime=true&loc=UTC", e.GetDSN())
+	assert.Equal(t, "/var/run/mysqld/mysqld.sock", e.Addr())
+	parsed, err := ParseDSN(e.GetDSN())
+	assert.NoError(t, err)
+	assert.Equal(t, e.Socket, parsed.Socket)
+	assert.Empty(t, parsed.Host)
+	assert.Equal(t, "UTC", parsed.Location)
+}
+
+func TestEnvMySQLServer_TLS(t *testing.T) {
+	base := EnvMySQLServer{Host: "db", Port: 3306, Username: "root", DB: "node"}
+	for mode, param := range map[string]string{TLSDisabled: "", "": "&tls=true", TLSVerify: "&tls=true", TLSPreferred: "&tls=preferred", TLSSkipVerify: "&tls=skip-verify"} {
+		e := base
+		e.TLS = &EnvMySQLServerTLS{Mode: mode}
+		dsn := e.GetDSN()
+		assert.Equal(t, "root@tcp(db:3306)/node?parseTime=true"+param, dsn, mode)
+		_, err := e.GetConfig()
+		assert.NoError(t, err, mode)
+		parsed, err := ParseDSN(dsn)
+		assert.NoError(t, err)
+		if mode == TLSDisabled {
+			assert.Nil(t, parsed.TLS)
+		} else {
+			assert.Equal(t, e.TLS.mode(), parsed.TLS.Mode)
+		}
+	}
+
+	t.Run("certificates", func(t *testing.T) {
+		certFile, keyFile := writeCertificate(t, t.TempDir())
+		e := base
+		e.TLS = &EnvMySQLServerTLS{CA: certFile, Cert: certFile, Key: keyFile, ServerName: "mysql"}
+		assert.NoError(t, e.Validate())
+		cfg, err := e.GetConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, "mysql", cfg.TLS.ServerName)
+		assert.NotNil(t, cfg.TLS.RootCAs)
+		assert.Len(t, cfg.TLS.Certificates, 1)
+		assert.Regexp(t, `&tls=env-[0-9a-f]{16}&`, e.GetDSN())
+
+		parsed, err := ParseDSN(e.GetDSN())
+		assert.NoError(t, err)
+		assert.Equal(t, *e.TLS, *parsed.TLS)
+
+		e.TLS = &EnvMySQLServerTLS{CA: keyFile}
+		_, err = e.GetConfig()
+		assert.ErrorContains(t, err, "no certificate")
+		e.TLS = &EnvMySQLServerTLS{Cert: certFile}
+		assert.Error(t, e.Validate())
+	})
+
+	_, err := ParseDSN("root@tcp(db:3306)/node?tls=unknown")
+	assert.ErrorContains(t, err, "unknown TLS configuration")
+}
@@ -0,0 +1,139 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+var ErrUsage = errors.New("usage: migrate [-dry-run] [-to version] up|down|status")
+
+// Command runs the migrate command line of the arguments, writing its output to out, so that an application exposes
+// it from its own main, e.g. when started with `migrate` as first argument:
+//
+//	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+//		err = migrations.Command(ctx, m, os.Args[2:], os.Stdout)
+//	}
+//
+// The subcommands are up, applying the migrations up to -to, all of them by default; down, reverting the migrations
+// above -to, the last one by default; and status, listing the migrations. With -dry-run, up and down print the SQL
+// they would run.
+func Command(ctx context.Context, m *Migrator, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(out, ErrUsage.Error())
+		fs.PrintDefaults()
+	}
+	dryRun := fs.Bool("dry-run", false, "print the SQL to run instead of running it")
+	var to *uint64
+	fs.Func("to", "target `version`", func(s string) error {
+		version, err := strconv.ParseUint(s, 10, 64)
+		to = &version
+		return err
+	})
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	// The flags may follow the subcommand.
+	subcommand := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[min(1, fs.NArg()):]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return ErrUsage
+	}
+	m.DryRun = *dryRun
+
+	switch subcommand {
+	case "status":
+		return printStatus(ctx, m, out)
+	case "up":
+		target := uint64(Latest)
+		if to != nil {
+			target = *to
+		}
+		done, err := m.Up(ctx, target)
+		printMigrations(out, done, false, m.DryRun)
+		return err
+	case "down":
+		if to == nil {
+			previous, err := previousVersion(ctx, m)
+			if err != nil {
+				return err
+			}
+			to = &previous
+		}
+		done, err := m.Down(ctx, *to)
+		printMigrations(out, done, true, m.DryRun)
+		return err
+	}
+	return ErrUsage
+}
+
+// previousVersion returns the version applied before the last one, 0 if there is none.
+func previousVersion(ctx context.Context, m *Migrator) (uint64, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	applied := make([]uint64, 0, len(statuses))
+	for _, status := range statuses {
+		if status.Applied {
+			applied = append(applied, status.Version)
+		}
+	}
+	if len(applied) < 2 {
+		return 0, nil
+	}
+	return applied[len(applied)-2], nil
+}
+
+func printMigrations(out io.Writer, migrations []Migration, down bool, dryRun bool) {
+	verb, suffix := "applied", ".up.sql"
+	if down {
+		verb, suffix = "reverted", ".down.sql"
+	}
+	for _, migration := range migrations {
+		if !dryRun {
+			_, _ = fmt.Fprintln(out, verb, migration)
+			continue
+		}
+		script := migration.Up
+		if down {
+			script = migration.Down
+		}
+		_, _ = fmt.Fprintf(out, "-- %s%s\n", migration, suffix)
+		for _, statement := range SplitStatements(script) {
+			_, _ = fmt.Fprintf(out, "%s;\n", statement)
+		}
+	}
+	if len(migrations) == 0 {
+		_, _ = fmt.Fprintln(out, "nothing to migrate")
+	}
+}
+
+func printStatus(ctx context.Context, m *Migrator, out io.Writer) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, status := range statuses {
+		state, appliedAt := "pending", ""
+		if status.Applied {
+			state, appliedAt = "applied", status.AppliedAt.Format(time.DateTime)
+		}
+		if status.Missing {
+			state = "missing"
+		}
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+	return w.Flush()
+}
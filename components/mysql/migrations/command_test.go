@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand(t *testing.T) {
+	ctx := context.Background()
+	m, s := setupMigrator(t)
+	out := &bytes.Buffer{}
+	run := func(args ...string) error {
+		out.Reset()
+		return Command(ctx, m, args, out)
+	}
+
+	assert.NoError(t, run("up", "-dry-run", "-to", "1"))
+	assert.Equal(t, "-- 1_create_users.up.sql\nCREATE TABLE users (id INT);\n-- the admin\nINSERT INTO users VALUES (1);\n", out.String())
+	assert.Empty(t, s.records)
+
+	assert.NoError(t, run("up", "-to", "2"))
+	assert.Equal(t, "applied 1_create_users\napplied 2_add_name\n", out.String())
+
+	assert.NoError(t, run("status"))
+	assert.Regexp(t, `^VERSION +NAME +STATUS +APPLIED AT\n1 +create_users +applied +\d{4}-.*\n2 +add_name +applied +.*\n10 +create_roles +pending +\n$`, out.String())
+
+	// Down reverts the last migration by default.
+	assert.NoError(t, run("down"))
+	assert.Equal(t, "reverted 2_add_name\n", out.String())
+	assert.NoError(t, run("-dry-run", "down", "-to", "0"))
+	assert.Equal(t, "-- 1_create_users.down.sql\nDROP TABLE users;\n", out.String())
+	assert.Len(t, s.records, 1)
+
+	assert.NoError(t, run("up"))
+	assert.Equal(t, "applied 2_add_name\napplied 10_create_roles\n", out.String())
+	assert.NoError(t, run("up"))
+	assert.Equal(t, "nothing to migrate\n", out.String())
+	// The last migration has no down file.
+	assert.ErrorIs(t, run("down"), ErrIrreversible)
+
+	assert.ErrorIs(t, run(), ErrUsage)
+	assert.ErrorIs(t, run("sideways"), ErrUsage)
+	assert.ErrorIs(t, run("up", "extra"), ErrUsage)
+	assert.Error(t, run("up", "-to", "x"))
+}
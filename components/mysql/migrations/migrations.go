@@ -0,0 +1,429 @@
+// Package migrations applies versioned SQL migrations to a MySQL database.
+//
+// A migration is a pair of files `<version>_<name>.up.sql` and `<version>_<name>.down.sql`, the down file being
+// optional, usually embedded in the application:
+//
+//	//go:embed sql/*.sql
+//	var files embed.FS
+//
+//	sub, _ := fs.Sub(files, "sql")
+//	m, err := migrations.New(cluster.Primary(), sub)
+//	applied, err := m.Up(ctx, migrations.Latest)
+//
+// The applied versions are recorded with the checksums of their up files in a schema table, and the runs hold a
+// MySQL advisory lock so that the instances of an application starting together apply each migration once. MySQL
+// commits the DDL statements implicitly, so a failing migration mixing them with other statements may be left half
+// applied.
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/rhosocial/go-rush-common/components/mysql"
+)
+
+const (
+	// DefaultTable is the schema table of a Migrator.
+	DefaultTable = "schema_migrations"
+	// DefaultLockTimeout is the time in seconds a Migrator waits for the lock held by another run.
+	DefaultLockTimeout = 30
+	// Latest is the version Up migrates to in order to apply every migration.
+	Latest = math.MaxUint64
+)
+
+// The MySQL error number of a missing table.
+const errNumberNoSuchTable uint16 = 1146
+
+var (
+	ErrLocked       = errors.New("migrations locked by another run")
+	ErrChecksum     = errors.New("migration changed since applied")
+	ErrUnknown      = errors.New("applied migration not found in the files")
+	ErrIrreversible = errors.New("migration has no down file")
+	ErrInvalidTable = errors.New("invalid schema table name")
+	ErrNoDatabase   = errors.New("no database selected")
+)
+
+var (
+	filePattern  = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+	tablePattern = regexp.MustCompile(`^\w{1,64}$`)
+)
+
+// Migration is a versioned change of the schema.
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+	// Checksum is the SHA-256 of Up, recorded when applied to detect the files changed afterwards.
+	Checksum string
+}
+
+// String returns the version and the name of the migration, as in its file names.
+func (m Migration) String() string {
+	return strconv.FormatUint(m.Version, 10) + "_" + m.Name
+}
+
+// Status is a migration of the files or of the schema table, and whether it is applied.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	// Missing reports an applied migration absent from the files.
+	Missing bool
+}
+
+// record is a row of the schema table.
+type record struct {
+	Version   uint64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Migrator applies the migrations read from a file system to a database.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	// Table is the schema table recording the applied migrations.
+	Table string
+	// LockTimeout is the time in seconds waited for the lock held by another run.
+	LockTimeout uint16
+	// DryRun returns the migrations Up and Down would run without running them nor taking the lock.
+	DryRun bool
+}
+
+// New reads the migrations at the root of fsys, e.g. an embed.FS narrowed by fs.Sub. The .sql files not named after a
+// migration are refused, the other files ignored.
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Read(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations, Table: DefaultTable, LockTimeout: DefaultLockTimeout}, nil
+}
+
+// Read reads the migrations at the root of fsys, ordered by version.
+func Read(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[uint64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		match := filePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("%s: not named <version>_<name>.up.sql or <version>_<name>.down.sql", entry.Name())
+		}
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("%s: version %d already used by %s", entry.Name(), version, m)
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("%s: no up file", m)
+		}
+		sum := sha256.Sum256([]byte(m.Up))
+		m.Checksum = hex.EncodeToString(sum[:])
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrations returns the migrations read, ordered by version.
+func (m *Migrator) Migrations() []Migration {
+	return append([]Migration(nil), m.migrations...)
+}
+
+// Status returns the migrations of the files and the schema table, ordered by version.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	records, err := m.records(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Migration: migration}
+		if r, ok := records[migration.Version]; ok {
+			status.Applied, status.AppliedAt = true, r.AppliedAt
+			delete(records, migration.Version)
+		}
+		result = append(result, status)
+	}
+	for _, r := range records {
+		result = append(result, Status{
+			Migration: Migration{Version: r.Version, Name: r.Name, Checksum: r.Checksum},
+			Applied:   true,
+			AppliedAt: r.AppliedAt,
+			Missing:   true,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
+
+// Up applies in order the migrations not applied yet up to the version to, Latest for all of them, and returns those
+// applied, the ones before the failure on error. It fails before applying any if an applied migration changed or is
+// absent from the files.
+func (m *Migrator) Up(ctx context.Context, to uint64) ([]Migration, error) {
+	return m.run(ctx, false, func(records map[uint64]record) ([]Migration, error) {
+		for version, r := range records {
+			if _, ok := m.find(version); !ok {
+				return nil, fmt.Errorf("%w: %d_%s", ErrUnknown, version, r.Name)
+			}
+		}
+		pending := make([]Migration, 0)
+		for _, migration := range m.migrations {
+			r, ok := records[migration.Version]
+			switch {
+			case ok && r.Checksum != migration.Checksum:
+				return nil, fmt.Errorf("%w: %s", ErrChecksum, migration)
+			case !ok && migration.Version <= to:
+				pending = append(pending, migration)
+			}
+		}
+		return pending, nil
+	})
+}
+
+// Down reverts in reverse order the applied migrations above the version to, 0 for all of them, and returns those
+// reverted, the ones before the failure on error. It fails before reverting any if one of them has no down file.
+func (m *Migrator) Down(ctx context.Context, to uint64) ([]Migration, error) {
+	return m.run(ctx, true, func(records map[uint64]record) ([]Migration, error) {
+		pending := make([]Migration, 0)
+		for version, r := range records {
+			if version <= to {
+				continue
+			}
+			migration, ok := m.find(version)
+			switch {
+			case !ok:
+				return nil, fmt.Errorf("%w: %d_%s", ErrUnknown, version, r.Name)
+			case migration.Down == "":
+				return nil, fmt.Errorf("%w: %s", ErrIrreversible, migration)
+			}
+			pending = append(pending, migration)
+		}
+		sort.Slice(pending, func(i, j int) bool { return pending[i].Version > pending[j].Version })
+		return pending, nil
+	})
+}
+
+func (m *Migrator) find(version uint64) (Migration, bool) {
+	i := sort.Search(len(m.migrations), func(i int) bool { return m.migrations[i].Version >= version })
+	if i < len(m.migrations) && m.migrations[i].Version == version {
+		return m.migrations[i], true
+	}
+	return Migration{}, false
+}
+
+func (m *Migrator) validate() error {
+	if !tablePattern.MatchString(m.Table) {
+		return fmt.Errorf("%w: %q", ErrInvalidTable, m.Table)
+	}
+	return nil
+}
+
+// run plans the migrations from the applied ones and runs them, holding the lock on a dedicated connection since
+// MySQL ties the advisory locks to the connection taking them.
+func (m *Migrator) run(ctx context.Context, down bool, plan func(records map[uint64]record) ([]Migration, error)) ([]Migration, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	if m.DryRun {
+		records, err := m.records(ctx, m.db)
+		if err != nil {
+			return nil, err
+		}
+		return plan(records)
+	}
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := m.lock(ctx, conn); err != nil {
+		return nil, err
+	}
+	defer m.unlock(conn)
+	if _, err := conn.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS `"+m.Table+"` ("+
+		"version BIGINT UNSIGNED NOT NULL PRIMARY KEY, "+
+		"name VARCHAR(255) NOT NULL, "+
+		"checksum CHAR(64) NOT NULL, "+
+		"applied_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6))"); err != nil {
+		return nil, err
+	}
+	records, err := m.records(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := plan(records)
+	if err != nil {
+		return nil, err
+	}
+	done := make([]Migration, 0, len(pending))
+	for _, migration := range pending {
+		if err := m.apply(ctx, conn, migration, down); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// apply runs a migration and records it in a transaction of the locked connection, not retried since the statements
+// committed implicitly would be run again. A transaction of ctx, on another connection, is ignored.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration, down bool) error {
+	script, file := migration.Up, migration.String()+".up.sql"
+	if down {
+		script, file = migration.Down, migration.String()+".down.sql"
+	}
+	return mysql.WithTx(mysql.WithoutTx(ctx), conn, &mysql.TxOptions{}, func(ctx context.Context, tx *mysql.Tx) error {
+		for i, statement := range SplitStatements(script) {
+			if _, err := tx.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("%s: statement %d: %w", file, i+1, err)
+			}
+		}
+		var err error
+		if down {
+			_, err = tx.ExecContext(ctx, "DELETE FROM `"+m.Table+"` WHERE version = ?", migration.Version)
+		} else {
+			_, err = tx.ExecContext(ctx, "INSERT INTO `"+m.Table+"` (version, name, checksum) VALUES (?, ?, ?)",
+				migration.Version, migration.Name, migration.Checksum)
+		}
+		return err
+	})
+}
+
+// records returns the applied migrations, none if the schema table does not exist yet.
+func (m *Migrator) records(ctx context.Context, db mysql.Querier) (map[uint64]record, error) {
+	rows, err := db.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM `"+m.Table+"` ORDER BY version")
+	var mysqlErr *mysqldriver.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == errNumberNoSuchTable {
+		return map[uint64]record{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	records := make(map[uint64]record)
+	for rows.Next() {
+		var r record
+		if err := rows.Scan(&r.Version, &r.Name, &r.Checksum, &r.AppliedAt); err != nil {
+			return nil, err
+		}
+		records[r.Version] = r
+	}
+	return records, rows.Err()
+}
+
+// lockName is the name of the advisory lock of the schema table, named after the database so that the databases of a
+// server are migrated independently, and hashed to fit in the 64 characters allowed by MySQL.
+const lockName = "SHA2(CONCAT(DATABASE(), '.', ?), 256)"
+
+// lock takes the advisory lock of the schema table. No row is returned without a selected database.
+func (m *Migrator) lock(ctx context.Context, conn *sql.Conn) error {
+	var locked sql.NullInt64
+	err := conn.QueryRowContext(ctx, "SELECT GET_LOCK("+lockName+", ?) FROM DUAL WHERE DATABASE() IS NOT NULL",
+		m.Table, int(m.LockTimeout)).Scan(&locked)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrNoDatabase
+	case err != nil:
+		return err
+	case !locked.Valid:
+		return errors.New("migrations lock failed")
+	case locked.Int64 != 1:
+		return fmt.Errorf("%w, waited %ds", ErrLocked, m.LockTimeout)
+	}
+	return nil
+}
+
+// unlock releases the lock, also released by MySQL if the connection is lost.
+func (m *Migrator) unlock(conn *sql.Conn) {
+	_, _ = conn.ExecContext(context.Background(), "DO RELEASE_LOCK("+lockName+")", m.Table)
+}
+
+// SplitStatements splits a script into its statements, on the semicolons outside of the quotes and the comments. The
+// statements made of comments only are dropped.
+func SplitStatements(script string) []string {
+	statements := make([]string, 0)
+	start, code := 0, false
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			code = true
+			for i++; i < len(script) && script[i] != c; i++ {
+				if script[i] == '\\' && c != '`' {
+					i++
+				}
+			}
+		case c == '#' || strings.HasPrefix(script[i:], "--") && (i+2 == len(script) || unicode.IsSpace(rune(script[i+2]))):
+			if end := strings.IndexByte(script[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(script)
+			}
+		case strings.HasPrefix(script[i:], "/*"):
+			// The executable comments /*! ... */ are run by MySQL.
+			if strings.HasPrefix(script[i:], "/*!") {
+				code = true
+			}
+			if end := strings.Index(script[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(script)
+			}
+		case c == ';':
+			if code {
+				statements = append(statements, strings.TrimSpace(script[start:i]))
+			}
+			start, code = i+1, false
+		case !unicode.IsSpace(rune(c)):
+			code = true
+		}
+	}
+	if code {
+		statements = append(statements, strings.TrimSpace(script[start:]))
+	}
+	return statements
+}
@@ -0,0 +1,318 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/rhosocial/go-rush-common/components/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+// server is a database/sql driver emulating the lock and the schema table of a MySQL server, recording the other
+// statements and failing the one equal to fail.
+type server struct {
+	mu         sync.Mutex
+	noDatabase bool
+	locked     bool
+	created    bool
+	records    map[int64][]driver.Value
+	statements []string
+	fail       string
+}
+
+func newServer() *server {
+	return &server{records: make(map[int64][]driver.Value)}
+}
+
+func (s *server) Connect(context.Context) (driver.Conn, error) { return serverConn{s}, nil }
+func (s *server) Driver() driver.Driver                        { return nil }
+
+func (s *server) executed() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.statements...)
+}
+
+type serverConn struct{ s *server }
+
+func (c serverConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c serverConn) Close() error                        { return nil }
+func (c serverConn) Begin() (driver.Tx, error)           { return serverTx(c), nil }
+
+func (c serverConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return serverTx(c), nil
+}
+
+func (c serverConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	s := c.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case strings.HasPrefix(query, "DO RELEASE_LOCK("):
+		s.locked = false
+	case strings.HasPrefix(query, "CREATE TABLE IF NOT EXISTS `schema_migrations`"):
+		s.created = true
+	case strings.HasPrefix(query, "INSERT INTO `schema_migrations`"):
+		s.records[args[0].Value.(int64)] = []driver.Value{args[0].Value, args[1].Value, args[2].Value, time.Now()}
+	case strings.HasPrefix(query, "DELETE FROM `schema_migrations`"):
+		delete(s.records, args[0].Value.(int64))
+	default:
+		s.statements = append(s.statements, query)
+		if query == s.fail {
+			return nil, &mysqldriver.MySQLError{Number: 1064, Message: "You have an error in your SQL syntax"}
+		}
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c serverConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	s := c.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case strings.HasPrefix(query, "SELECT GET_LOCK(SHA2("):
+		if s.noDatabase {
+			return &rows{columns: []string{"lock"}}, nil
+		}
+		if s.locked {
+			return &rows{columns: []string{"lock"}, values: [][]driver.Value{{int64(0)}}}, nil
+		}
+		s.locked = true
+		return &rows{columns: []string{"lock"}, values: [][]driver.Value{{int64(1)}}}, nil
+	case strings.HasPrefix(query, "SELECT version, name, checksum, applied_at FROM `schema_migrations`"):
+		if !s.created {
+			return nil, &mysqldriver.MySQLError{Number: errNumberNoSuchTable, Message: "Table doesn't exist"}
+		}
+		r := &rows{columns: []string{"version", "name", "checksum", "applied_at"}}
+		for _, record := range s.records {
+			r.values = append(r.values, record)
+		}
+		return r, nil
+	}
+	return nil, errors.New("unexpected query " + query)
+}
+
+type serverTx struct{ s *server }
+
+func (t serverTx) Commit() error   { return nil }
+func (t serverTx) Rollback() error { return nil }
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *rows) Columns() []string { return r.columns }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+var files = fstest.MapFS{
+	"1_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INT);\n-- the admin\nINSERT INTO users VALUES (1);\n")},
+	"1_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+	"2_add_name.up.sql":       {Data: []byte("ALTER TABLE users ADD name VARCHAR(64);")},
+	"2_add_name.down.sql":     {Data: []byte("ALTER TABLE users DROP name;")},
+	"10_create_roles.up.sql":  {Data: []byte("CREATE TABLE roles (id INT);")},
+	"README.md":               {Data: []byte("# Migrations")},
+}
+
+func setupMigrator(t *testing.T) (*Migrator, *server) {
+	s := newServer()
+	db := sql.OpenDB(s)
+	t.Cleanup(func() { _ = db.Close() })
+	m, err := New(db, files)
+	assert.NoError(t, err)
+	return m, s
+}
+
+func versions(migrations []Migration) []uint64 {
+	result := make([]uint64, len(migrations))
+	for i, migration := range migrations {
+		result[i] = migration.Version
+	}
+	return result
+}
+
+func TestRead(t *testing.T) {
+	migrations, err := Read(files)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{1, 2, 10}, versions(migrations))
+	assert.Equal(t, "1_create_users", migrations[0].String())
+	assert.Equal(t, "DROP TABLE users;", migrations[0].Down)
+	assert.Empty(t, migrations[2].Down)
+	assert.Len(t, migrations[0].Checksum, 64)
+	assert.NotEqual(t, migrations[0].Checksum, migrations[1].Checksum)
+
+	for name, fsys := range map[string]fstest.MapFS{
+		"invalid name":     {"create_users.up.sql": {Data: []byte("SELECT 1")}},
+		"duplicate":        {"1_a.up.sql": {Data: []byte("SELECT 1")}, "1_b.up.sql": {Data: []byte("SELECT 1")}},
+		"no up file":       {"1_a.down.sql": {Data: []byte("SELECT 1")}},
+		"version overflow": {"99999999999999999999_a.up.sql": {Data: []byte("SELECT 1")}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Read(fsys)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	assert.Equal(t, []string{"CREATE TABLE t (id INT)", "-- the admin\nINSERT INTO t VALUES (1)"},
+		SplitStatements("CREATE TABLE t (id INT);\n-- the admin\nINSERT INTO t VALUES (1);\n-- done\n"))
+	assert.Equal(t, []string{`INSERT INTO t VALUES ('a;b', "c;d", 'e\';f', 'g'';h')`, "SELECT `i;j` FROM t"},
+		SplitStatements(`INSERT INTO t VALUES ('a;b', "c;d", 'e\';f', 'g'';h'); SELECT `+"`i;j`"+` FROM t`))
+	assert.Equal(t, []string{"/* a; b */ SELECT 1", "# c; d\nSELECT 2"},
+		SplitStatements("/* a; b */ SELECT 1;\n# c; d\nSELECT 2;\n/* trailing */"))
+	assert.Equal(t, []string{"/*!40101 SET NAMES utf8mb4 */", "SELECT 3--1"},
+		SplitStatements("/*!40101 SET NAMES utf8mb4 */;;SELECT 3--1"))
+	assert.Empty(t, SplitStatements(" \n-- nothing\n"))
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("up", func(t *testing.T) {
+		m, s := setupMigrator(t)
+		done, err := m.Up(ctx, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint64{1, 2}, versions(done))
+		assert.Equal(t, []string{
+			"CREATE TABLE users (id INT)",
+			"-- the admin\nINSERT INTO users VALUES (1)",
+			"ALTER TABLE users ADD name VARCHAR(64)",
+		}, s.executed())
+		assert.False(t, s.locked)
+
+		done, err = m.Up(ctx, Latest)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint64{10}, versions(done))
+		done, err = m.Up(ctx, Latest)
+		assert.NoError(t, err)
+		assert.Empty(t, done)
+
+		statuses, err := m.Status(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, statuses, 3)
+		for _, status := range statuses {
+			assert.True(t, status.Applied)
+			assert.False(t, status.AppliedAt.IsZero())
+		}
+	})
+
+	t.Run("down", func(t *testing.T) {
+		m, s := setupMigrator(t)
+		_, err := m.Up(ctx, 2)
+		assert.NoError(t, err)
+		done, err := m.Down(ctx, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint64{2, 1}, versions(done))
+		assert.Equal(t, []string{"ALTER TABLE users DROP name", "DROP TABLE users"}, s.executed()[3:])
+		assert.Empty(t, s.records)
+
+		_, err = m.Up(ctx, Latest)
+		assert.NoError(t, err)
+		_, err = m.Down(ctx, 0)
+		assert.ErrorIs(t, err, ErrIrreversible)
+		assert.Len(t, s.records, 3)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		m, s := setupMigrator(t)
+		m.DryRun = true
+		done, err := m.Up(ctx, Latest)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint64{1, 2, 10}, versions(done))
+		assert.Empty(t, s.executed())
+		assert.False(t, s.created)
+		assert.Empty(t, s.records)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		m, s := setupMigrator(t)
+		s.fail = "ALTER TABLE users ADD name VARCHAR(64)"
+		done, err := m.Up(ctx, Latest)
+		assert.ErrorContains(t, err, "2_add_name.up.sql: statement 1")
+		assert.Equal(t, []uint64{1}, versions(done))
+		assert.Len(t, s.records, 1)
+		assert.False(t, s.locked)
+	})
+
+	t.Run("locked", func(t *testing.T) {
+		m, s := setupMigrator(t)
+		s.locked = true
+		_, err := m.Up(ctx, Latest)
+		assert.ErrorIs(t, err, ErrLocked)
+		assert.Empty(t, s.executed())
+	})
+
+	t.Run("checksum", func(t *testing.T) {
+		m, s := setupMigrator(t)
+		_, err := m.Up(ctx, 1)
+		assert.NoError(t, err)
+		s.records[1][2] = "changed"
+		_, err = m.Up(ctx, Latest)
+		assert.ErrorIs(t, err, ErrChecksum)
+		assert.Len(t, s.records, 1)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		m, s := setupMigrator(t)
+		_, err := m.Up(ctx, Latest)
+		assert.NoError(t, err)
+		s.records[5] = []driver.Value{int64(5), "removed", "", time.Now()}
+		_, err = m.Up(ctx, Latest)
+		assert.ErrorIs(t, err, ErrUnknown)
+		statuses, err := m.Status(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint64{1, 2, 5, 10}, []uint64{statuses[0].Version, statuses[1].Version, statuses[2].Version, statuses[3].Version})
+		assert.True(t, statuses[2].Missing)
+		assert.Equal(t, "removed", statuses[2].Name)
+	})
+
+	t.Run("no database", func(t *testing.T) {
+		m, s := setupMigrator(t)
+		s.noDatabase = true
+		_, err := m.Up(ctx, Latest)
+		assert.ErrorIs(t, err, ErrNoDatabase)
+		assert.Empty(t, s.executed())
+	})
+
+	t.Run("ambient transaction", func(t *testing.T) {
+		m, s := setupMigrator(t)
+		other := newServer()
+		db := sql.OpenDB(other)
+		defer db.Close()
+		err := mysql.WithTx(ctx, db, nil, func(ctx context.Context, tx *mysql.Tx) error {
+			_, err := m.Up(ctx, 1)
+			return err
+		})
+		assert.NoError(t, err)
+		// The migration runs on the locked connection, not in a savepoint of the transaction of ctx.
+		assert.Len(t, s.executed(), 2)
+		assert.Len(t, s.records, 1)
+		assert.Empty(t, other.executed())
+	})
+
+	t.Run("invalid table", func(t *testing.T) {
+		m, _ := setupMigrator(t)
+		m.Table = "users`; DROP TABLE users"
+		_, err := m.Up(ctx, Latest)
+		assert.ErrorIs(t, err, ErrInvalidTable)
+	})
+}
@@ -1,17 +1,159 @@
 package mysql
 
-import "fmt"
+import (
+	"log/slog"
+	"net"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rhosocial/go-rush-common/components/config"
+	"github.com/rhosocial/go-rush-common/components/redact"
+)
+
+// EnvMySQLServerPool defines the database/sql connection pool of a server.
+type EnvMySQLServerPool struct {
+	MaxOpenConns uint16 `yaml:"MaxOpenConns,omitempty" default:"16" validate:"min=1"`
+	MaxIdleConns uint16 `yaml:"MaxIdleConns,omitempty" default:"4" validate:"ltefield=MaxOpenConns"`
+	// ConnMaxLifetime is the time in seconds a connection is reused for. Zero means forever.
+	ConnMaxLifetime uint32 `yaml:"ConnMaxLifetime,omitempty" default:"3600"`
+	// ConnMaxIdleTime is the time in seconds a connection stays idle before being closed. Zero means forever.
+	ConnMaxIdleTime uint32 `yaml:"ConnMaxIdleTime,omitempty" default:"600"`
+}
+
+func (e *EnvMySQLServerPool) Validate() error {
+	validate := validator.New()
+	return validate.Struct(e)
+}
+
+// EnvMySQLServerDialer defines the timeouts of the connections, in seconds.
+type EnvMySQLServerDialer struct {
+	Timeout uint8 `yaml:"Timeout,omitempty" default:"5" validate:"min=1,max=60"`
+	// ReadTimeout and WriteTimeout bound the I/O of a query. Zero means none.
+	ReadTimeout  uint16 `yaml:"ReadTimeout,omitempty" default:"30"`
+	WriteTimeout uint16 `yaml:"WriteTimeout,omitempty" default:"30"`
+}
+
+func (e *EnvMySQLServerDialer) Validate() error {
+	validate := validator.New()
+	return validate.Struct(e)
+}
+
+// EnvMySQLServerStartup defines how the server is waited for on start.
+type EnvMySQLServerStartup struct {
+	// Retries is the number of pings retried before giving up.
+	Retries uint8 `yaml:"Retries,omitempty" default:"3" validate:"max=20"`
+	// Interval is the time in milliseconds between two pings.
+	Interval uint16 `yaml:"Interval,omitempty" default:"1000" validate:"min=100,max=60000"`
+}
+
+func (e *EnvMySQLServerStartup) Validate() error {
+	validate := validator.New()
+	return validate.Struct(e)
+}
+
+// EnvMySQLServerHealth defines the health checks of a server in a Cluster.
+type EnvMySQLServerHealth struct {
+	// Interval is the time in milliseconds between two checks.
+	Interval uint16 `yaml:"Interval,omitempty" default:"5000" validate:"min=100,max=60000"`
+	// MaxLag is the replication lag in seconds beyond which a replica stops receiving reads. Zero means no limit.
+	MaxLag uint16 `yaml:"MaxLag,omitempty" default:"10"`
+}
+
+func (e *EnvMySQLServerHealth) Validate() error {
+	validate := validator.New()
+	return validate.Struct(e)
+}
+
+const (
+	RolePrimary = "primary"
+	RoleReplica = "replica"
+)
 
 type EnvMySQLServer struct {
 	Host     string `yaml:"Host" default:"localhost"`
 	Port     uint16 `yaml:"Port" default:"3306"`
 	Username string `yaml:"Username" default:"root"`
-	Password string `yaml:"Password" default:"123456"`
+	Password string `yaml:"Password" default:"" log:"secret"`
 	DB       string `yaml:"DB" default:"node"`
 	Charset  string `yaml:"Charset" default:"utf8mb4"`
 	Location string `yaml:"Location" default:"Local"`
+	// Socket is the path of the unix socket of the server, used instead of Host and Port if set.
+	Socket    string `yaml:"Socket,omitempty"`
+	Collation string `yaml:"Collation,omitempty"`
+	// InterpolateParams interpolates the query arguments client-side instead of preparing the statements, saving a
+	// round trip. It is refused with the collations not safe for interpolation.
+	InterpolateParams bool               `yaml:"InterpolateParams,omitempty"`
+	TLS               *EnvMySQLServerTLS `yaml:"TLS,omitempty"`
+	// Params are extra DSN parameters, e.g. system variables such as `sql_mode: "'STRICT_ALL_TABLES'"`.
+	Params map[string]string `yaml:"Params,omitempty"`
+	// Role is primary, receiving the writes and the transactions, or replica, receiving the reads. Empty means primary.
+	Role string `yaml:"Role,omitempty" default:"primary" validate:"omitempty,oneof=primary replica"`
+	// Weight is the share of the reads a replica receives. Zero means 1.
+	Weight  uint8                  `yaml:"Weight,omitempty" default:"1" validate:"max=10"`
+	Pool    *EnvMySQLServerPool    `yaml:"Pool,omitempty" default:"{}"`
+	Dialer  *EnvMySQLServerDialer  `yaml:"Dialer,omitempty" default:"{}"`
+	Startup *EnvMySQLServerStartup `yaml:"Startup,omitempty" default:"{}"`
+	Health  *EnvMySQLServerHealth  `yaml:"Health,omitempty" default:"{}"`
+}
+
+// IsReplica reports whether the server receives the reads.
+func (e *EnvMySQLServer) IsReplica() bool {
+	return e.Role == RoleReplica
+}
+
+// Addr returns the `host:port` address of the server, or the path of its socket.
+func (e *EnvMySQLServer) Addr() string {
+	if e.Socket != "" {
+		return e.Socket
+	}
+	return net.JoinHostPort(e.Host, strconv.Itoa(int(e.Port)))
+}
+
+// Validate checks the server, setting the absent sections to their defaults.
+func (e *EnvMySQLServer) Validate() error {
+	if e.Pool == nil {
+		e.Pool = &EnvMySQLServerPool{}
+		_ = config.ApplyDefaults(e.Pool)
+	}
+	if err := e.Pool.Validate(); err != nil {
+		return err
+	}
+	if e.Dialer == nil {
+		e.Dialer = &EnvMySQLServerDialer{}
+		_ = config.ApplyDefaults(e.Dialer)
+	}
+	if err := e.Dialer.Validate(); err != nil {
+		return err
+	}
+	if e.Startup == nil {
+		e.Startup = &EnvMySQLServerStartup{}
+		_ = config.ApplyDefaults(e.Startup)
+	}
+	if err := e.Startup.Validate(); err != nil {
+		return err
+	}
+	if e.Health == nil {
+		e.Health = &EnvMySQLServerHealth{}
+		_ = config.ApplyDefaults(e.Health)
+	}
+	if err := e.Health.Validate(); err != nil {
+		return err
+	}
+	if e.TLS != nil {
+		if err := e.TLS.Validate(); err != nil {
+			return err
+		}
+	}
+	validate := validator.New()
+	return validate.Struct(e)
+}
+
+// String hides the password.
+func (e EnvMySQLServer) String() string {
+	return redact.String(e)
 }
 
-func (e EnvMySQLServer) GetDSN() string {
-	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=true&loc=%s", e.Username, e.Password, e.Host, e.Port, e.DB, e.Charset, e.Location)
+// LogValue hides the password.
+func (e EnvMySQLServer) LogValue() slog.Value {
+	return redact.Value(e)
 }
@@ -0,0 +1,44 @@
+package mysql
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/rhosocial/go-rush-common/components/config"
+	"github.com/rhosocial/go-rush-common/components/redact"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvMySQLServer_GetDSN(t *testing.T) {
+	e := EnvMySQLServer{
+		Host: "localhost", Port: 3306, Username: "root", Password: "s3cr3t-passw0rd", DB: "node", Charset: "utf8mb4", Location: "Local",
+	}
+	assert.Equal(t, "root:s3cr3t-passw0rd@tcp(localhost:3306)/node?charset=utf8mb4&parseTime=true&loc=Local", e.GetDSN())
+	assert.Equal(t, "root:"+redact.Mask+"@tcp(localhost:3306)/node?charset=utf8mb4&parseTime=true&loc=Local", e.GetRedactedDSN())
+}
+
+func TestEnvMySQLServer_Defaults(t *testing.T) {
+	e := EnvMySQLServer{}
+	assert.NoError(t, config.ApplyDefaults(&e))
+	assert.Equal(t, "root", e.Username)
+	// No password is assumed, and CheckSecrets rejects the missing one in production.
+	assert.Empty(t, e.Password)
+	assert.ErrorContains(t, config.CheckSecrets(&e), "Password: is an empty secret")
+	assert.True(t, strings.HasPrefix(e.GetDSN(), "root@tcp(localhost:3306)/node?"))
+}
+
+func TestEnvMySQLServer_String(t *testing.T) {
+	e := EnvMySQLServer{Host: "localhost", Username: "root", Password: "123456"}
+	assert.NotContains(t, e.String(), "123456")
+	assert.NotContains(t, fmt.Sprintf("%v", e), "123456")
+	assert.NotContains(t, fmt.Sprintf("%+v", &e), "123456")
+	assert.Contains(t, e.String(), "Username:root")
+
+	buffer := bytes.Buffer{}
+	slog.New(slog.NewJSONHandler(&buffer, nil)).Info("connecting", "server", e)
+	assert.NotContains(t, buffer.String(), "123456")
+	assert.Contains(t, buffer.String(), `"Host":"localhost"`)
+}
@@ -0,0 +1,118 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+var ErrPoolClosed = errors.New("mysql pool closed")
+
+// ServerStatus is the health of a server, as reported by Pool.Status.
+type ServerStatus struct {
+	Valid   bool   `json:"valid"`
+	Message string `json:"message"`
+}
+
+// Pool is the database/sql connection pool of a MySQL server.
+type Pool struct {
+	db     *sql.DB
+	server EnvMySQLServer
+}
+
+// Open creates the pool of the server and pings it until it answers, at most Startup.Retries more times. The pool is
+// closed if the server never answers.
+func Open(ctx context.Context, server EnvMySQLServer) (*Pool, error) {
+	p, err := newPool(server)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.ping(ctx); err != nil {
+		_ = p.db.Close()
+		return nil, fmt.Errorf("mysql %s: %w", server.GetRedactedDSN(), err)
+	}
+	return p, nil
+}
+
+// newPool creates the pool of the server without connecting to it.
+func newPool(server EnvMySQLServer) (*Pool, error) {
+	if err := server.Validate(); err != nil {
+		return nil, err
+	}
+	cfg, err := server.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	connector, err := mysqldriver.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	db := sql.OpenDB(connector)
+	db.SetMaxOpenConns(int(server.Pool.MaxOpenConns))
+	db.SetMaxIdleConns(int(server.Pool.MaxIdleConns))
+	db.SetConnMaxLifetime(time.Duration(server.Pool.ConnMaxLifetime) * time.Second)
+	db.SetConnMaxIdleTime(time.Duration(server.Pool.ConnMaxIdleTime) * time.Second)
+	return &Pool{db: db, server: server}, nil
+}
+
+func (p *Pool) ping(ctx context.Context) error {
+	interval := time.Duration(p.server.Startup.Interval) * time.Millisecond
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = p.db.PingContext(ctx); err == nil {
+			return nil
+		}
+		if attempt >= int(p.server.Startup.Retries) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
+
+// DB returns the database/sql pool.
+func (p *Pool) DB() *sql.DB {
+	return p.db
+}
+
+// Server returns the configuration of the server.
+func (p *Pool) Server() EnvMySQLServer {
+	return p.server
+}
+
+// Stats returns the connection pool statistics.
+func (p *Pool) Stats() sql.DBStats {
+	return p.db.Stats()
+}
+
+// Status pings the server and reports its health along with the pool statistics, like
+// redis.ClientPool.GetRedisServerStatus.
+func (p *Pool) Status(ctx context.Context) *ServerStatus {
+	if p == nil || p.db == nil {
+		return &ServerStatus{Valid: false, Message: ErrPoolClosed.Error()}
+	}
+	if err := p.db.PingContext(ctx); err != nil {
+		return &ServerStatus{Valid: false, Message: err.Error()}
+	}
+	stats := p.db.Stats()
+	return &ServerStatus{
+		Valid: true,
+		Message: fmt.Sprintf("打开连接:%d, 使用中:%d, 空闲连接:%d, 等待次数:%d, 等待时长:%s, 超时关闭:%d.",
+			stats.OpenConnections, stats.InUse, stats.Idle, stats.WaitCount, stats.WaitDuration, stats.MaxLifetimeClosed+stats.MaxIdleTimeClosed),
+	}
+}
+
+// Close closes the pool once its connections are released. Closing a nil pool does nothing.
+func (p *Pool) Close() error {
+	if p == nil || p.db == nil {
+		return nil
+	}
+	return p.db.Close()
+}
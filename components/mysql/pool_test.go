@@ -0,0 +1,103 @@
+package mysql
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	// The driver logs the connections closed by refusingServer.
+	_ = mysqldriver.SetLogger(log.New(io.Discard, "", 0))
+}
+
+// refusingServer accepts connections and closes them at once, counting them.
+func refusingServer(t *testing.T) (*net.TCPAddr, *atomic.Int32) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	var connections atomic.Int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			connections.Add(1)
+			_ = conn.Close()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr), &connections
+}
+
+func TestEnvMySQLServer_Validate(t *testing.T) {
+	e := EnvMySQLServer{Host: "localhost", Port: 3306}
+	assert.NoError(t, e.Validate())
+	assert.Equal(t, uint16(16), e.Pool.MaxOpenConns)
+	assert.Equal(t, uint8(5), e.Dialer.Timeout)
+	assert.Equal(t, uint8(3), e.Startup.Retries)
+
+	e.Pool.MaxIdleConns = 32
+	assert.Error(t, e.Validate())
+	e.Pool = nil
+	e.Startup.Interval = 10
+	assert.Error(t, e.Validate())
+
+	cfg, err := (&EnvMySQLServer{Username: "root", Host: "db", Port: 3306, DB: "node", Charset: "utf8mb4", Location: "UTC",
+		Dialer: &EnvMySQLServerDialer{Timeout: 2, ReadTimeout: 10}}).GetConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "db:3306", cfg.Addr)
+	assert.Equal(t, 2*time.Second, cfg.Timeout)
+	assert.Equal(t, 10*time.Second, cfg.ReadTimeout)
+	assert.Equal(t, time.Duration(0), cfg.WriteTimeout)
+}
+
+func TestPool(t *testing.T) {
+	addr, connections := refusingServer(t)
+	server := EnvMySQLServer{
+		Host: "127.0.0.1", Port: uint16(addr.Port), Username: "root", Password: "secret-password", DB: "node", Charset: "utf8mb4", Location: "Local",
+		Pool:    &EnvMySQLServerPool{MaxOpenConns: 8, MaxIdleConns: 2, ConnMaxLifetime: 60},
+		Startup: &EnvMySQLServerStartup{Retries: 2, Interval: 100},
+	}
+
+	t.Run("settings", func(t *testing.T) {
+		p, err := newPool(server)
+		assert.NoError(t, err)
+		assert.Equal(t, 8, p.Stats().MaxOpenConnections)
+		status := p.Status(context.Background())
+		assert.False(t, status.Valid)
+		assert.NotEmpty(t, status.Message)
+		assert.NoError(t, p.Close())
+		assert.Equal(t, "sql: database is closed", p.Status(context.Background()).Message)
+	})
+
+	t.Run("startup retries", func(t *testing.T) {
+		connections.Store(0)
+		_, err := Open(context.Background(), server)
+		assert.ErrorContains(t, err, "127.0.0.1")
+		assert.NotContains(t, err.Error(), "secret-password")
+		assert.GreaterOrEqual(t, connections.Load(), int32(3))
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		s := server
+		s.Startup = &EnvMySQLServerStartup{Retries: 20, Interval: 60000}
+		start := time.Now()
+		_, err := Open(ctx, s)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	var empty *Pool
+	assert.NoError(t, empty.Close())
+	assert.False(t, empty.Status(context.Background()).Valid)
+}
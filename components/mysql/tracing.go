@@ -0,0 +1,82 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/rhosocial/go-rush-common/components/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSpan starts a client span around a statement sent to the server at addr, named after its operation like the
+// spans of tracing.RedisHook. The address is empty for the statements of a transaction.
+func startSpan(ctx context.Context, addr string, query string) (context.Context, trace.Span) {
+	operation := "QUERY"
+	if fields := strings.Fields(query); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+	attrs := []attribute.KeyValue{semconv.DBSystemNameMySQL, semconv.DBOperationName(operation), semconv.DBQueryText(query)}
+	if addr != "" {
+		attrs = append(attrs, semconv.ServerAddress(addr))
+	}
+	return tracing.StartClientSpan(ctx, operation, attrs...)
+}
+
+// QueryContext runs a query on the pool in a client span.
+func (p *Pool) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startSpan(ctx, p.server.Addr(), query)
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	tracing.End(span, err)
+	return rows, err
+}
+
+// QueryRowContext runs a query returning a row on the pool in a client span.
+func (p *Pool) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := startSpan(ctx, p.server.Addr(), query)
+	row := p.db.QueryRowContext(ctx, query, args...)
+	tracing.End(span, row.Err())
+	return row
+}
+
+// ExecContext runs a statement on the pool in a client span.
+func (p *Pool) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := startSpan(ctx, p.server.Addr(), query)
+	result, err := p.db.ExecContext(ctx, query, args...)
+	tracing.End(span, err)
+	return result, err
+}
+
+// BeginTx starts a transaction on the pool in a client span.
+func (p *Pool) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	ctx, span := startSpan(ctx, p.server.Addr(), "BEGIN")
+	tx, err := p.db.BeginTx(ctx, opts)
+	tracing.End(span, err)
+	return tx, err
+}
+
+// QueryContext runs a query in the transaction in a client span.
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startSpan(ctx, "", query)
+	rows, err := tx.Tx.QueryContext(ctx, query, args...)
+	tracing.End(span, err)
+	return rows, err
+}
+
+// QueryRowContext runs a query returning a row in the transaction in a client span.
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := startSpan(ctx, "", query)
+	row := tx.Tx.QueryRowContext(ctx, query, args...)
+	tracing.End(span, row.Err())
+	return row
+}
+
+// ExecContext runs a statement in the transaction in a client span.
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := startSpan(ctx, "", query)
+	result, err := tx.Tx.ExecContext(ctx, query, args...)
+	tracing.End(span, err)
+	return result, err
+}
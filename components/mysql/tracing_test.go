@@ -0,0 +1,130 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/rhosocial/go-rush-common/components/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func setupTracing(t *testing.T) *tracetest.InMemoryExporter {
+	provider, exporter := tracing.NewInMemoryTracerProvider()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+func spanNames(spans tracetest.SpanStubs) []string {
+	names := make([]string, len(spans))
+	for i, span := range spans {
+		names[i] = span.Name
+	}
+	return names
+}
+
+func hasAttribute(span tracetest.SpanStub, kv attribute.KeyValue) bool {
+	for _, attr := range span.Attributes {
+		if attr == kv {
+			return true
+		}
+	}
+	return false
+}
+
+func TestTracing(t *testing.T) {
+	exporter := setupTracing(t)
+
+	t.Run("cluster", func(t *testing.T) {
+		exporter.Reset()
+		addr, _ := refusingServer(t)
+		c, err := newCluster(testServers(uint16(addr.Port)))
+		assert.NoError(t, err)
+		defer func() {
+			assert.NoError(t, c.Close())
+		}()
+
+		ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+		_, err = c.QueryContext(ctx, "SELECT id FROM user WHERE id = ?", 1)
+		assert.Error(t, err)
+		_, err = c.ExecContext(ctx, "update user SET name = ?", "name")
+		assert.Error(t, err)
+		assert.Error(t, c.QueryRowContext(ctx, "SELECT 1").Err())
+		_, err = c.BeginTx(ctx, nil)
+		assert.Error(t, err)
+		parent.End()
+
+		spans := exporter.GetSpans()
+		assert.Equal(t, []string{"SELECT", "UPDATE", "SELECT", "BEGIN", "parent"}, spanNames(spans))
+		for _, span := range spans[:4] {
+			assert.Equal(t, trace.SpanKindClient, span.SpanKind)
+			assert.Equal(t, codes.Error, span.Status.Code)
+			assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+			assert.True(t, hasAttribute(span, attribute.String("db.system.name", "mysql")))
+			assert.True(t, hasAttribute(span, attribute.String("server.address", addr.String())))
+		}
+		assert.True(t, hasAttribute(spans[0], attribute.String("db.query.text", "SELECT id FROM user WHERE id = ?")))
+		assert.True(t, hasAttribute(spans[1], attribute.String("db.operation.name", "UPDATE")))
+	})
+
+	t.Run("transactions", func(t *testing.T) {
+		exporter.Reset()
+		r := &recorder{}
+		p := &Pool{db: sql.OpenDB(r), server: EnvMySQLServer{Host: "db", Port: 3306}}
+		defer func() {
+			assert.NoError(t, p.Close())
+		}()
+		ctx := context.Background()
+		failure := errors.New("failure")
+
+		err := p.WithTx(ctx, nil, func(ctx context.Context, tx *Tx) error {
+			_, _ = QuerierFrom(ctx, p).ExecContext(ctx, "INSERT INTO a")
+			return WithTx(ctx, p, nil, func(ctx context.Context, tx *Tx) error {
+				_, _ = QuerierFrom(ctx, p).ExecContext(ctx, "INSERT INTO b")
+				return failure
+			})
+		})
+		assert.ErrorIs(t, err, failure)
+		var value int
+		assert.ErrorIs(t, p.QueryRowContext(ctx, "SELECT 1").Scan(&value), sql.ErrNoRows)
+
+		spans := exporter.GetSpans()
+		assert.Equal(t, []string{
+			"BEGIN", "INSERT", "SAVEPOINT", "INSERT", "ROLLBACK", "mysql.savepoint", "mysql.transaction", "SELECT",
+		}, spanNames(spans))
+		transaction, savepoint := spans[6], spans[5]
+		assert.Equal(t, codes.Error, transaction.Status.Code)
+		assert.Equal(t, codes.Error, savepoint.Status.Code)
+		assert.Equal(t, transaction.SpanContext.SpanID(), spans[0].Parent.SpanID())
+		assert.Equal(t, transaction.SpanContext.SpanID(), spans[1].Parent.SpanID())
+		assert.Equal(t, transaction.SpanContext.SpanID(), savepoint.Parent.SpanID())
+		for _, span := range spans[2:5] {
+			assert.Equal(t, savepoint.SpanContext.SpanID(), span.Parent.SpanID())
+		}
+		assert.True(t, hasAttribute(spans[0], attribute.String("server.address", "db:3306")))
+		// A missing row is not a failure.
+		assert.Equal(t, codes.Unset, spans[7].Status.Code)
+
+		exporter.Reset()
+		assert.Panics(t, func() {
+			_ = p.WithTx(ctx, nil, func(ctx context.Context, tx *Tx) error {
+				panic("boom")
+			})
+		})
+		spans = exporter.GetSpans()
+		assert.Equal(t, []string{"BEGIN", "mysql.transaction"}, spanNames(spans))
+		assert.Equal(t, fmt.Sprintf("panic: %v", "boom"), spans[1].Status.Description)
+	})
+}
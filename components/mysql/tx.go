@@ -0,0 +1,183 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/rhosocial/go-rush-common/components/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+)
+
+const (
+	// DefaultTxRetries is the number of retries of a transaction without options.
+	DefaultTxRetries = 3
+	// DefaultTxBackoff is the wait before the first retry, doubled on each one.
+	DefaultTxBackoff = 50 * time.Millisecond
+)
+
+// The MySQL error numbers after which a transaction is retried.
+const (
+	ErrNumberLockWaitTimeout uint16 = 1205
+	ErrNumberDeadlock        uint16 = 1213
+)
+
+// Querier runs queries, on a pool or in a transaction.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Beginner starts transactions, e.g. *sql.DB or Cluster.
+type Beginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// TxOptions defines a transaction run by WithTx.
+type TxOptions struct {
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+	// Retries is the number of times the transaction is run again after a deadlock or a lock wait timeout.
+	Retries uint8
+	// Backoff is the wait before the first retry, doubled on each one. Zero means DefaultTxBackoff.
+	Backoff time.Duration
+}
+
+// Tx is a transaction run by WithTx, or a savepoint within one.
+type Tx struct {
+	*sql.Tx
+	depth int
+}
+
+type txKey struct{}
+
+// TxFromContext returns the transaction run by WithTx for the context, if any.
+func TxFromContext(ctx context.Context) (*Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(*Tx)
+	return tx, ok
+}
+
+// WithoutTx returns a context without the transaction of ctx, so that WithTx and QuerierFrom ignore it, e.g. for work
+// bound to another connection or meant to be committed whatever the outcome of the transaction.
+func WithoutTx(ctx context.Context) context.Context {
+	if _, ok := TxFromContext(ctx); !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, txKey{}, nil)
+}
+
+// QuerierFrom returns the transaction of the context if any, db otherwise, so that repository functions join the
+// transaction of their caller:
+//
+//	func (r *Users) Rename(ctx context.Context, id int64, name string) error {
+//		_, err := mysql.QuerierFrom(ctx, r.cluster).ExecContext(ctx, "UPDATE user SET name = ? WHERE id = ?", name, id)
+//		return err
+//	}
+func QuerierFrom(ctx context.Context, db Querier) Querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return db
+}
+
+// WithTx runs fn in a transaction of db, committed if fn returns nil, rolled back if it returns an error or panics.
+// The context given to fn carries the transaction, see QuerierFrom.
+//
+// Within the transaction of ctx, fn runs in a savepoint instead, rolled back to on error, and the retries are left to
+// the outermost transaction. Otherwise the transaction is run again, at most opts.Retries times, after a deadlock or a
+// lock wait timeout. Nil opts means DefaultTxRetries.
+func WithTx(ctx context.Context, db Beginner, opts *TxOptions, fn func(ctx context.Context, tx *Tx) error) error {
+	if parent, ok := TxFromContext(ctx); ok {
+		return withSavepoint(ctx, parent, fn)
+	}
+	if opts == nil {
+		opts = &TxOptions{Retries: DefaultTxRetries}
+	}
+	backoff := opts.Backoff
+	if backoff <= 0 {
+		backoff = DefaultTxBackoff
+	}
+	for attempt := 0; ; attempt++ {
+		err := runTx(ctx, db, opts, fn)
+		if err == nil || !IsRetryable(err) || attempt >= int(opts.Retries) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(backoff << attempt):
+		}
+	}
+}
+
+// runTx runs a transaction in a client span, the parent of the spans of its statements.
+func runTx(ctx context.Context, db Beginner, opts *TxOptions, fn func(ctx context.Context, tx *Tx) error) (err error) {
+	ctx, span := tracing.StartClientSpan(ctx, "mysql.transaction", semconv.DBSystemNameMySQL)
+	defer func() { tracing.End(span, err) }()
+	sqlTx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly})
+	if err != nil {
+		return err
+	}
+	tx := &Tx{Tx: sqlTx}
+	defer func() {
+		if v := recover(); v != nil {
+			_ = sqlTx.Rollback()
+			err = fmt.Errorf("panic: %v", v)
+			panic(v)
+		}
+	}()
+	if err := fn(context.WithValue(ctx, txKey{}, tx), tx); err != nil {
+		if rollbackErr := sqlTx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			return errors.Join(err, fmt.Errorf("rollback: %w", rollbackErr))
+		}
+		return err
+	}
+	return sqlTx.Commit()
+}
+
+// withSavepoint runs fn in a savepoint in a client span.
+func withSavepoint(ctx context.Context, parent *Tx, fn func(ctx context.Context, tx *Tx) error) (err error) {
+	tx := &Tx{Tx: parent.Tx, depth: parent.depth + 1}
+	savepoint := fmt.Sprintf("sp_%d", tx.depth)
+	ctx, span := tracing.StartClientSpan(ctx, "mysql.savepoint", semconv.DBSystemNameMySQL)
+	defer func() { tracing.End(span, err) }()
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+		return err
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			_, _ = tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint)
+			err = fmt.Errorf("panic: %v", v)
+			panic(v)
+		}
+	}()
+	if err = fn(context.WithValue(ctx, txKey{}, tx), tx); err != nil {
+		if _, rollbackErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint); rollbackErr != nil {
+			return errors.Join(err, fmt.Errorf("rollback to savepoint: %w", rollbackErr))
+		}
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "RELEASE SAVEPOINT "+savepoint)
+	return err
+}
+
+// IsRetryable reports whether err is a deadlock or a lock wait timeout, after which a transaction may succeed if run
+// again.
+func IsRetryable(err error) bool {
+	var mysqlErr *mysqldriver.MySQLError
+	return errors.As(err, &mysqlErr) && (mysqlErr.Number == ErrNumberDeadlock || mysqlErr.Number == ErrNumberLockWaitTimeout)
+}
+
+// WithTx runs fn in a transaction of the pool, see the function WithTx.
+func (p *Pool) WithTx(ctx context.Context, opts *TxOptions, fn func(ctx context.Context, tx *Tx) error) error {
+	return WithTx(ctx, p, opts, fn)
+}
+
+// WithTx runs fn in a transaction of the primary, see the function WithTx.
+func (c *Cluster) WithTx(ctx context.Context, opts *TxOptions, fn func(ctx context.Context, tx *Tx) error) error {
+	return WithTx(ctx, c, opts, fn)
+}
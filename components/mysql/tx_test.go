@@ -0,0 +1,234 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder is a database/sql driver recording the statements, failing those for which fail returns an error.
+type recorder struct {
+	mu         sync.Mutex
+	statements []string
+	fail       func(statement string) error
+}
+
+func (r *recorder) record(statement string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = append(r.statements, statement)
+	if r.fail != nil {
+		return r.fail(statement)
+	}
+	return nil
+}
+
+func (r *recorder) reset(fail func(statement string) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements, r.fail = nil, fail
+}
+
+func (r *recorder) Connect(context.Context) (driver.Conn, error) { return recorderConn{r}, nil }
+func (r *recorder) Driver() driver.Driver                        { return nil }
+
+type recorderConn struct{ r *recorder }
+
+func (c recorderConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c recorderConn) Close() error                        { return nil }
+func (c recorderConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c recorderConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	statement := "BEGIN"
+	if opts.ReadOnly {
+		statement = "BEGIN READ ONLY"
+	}
+	return recorderTx(c), c.r.record(statement)
+}
+
+func (c recorderConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(0), c.r.record(query)
+}
+
+// QueryContext returns no rows.
+func (c recorderConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return noRows{}, c.r.record(query)
+}
+
+type noRows struct{}
+
+func (noRows) Columns() []string         { return []string{"value"} }
+func (noRows) Close() error              { return nil }
+func (noRows) Next([]driver.Value) error { return io.EOF }
+
+type recorderTx struct{ r *recorder }
+
+func (t recorderTx) Commit() error   { return t.r.record("COMMIT") }
+func (t recorderTx) Rollback() error { return t.r.record("ROLLBACK") }
+
+func TestWithTx(t *testing.T) {
+	r := &recorder{}
+	db := sql.OpenDB(r)
+	defer db.Close()
+	ctx := context.Background()
+	insert := func(ctx context.Context, table string) error {
+		_, err := QuerierFrom(ctx, db).ExecContext(ctx, "INSERT INTO "+table)
+		return err
+	}
+
+	t.Run("commit", func(t *testing.T) {
+		r.reset(nil)
+		err := WithTx(ctx, db, &TxOptions{ReadOnly: true}, func(ctx context.Context, tx *Tx) error {
+			current, ok := TxFromContext(ctx)
+			assert.True(t, ok)
+			assert.Same(t, tx, current)
+			return insert(ctx, "a")
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"BEGIN READ ONLY", "INSERT INTO a", "COMMIT"}, r.statements)
+		_, ok := TxFromContext(ctx)
+		assert.False(t, ok)
+		assert.Equal(t, ctx, WithoutTx(ctx))
+	})
+
+	t.Run("without transaction", func(t *testing.T) {
+		r.reset(nil)
+		err := WithTx(ctx, db, nil, func(ctx context.Context, tx *Tx) error {
+			ctx = WithoutTx(ctx)
+			_, ok := TxFromContext(ctx)
+			assert.False(t, ok)
+			assert.Equal(t, db, QuerierFrom(ctx, db))
+			return nil
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("rollback on error", func(t *testing.T) {
+		r.reset(nil)
+		failure := errors.New("failure")
+		err := WithTx(ctx, db, nil, func(ctx context.Context, tx *Tx) error {
+			_ = insert(ctx, "a")
+			return failure
+		})
+		assert.ErrorIs(t, err, failure)
+		assert.Equal(t, []string{"BEGIN", "INSERT INTO a", "ROLLBACK"}, r.statements)
+	})
+
+	t.Run("rollback on panic", func(t *testing.T) {
+		r.reset(nil)
+		assert.PanicsWithValue(t, "boom", func() {
+			_ = WithTx(ctx, db, nil, func(ctx context.Context, tx *Tx) error {
+				panic("boom")
+			})
+		})
+		assert.Equal(t, []string{"BEGIN", "ROLLBACK"}, r.statements)
+	})
+
+	t.Run("savepoints", func(t *testing.T) {
+		r.reset(nil)
+		failure := errors.New("failure")
+		err := WithTx(ctx, db, nil, func(ctx context.Context, tx *Tx) error {
+			_ = insert(ctx, "a")
+			assert.ErrorIs(t, WithTx(ctx, db, nil, func(ctx context.Context, tx *Tx) error {
+				_ = insert(ctx, "b")
+				return failure
+			}), failure)
+			return WithTx(ctx, db, nil, func(ctx context.Context, tx *Tx) error {
+				return WithTx(ctx, db, nil, func(ctx context.Context, tx *Tx) error {
+					return insert(ctx, "c")
+				})
+			})
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"BEGIN", "INSERT INTO a",
+			"SAVEPOINT sp_1", "INSERT INTO b", "ROLLBACK TO SAVEPOINT sp_1",
+			"SAVEPOINT sp_1", "SAVEPOINT sp_2", "INSERT INTO c", "RELEASE SAVEPOINT sp_2", "RELEASE SAVEPOINT sp_1",
+			"COMMIT",
+		}, r.statements)
+	})
+
+	t.Run("savepoint panic", func(t *testing.T) {
+		r.reset(nil)
+		assert.Panics(t, func() {
+			_ = WithTx(ctx, db, nil, func(ctx context.Context, tx *Tx) error {
+				return WithTx(ctx, db, nil, func(ctx context.Context, tx *Tx) error {
+					panic("boom")
+				})
+			})
+		})
+		assert.Equal(t, []string{"BEGIN", "SAVEPOINT sp_1", "ROLLBACK TO SAVEPOINT sp_1", "ROLLBACK"}, r.statements)
+	})
+
+	t.Run("retries", func(t *testing.T) {
+		deadlocks := 2
+		r.reset(func(statement string) error {
+			if statement == "INSERT INTO a" && deadlocks > 0 {
+				deadlocks--
+				return &mysqldriver.MySQLError{Number: ErrNumberDeadlock, Message: "Deadlock found"}
+			}
+			return nil
+		})
+		runs := 0
+		err := WithTx(ctx, db, &TxOptions{Retries: 2, Backoff: time.Millisecond}, func(ctx context.Context, tx *Tx) error {
+			runs++
+			return insert(ctx, "a")
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, runs)
+		assert.Equal(t, []string{
+			"BEGIN", "INSERT INTO a", "ROLLBACK",
+			"BEGIN", "INSERT INTO a", "ROLLBACK",
+			"BEGIN", "INSERT INTO a", "COMMIT",
+		}, r.statements)
+
+		r.reset(func(statement string) error {
+			if statement == "INSERT INTO a" {
+				return &mysqldriver.MySQLError{Number: ErrNumberLockWaitTimeout, Message: "Lock wait timeout exceeded"}
+			}
+			return nil
+		})
+		runs = 0
+		err = WithTx(ctx, db, &TxOptions{Retries: 1, Backoff: time.Millisecond}, func(ctx context.Context, tx *Tx) error {
+			runs++
+			return insert(ctx, "a")
+		})
+		assert.True(t, IsRetryable(err))
+		assert.Equal(t, 2, runs)
+
+		r.reset(func(statement string) error {
+			if statement == "INSERT INTO a" {
+				return &mysqldriver.MySQLError{Number: 1062, Message: "Duplicate entry"}
+			}
+			return nil
+		})
+		runs = 0
+		err = WithTx(ctx, db, nil, func(ctx context.Context, tx *Tx) error {
+			runs++
+			return insert(ctx, "a")
+		})
+		assert.False(t, IsRetryable(err))
+		assert.Equal(t, 1, runs)
+	})
+
+	t.Run("canceled retries", func(t *testing.T) {
+		r.reset(nil)
+		ctx, cancel := context.WithCancel(ctx)
+		err := WithTx(ctx, db, &TxOptions{Retries: 5, Backoff: time.Hour}, func(ctx context.Context, tx *Tx) error {
+			cancel()
+			return &mysqldriver.MySQLError{Number: ErrNumberDeadlock, Message: "Deadlock found"}
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.True(t, IsRetryable(err))
+	})
+}
@@ -0,0 +1,46 @@
+package openapi_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rhosocial/go-rush-common/components/controller"
+	"github.com/rhosocial/go-rush-common/components/openapi"
+	"github.com/rhosocial/go-rush-common/components/openapi/openapitest"
+	"github.com/stretchr/testify/assert"
+)
+
+type Item struct {
+	ID   uint64 `json:"id"`
+	Name string `json:"name" binding:"required,max=64"`
+}
+
+type getItemRequest struct {
+	ID uint64 `uri:"id" binding:"required"`
+}
+
+type itemController struct{}
+
+func (itemController) Definition() controller.Definition {
+	return controller.Definition{
+		BasePath: "/items",
+		Actions: []controller.Action{
+			controller.NewAction(http.MethodGet, "/:id", "getItem", func(ctx context.Context, req getItemRequest) (Item, error) {
+				return Item{ID: req.ID}, nil
+			}),
+			controller.NewAction(http.MethodPost, "", "createItem", func(ctx context.Context, req Item) (Item, error) {
+				return req, nil
+			}),
+		},
+	}
+}
+
+func TestDocument_Golden(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := controller.NewRegistry(itemController{})
+	assert.NoError(t, registry.Register(gin.New()))
+	doc := openapi.New(registry, openapi.Config{Info: openapi.Info{Title: "Items", Version: "1.0.0"}})
+	openapitest.AssertGolden(t, doc, "testdata/openapi.json")
+}
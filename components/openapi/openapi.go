@@ -0,0 +1,309 @@
+// Package openapi generates an OpenAPI 3.1 document from the routes registered by a controller.Registry, and serves it
+// along with an offline Swagger UI.
+//
+// The parameters and the request body of an operation come from the fields of its request type: `uri` fields are path
+// parameters, `form` fields query parameters, `header` fields header parameters, and the other JSON fields make up the
+// body. The `binding` rules become schema constraints, e.g. `required`, `min`, `max`, `oneof` or `email`. The response
+// is the response.Generic envelope around the response type; errors are response.Base envelopes.
+//
+// Only actions created with controller.NewAction (or with their Request and Response set) are fully documented.
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/rhosocial/go-rush-common/components/controller"
+	"github.com/rhosocial/go-rush-common/components/response"
+)
+
+// Version is the OpenAPI version of the generated documents.
+const Version = "3.1.0"
+
+// MediaTypeJSON is the media type of the request and response bodies.
+const MediaTypeJSON = "application/json"
+
+// Document is an OpenAPI document.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Servers    []Server             `json:"servers,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps the lower-case methods to the operations of a path.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// Config defines the top-level fields of the generated document.
+type Config struct {
+	Info    Info
+	Servers []Server
+}
+
+// New generates the document of the routes registered by the registry.
+func New(registry *controller.Registry, config Config) *Document {
+	return Generate(registry.Routes(), config)
+}
+
+// Generate generates the document of the routes.
+func Generate(routes []controller.Route, config Config) *Document {
+	g := generator{schemas: newSchemas()}
+	g.base = &Schema{Ref: RefPrefix + g.schemas.component(reflect.TypeFor[response.Base](), "", nil)}
+	doc := Document{
+		OpenAPI: Version,
+		Info:    config.Info,
+		Servers: config.Servers,
+		Paths:   make(map[string]*PathItem),
+	}
+	// The actions of versioned controllers are registered once per version, their operation IDs must be told apart.
+	actions := make(map[string]int)
+	for _, route := range routes {
+		actions[route.Action]++
+	}
+	for _, route := range routes {
+		if route.Action != "" && route.Version != "" && actions[route.Action] > 1 {
+			route.Action += "." + route.Version
+		}
+		p := Path(route.Path)
+		item, ok := doc.Paths[p]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[p] = item
+		}
+		(*item)[strings.ToLower(route.Method)] = g.operation(route)
+	}
+	doc.Components.Schemas = g.schemas.components
+	return &doc
+}
+
+// Path converts a gin route path into an OpenAPI path, e.g. `/users/:id` into `/users/{id}`.
+func Path(route string) string {
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+type generator struct {
+	schemas *schemas
+	base    *Schema
+}
+
+func (g *generator) operation(route controller.Route) *Operation {
+	op := Operation{
+		OperationID: route.Action,
+		Summary:     route.Summary,
+		Description: route.Description,
+		Deprecated:  route.Deprecated,
+		Responses:   make(map[string]*Response),
+	}
+	if route.Controller != "" {
+		op.Tags = []string{route.Controller}
+	}
+	documented := g.request(&op, route)
+	if route.Response != nil {
+		envelope := Schema{
+			Type:       "object",
+			Properties: map[string]*Schema{"data": g.schemas.of(route.Response)},
+		}
+		op.Responses["200"] = g.response(http.StatusOK, &Schema{AllOf: []*Schema{g.base, &envelope}})
+	} else {
+		op.Responses["200"] = &Response{Description: http.StatusText(http.StatusOK)}
+	}
+	if documented {
+		op.Responses["400"] = g.response(http.StatusBadRequest, g.base)
+	}
+	if route.Response != nil || route.Request != nil {
+		op.Responses["500"] = g.response(http.StatusInternalServerError, g.base)
+	}
+	return &op
+}
+
+func (g *generator) response(status int, schema *Schema) *Response {
+	return &Response{
+		Description: http.StatusText(status),
+		Content:     map[string]*MediaType{MediaTypeJSON: {Schema: schema}},
+	}
+}
+
+// request documents the parameters and the body of the operation, and reports whether there is any.
+func (g *generator) request(op *Operation, route controller.Route) bool {
+	// Path parameters are documented even without a request type, as OpenAPI requires.
+	declared := make(map[string]bool)
+	t := route.Request
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t != nil && t.Kind() == reflect.Struct {
+		body := false
+		for _, f := range requestFields(t) {
+			rules := f.Tag.Get("binding")
+			in := ""
+			name := ""
+			for _, source := range []struct{ tag, in string }{{"uri", "path"}, {"form", "query"}, {"header", "header"}} {
+				if tag, ok := f.Tag.Lookup(source.tag); ok {
+					name, _, _ = strings.Cut(tag, ",")
+					in = source.in
+					break
+				}
+			}
+			if in == "" {
+				body = true
+				continue
+			}
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			schema := g.schemas.of(f.Type)
+			applyRules(schema, f.Type, rules)
+			if value, ok := defaultValue(f.Tag.Get("form")); ok && in == "query" {
+				schema.Default = typedValue(schema.Type, value)
+			}
+			op.Parameters = append(op.Parameters, &Parameter{
+				Name:     name,
+				In:       in,
+				Required: in == "path" || hasRule(rules, "required"),
+				Schema:   schema,
+			})
+			if in == "path" {
+				declared[name] = true
+			}
+		}
+		if body && route.Method != http.MethodGet && route.Method != http.MethodHead {
+			op.RequestBody = g.requestBody(t)
+		}
+	} else if t != nil && route.Method != http.MethodGet && route.Method != http.MethodHead {
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]*MediaType{MediaTypeJSON: {Schema: g.schemas.of(t)}}}
+	}
+	for _, segment := range strings.Split(route.Path, "/") {
+		if (strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*")) && !declared[segment[1:]] {
+			op.Parameters = append(op.Parameters, &Parameter{Name: segment[1:], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	sort.SliceStable(op.Parameters, func(i, j int) bool {
+		return parameterOrder[op.Parameters[i].In] < parameterOrder[op.Parameters[j].In]
+	})
+	return len(op.Parameters) > 0 || op.RequestBody != nil
+}
+
+var parameterOrder = map[string]int{"path": 0, "query": 1, "header": 2}
+
+// requestBody documents the JSON fields of the request type t. The type itself is referred to if all its fields are
+// body fields, otherwise a `<Type>Body` component holds them.
+func (g *generator) requestBody(t reflect.Type) *RequestBody {
+	required := false
+	onlyBody := true
+	for _, f := range requestFields(t) {
+		if isBodyField(f.StructField) {
+			required = required || hasRule(f.Tag.Get("binding"), "required")
+		} else {
+			onlyBody = false
+		}
+	}
+	var schema *Schema
+	if onlyBody {
+		schema = g.schemas.of(t)
+	} else {
+		schema = &Schema{Ref: RefPrefix + g.schemas.component(t, "Body", isBodyField)}
+	}
+	return &RequestBody{Required: required, Content: map[string]*MediaType{MediaTypeJSON: {Schema: schema}}}
+}
+
+// requestFields returns the fields of the request type t, including the promoted ones and those hidden from JSON.
+func requestFields(t reflect.Type) []field {
+	result := make([]field, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && ft.Kind() == reflect.Struct && f.Tag.Get("json") == "" {
+			result = append(result, requestFields(ft)...)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if !isBodyField(f) || f.Tag.Get("json") != "-" {
+			result = append(result, field{StructField: f})
+		}
+	}
+	return result
+}
+
+func isBodyField(f reflect.StructField) bool {
+	for _, tag := range []string{"uri", "form", "header"} {
+		if _, ok := f.Tag.Lookup(tag); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// defaultValue returns the value of the `default=` option of a form tag.
+func defaultValue(tag string) (string, bool) {
+	_, options, _ := strings.Cut(tag, ",")
+	for _, option := range strings.Split(options, ",") {
+		if value, ok := strings.CutPrefix(option, "default="); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,192 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rhosocial/go-rush-common/components/controller"
+	"github.com/stretchr/testify/assert"
+)
+
+type Pagination struct {
+	Page uint32 `form:"page,default=1" binding:"min=1"`
+	Size uint32 `form:"size,default=20" binding:"min=1,max=100"`
+}
+
+type listUsersRequest struct {
+	Pagination
+	Status string `form:"status" binding:"omitempty,oneof=active disabled"`
+}
+
+type createUserRequest struct {
+	Name  string   `json:"name" binding:"required,min=2,max=32"`
+	Email string   `json:"email" binding:"required,email"`
+	Age   *uint8   `json:"age,omitempty" binding:"omitempty,gte=18,lt=150"`
+	Tags  []string `json:"tags,omitempty" binding:"max=5,dive,max=16"`
+}
+
+type updateUserRequest struct {
+	ID      uint64 `uri:"id" binding:"required"`
+	Version string `header:"If-Match" binding:"required"`
+	Name    string `json:"name" binding:"required"`
+}
+
+type User struct {
+	ID        uint64            `json:"id"`
+	Name      string            `json:"name"`
+	Email     string            `json:"email"`
+	CreatedAt time.Time         `json:"created_at"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Manager   *User             `json:"manager,omitempty"`
+	internal  string
+}
+
+type userController struct{}
+
+func (userController) Definition() controller.Definition {
+	return controller.Definition{
+		Name:     "users",
+		BasePath: "/users",
+		Version:  "v1",
+		Actions: []controller.Action{
+			controller.NewAction(http.MethodGet, "", "listUsers", func(ctx context.Context, req listUsersRequest) ([]User, error) {
+				return nil, nil
+			}),
+			controller.NewAction(http.MethodPost, "", "createUser", func(ctx context.Context, req createUserRequest) (*User, error) {
+				return nil, nil
+			}),
+			controller.NewAction(http.MethodPut, "/:id", "updateUser", func(ctx context.Context, req updateUserRequest) (*User, error) {
+				return nil, nil
+			}),
+			{Method: http.MethodDelete, Path: "/:id", Name: "deleteUser", Summary: "Delete a user", Handler: func(c *gin.Context) {}},
+		},
+	}
+}
+
+func newDocument(t *testing.T) *Document {
+	gin.SetMode(gin.TestMode)
+	registry := controller.NewRegistry(userController{})
+	assert.NoError(t, registry.Register(gin.New()))
+	return New(registry, Config{Info: Info{Title: "Users", Version: "1.0.0"}})
+}
+
+func TestPath(t *testing.T) {
+	assert.Equal(t, "/users/{id}/files/{path}", Path("/users/:id/files/*path"))
+	assert.Equal(t, "/", Path("/"))
+}
+
+func TestNew(t *testing.T) {
+	doc := newDocument(t)
+	assert.Equal(t, Version, doc.OpenAPI)
+	assert.Len(t, doc.Paths, 2)
+
+	list := (*doc.Paths["/v1/users"])["get"]
+	assert.Equal(t, "listUsers", list.OperationID)
+	assert.Equal(t, []string{"users"}, list.Tags)
+	assert.Nil(t, list.RequestBody)
+	assert.Len(t, list.Parameters, 3)
+	assert.Equal(t, "page", list.Parameters[0].Name)
+	assert.Equal(t, int64(1), list.Parameters[0].Schema.Default)
+	assert.Equal(t, 100.0, *list.Parameters[1].Schema.Maximum)
+	assert.Equal(t, []any{"active", "disabled"}, list.Parameters[2].Schema.Enum)
+	data := list.Responses["200"].Content[MediaTypeJSON].Schema.AllOf[1].Properties["data"]
+	assert.Equal(t, "array", data.Type)
+	assert.Equal(t, RefPrefix+"User", data.Items.Ref)
+
+	create := (*doc.Paths["/v1/users"])["post"]
+	assert.True(t, create.RequestBody.Required)
+	assert.Equal(t, RefPrefix+"CreateUserRequest", create.RequestBody.Content[MediaTypeJSON].Schema.Ref)
+	body := doc.Components.Schemas["CreateUserRequest"]
+	assert.Equal(t, []string{"name", "email"}, body.Required)
+	assert.Equal(t, uint64(2), *body.Properties["name"].MinLength)
+	assert.Equal(t, "email", body.Properties["email"].Format)
+	assert.Equal(t, 18.0, *body.Properties["age"].Minimum)
+	assert.Equal(t, 150.0, *body.Properties["age"].ExclusiveMaximum)
+	assert.Equal(t, uint64(5), *body.Properties["tags"].MaxItems)
+	assert.Equal(t, uint64(16), *body.Properties["tags"].Items.MaxLength)
+
+	update := (*doc.Paths["/v1/users/{id}"])["put"]
+	assert.Equal(t, "path", update.Parameters[0].In)
+	assert.True(t, update.Parameters[0].Required)
+	assert.Equal(t, "If-Match", update.Parameters[1].Name)
+	assert.Equal(t, RefPrefix+"UpdateUserRequestBody", update.RequestBody.Content[MediaTypeJSON].Schema.Ref)
+	assert.Equal(t, []string{"name"}, doc.Components.Schemas["UpdateUserRequestBody"].Required)
+	assert.Contains(t, update.Responses, "400")
+
+	remove := (*doc.Paths["/v1/users/{id}"])["delete"]
+	assert.Equal(t, "Delete a user", remove.Summary)
+	assert.Equal(t, "id", remove.Parameters[0].Name)
+	assert.NotContains(t, remove.Responses, "500")
+
+	user := doc.Components.Schemas["User"]
+	assert.Equal(t, "date-time", user.Properties["created_at"].Format)
+	assert.Equal(t, "string", user.Properties["labels"].AdditionalProperties.Type)
+	assert.Equal(t, RefPrefix+"User", user.Properties["manager"].Ref)
+	assert.NotContains(t, user.Properties, "internal")
+	assert.Contains(t, doc.Components.Schemas, "Base")
+
+	_, err := json.Marshal(doc)
+	assert.NoError(t, err)
+}
+
+func TestGenerate_Versions(t *testing.T) {
+	doc := Generate([]controller.Route{
+		{Method: http.MethodGet, Path: "/v1/users", Controller: "users", Action: "listUsers", Version: "v1", Deprecated: true},
+		{Method: http.MethodGet, Path: "/v2/users", Controller: "users", Action: "listUsers", Version: "v2"},
+		{Method: http.MethodGet, Path: "/v2/groups", Controller: "groups", Action: "listGroups", Version: "v2"},
+	}, Config{})
+	v1 := (*doc.Paths["/v1/users"])["get"]
+	assert.Equal(t, "listUsers.v1", v1.OperationID)
+	assert.True(t, v1.Deprecated)
+	v2 := (*doc.Paths["/v2/users"])["get"]
+	assert.Equal(t, "listUsers.v2", v2.OperationID)
+	assert.False(t, v2.Deprecated)
+	assert.Equal(t, "listGroups", (*doc.Paths["/v2/groups"])["get"].OperationID)
+}
+
+func TestTypeName(t *testing.T) {
+	type Page[T any] struct{ Items []T }
+	assert.Equal(t, "User", typeName(reflect.TypeFor[User]()))
+	assert.Equal(t, "PageUser", typeName(reflect.TypeFor[Page[User]]()))
+	assert.Equal(t, "PageUserList", typeName(reflect.TypeFor[Page[[]User]]()))
+	assert.Equal(t, "PageAny", typeName(reflect.TypeFor[Page[any]]()))
+}
+
+func TestRegister(t *testing.T) {
+	doc := newDocument(t)
+	r := gin.New()
+	assert.NoError(t, Register(r.Group("/api"), doc, ServeConfig{}))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("/api/openapi.json")
+	assert.Equal(t, http.StatusOK, w.Code)
+	served := Document{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &served))
+	assert.Equal(t, doc.Info, served.Info)
+
+	assert.Equal(t, http.StatusMovedPermanently, serve("/api/docs").Code)
+	w = serve("/api/docs/")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "swagger-ui")
+	w = serve("/api/docs/swagger-initializer.js")
+	assert.Contains(t, w.Body.String(), `"/api/openapi.json"`)
+	w = serve("/api/docs/swagger-ui-bundle.js")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusNotFound, serve("/api/docs/missing.js").Code)
+
+	r = gin.New()
+	assert.NoError(t, Register(r, doc, ServeConfig{Path: "/spec.json", DisableUI: true}))
+	assert.Len(t, r.Routes(), 1)
+}
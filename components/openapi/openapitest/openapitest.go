@@ -0,0 +1,43 @@
+// Package openapitest provides a test helper failing when a generated OpenAPI document changes unexpectedly.
+package openapitest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rhosocial/go-rush-common/components/openapi"
+	"github.com/stretchr/testify/assert"
+)
+
+// EnvUpdate is the environment variable which, set to 1, makes AssertGolden rewrite the golden files instead of
+// comparing them.
+const EnvUpdate = "UPDATE_OPENAPI"
+
+// AssertGolden fails the test if the document differs from the golden file, conventionally
+// `testdata/openapi.json`. Review and commit the golden file after running the tests with UPDATE_OPENAPI=1.
+func AssertGolden(t testing.TB, doc *openapi.Document, filename string) bool {
+	t.Helper()
+	actual, err := json.MarshalIndent(doc, "", "  ")
+	if !assert.NoError(t, err) {
+		return false
+	}
+	actual = append(actual, '\n')
+	if os.Getenv(EnvUpdate) == "1" {
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); !assert.NoError(t, err) {
+			return false
+		}
+		return assert.NoError(t, os.WriteFile(filename, actual, 0o644))
+	}
+	expected, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		t.Errorf("golden file %s does not exist; run the tests with %s=1 to create it", filename, EnvUpdate)
+		return false
+	}
+	if !assert.NoError(t, err) {
+		return false
+	}
+	return assert.Equal(t, string(expected), string(actual),
+		"the OpenAPI document changed; run the tests with %s=1 to update %s if this is expected", EnvUpdate, filename)
+}
@@ -0,0 +1,41 @@
+package openapitest
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/rhosocial/go-rush-common/components/openapi"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder records the failures instead of failing the test.
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestAssertGolden(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "testdata", "openapi.json")
+	doc := &openapi.Document{OpenAPI: openapi.Version, Info: openapi.Info{Title: "Test", Version: "1"}}
+
+	r := &recorder{TB: t}
+	assert.False(t, AssertGolden(r, doc, filename), "a missing golden file should fail")
+	assert.Len(t, r.failures, 1)
+
+	t.Setenv(EnvUpdate, "1")
+	assert.True(t, AssertGolden(t, doc, filename))
+	t.Setenv(EnvUpdate, "")
+
+	r = &recorder{TB: t}
+	assert.True(t, AssertGolden(r, doc, filename))
+	assert.Empty(t, r.failures)
+
+	doc.Info.Version = "2"
+	assert.False(t, AssertGolden(r, doc, filename), "a changed document should fail")
+	assert.NotEmpty(t, r.failures)
+}
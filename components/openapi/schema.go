@@ -0,0 +1,386 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Schema is an OpenAPI 3.1 (JSON Schema 2020-12) schema. Only the keywords generated from Go types are present.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+	MinLength            *uint64            `json:"minLength,omitempty"`
+	MaxLength            *uint64            `json:"maxLength,omitempty"`
+	MinItems             *uint64            `json:"minItems,omitempty"`
+	MaxItems             *uint64            `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+}
+
+// RefPrefix prefixes the references to component schemas.
+const RefPrefix = "#/components/schemas/"
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	durationType      = reflect.TypeFor[time.Duration]()
+	jsonMarshaler     = reflect.TypeFor[json.Marshaler]()
+	textMarshaler     = reflect.TypeFor[encoding.TextMarshaler]()
+	rawMessageType    = reflect.TypeFor[json.RawMessage]()
+	emptyInterfaceTyp = reflect.TypeFor[any]()
+)
+
+// schemas generates the schemas of Go types, the named structs becoming components.
+type schemas struct {
+	components map[string]*Schema
+	names      map[componentKey]string
+}
+
+type componentKey struct {
+	t      reflect.Type
+	suffix string
+}
+
+func newSchemas() *schemas {
+	return &schemas{components: make(map[string]*Schema), names: make(map[componentKey]string)}
+}
+
+// of returns the schema of t, a reference for named structs.
+func (s *schemas) of(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "nanoseconds"}
+	case t == rawMessageType || t == emptyInterfaceTyp:
+		return &Schema{}
+	case implements(t, jsonMarshaler):
+		// The encoding is unknown.
+		return &Schema{}
+	case implements(t, textMarshaler):
+		return &Schema{Type: "string"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32", Minimum: float(0)}
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer", Format: "int64", Minimum: float(0)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.of(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.of(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t, nil)
+		}
+		return &Schema{Ref: RefPrefix + s.component(t, "", nil)}
+	}
+	return &Schema{}
+}
+
+// component registers the schema of the struct t under its name with the suffix, keeping the fields accepted by
+// filter (all if nil), and returns the name. The filter must be the same for every call with the same suffix.
+func (s *schemas) component(t reflect.Type, suffix string, filter func(reflect.StructField) bool) string {
+	key := componentKey{t: t, suffix: suffix}
+	if name, ok := s.names[key]; ok {
+		return name
+	}
+	name := s.uniqueName(t, suffix)
+	s.names[key] = name
+	// Register before generating the fields, so that recursive types refer to themselves.
+	s.components[name] = &Schema{}
+	*s.components[name] = *s.object(t, filter)
+	return name
+}
+
+func (s *schemas) uniqueName(t reflect.Type, suffix string) string {
+	name := typeName(t) + suffix
+	if _, taken := s.components[name]; !taken {
+		return name
+	}
+	if pkg := t.PkgPath(); pkg != "" {
+		name = exported(pkg[strings.LastIndex(pkg, "/")+1:]) + name
+	}
+	candidate := name
+	for i := 2; ; i++ {
+		if _, taken := s.components[candidate]; !taken {
+			return candidate
+		}
+		candidate = name + strconv.Itoa(i)
+	}
+}
+
+// object returns the inline object schema of the struct t, with the fields accepted by filter.
+func (s *schemas) object(t reflect.Type, filter func(reflect.StructField) bool) *Schema {
+	o := Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, field := range fields(t) {
+		if filter != nil && !filter(field.StructField) {
+			continue
+		}
+		fs := s.of(field.Type)
+		applyRules(fs, field.Type, field.Tag.Get("binding"))
+		o.Properties[field.name] = fs
+		if field.required() {
+			o.Required = append(o.Required, field.name)
+		}
+	}
+	return &o
+}
+
+// field is a struct field as encoding/json sees it.
+type field struct {
+	reflect.StructField
+	name string
+}
+
+func (f field) required() bool {
+	return hasRule(f.Tag.Get("binding"), "required")
+}
+
+// fields returns the JSON fields of the struct t, with the fields of embedded structs promoted.
+func fields(t reflect.Type) []field {
+	result := make([]field, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			result = append(result, fields(ft)...)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		result = append(result, field{StructField: f, name: name})
+	}
+	return result
+}
+
+func implements(t reflect.Type, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PointerTo(t).Implements(iface)
+}
+
+// typeName returns the name of t, e.g. `GenericUserAny` for `response.Generic[pkg.User, interface {}]`.
+func typeName(t reflect.Type) string {
+	name := t.Name()
+	base, args, generic := strings.Cut(name, "[")
+	if !generic {
+		return exported(name)
+	}
+	b := strings.Builder{}
+	b.WriteString(exported(base))
+	for _, arg := range strings.Split(strings.TrimSuffix(args, "]"), ",") {
+		arg = strings.TrimSpace(arg)
+		switch {
+		case arg == "interface {}":
+			arg = "Any"
+		case strings.HasPrefix(arg, "[]"):
+			arg = strings.TrimPrefix(arg, "[]") + "List"
+		}
+		arg = arg[strings.LastIndex(arg, ".")+1:]
+		b.WriteString(exported(arg))
+	}
+	return b.String()
+}
+
+// exported capitalizes s and drops the characters not allowed in component names.
+func exported(s string) string {
+	b := strings.Builder{}
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func hasRule(rules string, name string) bool {
+	for _, rule := range strings.Split(rules, ",") {
+		if rule == name {
+			return true
+		}
+	}
+	return false
+}
+
+// applyRules translates the validator rules of a field into schema constraints. The rules following `dive` apply to
+// the items of slices and the values of maps.
+func applyRules(schema *Schema, t reflect.Type, rules string) {
+	if rules == "" {
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	own, itemRules, dive := strings.Cut(rules, ",dive")
+	if dive {
+		itemRules = strings.TrimPrefix(itemRules, ",")
+		switch {
+		case schema.Items != nil:
+			applyRules(schema.Items, t.Elem(), itemRules)
+		case schema.AdditionalProperties != nil:
+			applyRules(schema.AdditionalProperties, t.Elem(), itemRules)
+		}
+	}
+	if schema.Ref != "" {
+		return
+	}
+	for _, rule := range strings.Split(own, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "min", "gte":
+			setMin(schema, param, false)
+		case "max", "lte":
+			setMax(schema, param, false)
+		case "gt":
+			setMin(schema, param, true)
+		case "lt":
+			setMax(schema, param, true)
+		case "len":
+			setMin(schema, param, false)
+			setMax(schema, param, false)
+		case "oneof":
+			schema.Enum = nil
+			for _, value := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, typedValue(schema.Type, value))
+			}
+		case "email":
+			schema.Format = "email"
+		case "url", "uri", "http_url":
+			schema.Format = "uri"
+		case "uuid", "uuid4":
+			schema.Format = "uuid"
+		case "ip", "ipv4", "ipv6", "hostname":
+			schema.Format = name
+		case "datetime":
+			schema.Description = "layout " + param
+		}
+	}
+}
+
+func setMin(schema *Schema, param string, exclusive bool) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	switch schema.Type {
+	case "integer", "number":
+		if exclusive {
+			schema.ExclusiveMinimum = &n
+		} else {
+			schema.Minimum = &n
+		}
+	case "string":
+		length := uint64(n)
+		if exclusive {
+			length++
+		}
+		schema.MinLength = &length
+	case "array":
+		length := uint64(n)
+		if exclusive {
+			length++
+		}
+		schema.MinItems = &length
+	}
+}
+
+func setMax(schema *Schema, param string, exclusive bool) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	switch schema.Type {
+	case "integer", "number":
+		if exclusive {
+			schema.ExclusiveMaximum = &n
+		} else {
+			schema.Maximum = &n
+		}
+	case "string":
+		length := uint64(n)
+		if exclusive && length > 0 {
+			length--
+		}
+		schema.MaxLength = &length
+	case "array":
+		length := uint64(n)
+		if exclusive && length > 0 {
+			length--
+		}
+		schema.MaxItems = &length
+	}
+}
+
+// typedValue converts a value written in a tag to the type of the schema.
+func typedValue(schemaType string, value string) any {
+	switch schemaType {
+	case "integer":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
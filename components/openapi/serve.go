@@ -0,0 +1,91 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+const (
+	DefaultPath   = "/openapi.json"
+	DefaultUIPath = "/docs"
+)
+
+// ServeConfig defines the routes of Register.
+type ServeConfig struct {
+	// Path is the route serving the document. Empty means DefaultPath.
+	Path string
+	// UIPath is the route prefix serving the Swagger UI. Empty means DefaultUIPath.
+	UIPath string
+	// DisableUI only serves the document.
+	DisableUI bool
+}
+
+// swaggerInitializer replaces the initializer of the Swagger UI distribution, which loads a demo document.
+const swaggerInitializer = `window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: %q,
+    dom_id: '#swagger-ui',
+    deepLinking: true,
+    presets: [
+      SwaggerUIBundle.presets.apis,
+      SwaggerUIStandalonePreset
+    ],
+    plugins: [
+      SwaggerUIBundle.plugins.DownloadUrl
+    ],
+    layout: "StandaloneLayout"
+  });
+};
+`
+
+// Register serves the document, and the Swagger UI unless disabled, on r. The UI assets are embedded in the binary,
+// so that no CDN is needed.
+func Register(r gin.IRouter, doc *Document, config ServeConfig) error {
+	if config.Path == "" {
+		config.Path = DefaultPath
+	}
+	if config.UIPath == "" {
+		config.UIPath = DefaultUIPath
+	}
+	content, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	r.GET(config.Path, func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", content)
+	})
+	if config.DisableUI {
+		return nil
+	}
+	base := "/"
+	if g, ok := r.(interface{ BasePath() string }); ok {
+		base = g.BasePath()
+	}
+	index, err := fs.ReadFile(swaggerFiles.FS, "index.html")
+	if err != nil {
+		return err
+	}
+	initializer := []byte(fmt.Sprintf(swaggerInitializer, path.Join(base, config.Path)))
+	uiPath := strings.TrimSuffix(config.UIPath, "/")
+	r.GET(uiPath, func(c *gin.Context) {
+		c.Redirect(http.StatusMovedPermanently, path.Join(base, uiPath)+"/")
+	})
+	r.GET(uiPath+"/*filepath", func(c *gin.Context) {
+		switch name := strings.TrimPrefix(c.Param("filepath"), "/"); name {
+		case "", "index.html":
+			c.Data(http.StatusOK, "text/html; charset=utf-8", index)
+		case "swagger-initializer.js":
+			c.Data(http.StatusOK, "text/javascript; charset=utf-8", initializer)
+		default:
+			c.FileFromFS(name, http.FS(swaggerFiles.FS))
+		}
+	})
+	return nil
+}
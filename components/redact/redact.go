@@ -0,0 +1,328 @@
+// Package redact masks sensitive data — credentials in headers, query parameters, DSNs and struct fields — before it
+// reaches logs or error output.
+//
+// Struct fields are marked sensitive with the `log:"secret"` tag:
+//
+//	type EnvMySQLServer struct {
+//		Password string `yaml:"Password" log:"secret"`
+//	}
+package redact
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Mask replaces sensitive values.
+const Mask = "******"
+
+// TagName is the struct tag marking sensitive fields, with the value TagSecret. The option TagAllowEmpty, as in
+// `log:"secret,allowempty"`, marks a secret that may be left empty, e.g. the password of a server without
+// authentication.
+const (
+	TagName       = "log"
+	TagSecret     = "secret"
+	TagAllowEmpty = "allowempty"
+)
+
+// IsSecret reports whether the field is tagged `log:"secret"`, with or without options.
+func IsSecret(f reflect.StructField) bool {
+	name, _, _ := strings.Cut(f.Tag.Get(TagName), ",")
+	return name == TagSecret
+}
+
+// AllowsEmpty reports whether the field is a secret with the option TagAllowEmpty.
+func AllowsEmpty(f reflect.StructField) bool {
+	name, options, _ := strings.Cut(f.Tag.Get(TagName), ",")
+	return name == TagSecret && slices.Contains(strings.Split(options, ","), TagAllowEmpty)
+}
+
+// DefaultHeaders lists the headers masked by the Default redactor.
+var DefaultHeaders = []string{
+	"X-Authorization-Token",
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+}
+
+// DefaultQueryParams lists the query parameters (and log attribute keys) masked by the Default redactor.
+var DefaultQueryParams = []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"access_token",
+	"refresh_token",
+	"api_key",
+	"apikey",
+}
+
+// Redactor masks the configured headers and query parameters, and the fields tagged `log:"secret"`.
+// Names are matched case-insensitively.
+type Redactor struct {
+	headers map[string]struct{}
+	params  map[string]struct{}
+}
+
+// New creates a Redactor for the headers and query parameters.
+func New(headers []string, params []string) *Redactor {
+	r := Redactor{headers: make(map[string]struct{}), params: make(map[string]struct{})}
+	for _, h := range headers {
+		r.headers[strings.ToLower(h)] = struct{}{}
+	}
+	for _, p := range params {
+		r.params[strings.ToLower(p)] = struct{}{}
+	}
+	return &r
+}
+
+// Default masks DefaultHeaders and DefaultQueryParams.
+var Default = New(DefaultHeaders, DefaultQueryParams)
+
+// EnvRedact defines the headers and query parameters to mask, in addition to the defaults.
+type EnvRedact struct {
+	Headers     []string `yaml:"Headers,omitempty"`
+	QueryParams []string `yaml:"QueryParams,omitempty"`
+}
+
+// NewFromEnv creates a Redactor masking the defaults and the configured names. A nil configuration returns Default.
+func NewFromEnv(e *EnvRedact) *Redactor {
+	if e == nil {
+		return Default
+	}
+	return New(append(append([]string(nil), DefaultHeaders...), e.Headers...),
+		append(append([]string(nil), DefaultQueryParams...), e.QueryParams...))
+}
+
+// IsSensitiveHeader reports whether the header is masked.
+func (r *Redactor) IsSensitiveHeader(name string) bool {
+	_, ok := r.headers[strings.ToLower(name)]
+	return ok
+}
+
+// IsSensitiveKey reports whether a query parameter or log attribute with this name is masked.
+func (r *Redactor) IsSensitiveKey(name string) bool {
+	if _, ok := r.params[strings.ToLower(name)]; ok {
+		return true
+	}
+	_, ok := r.headers[strings.ToLower(name)]
+	return ok
+}
+
+// Header returns a copy of h in which the values of sensitive headers are masked.
+func (r *Redactor) Header(h http.Header) http.Header {
+	result := make(http.Header, len(h))
+	for name, values := range h {
+		if r.IsSensitiveHeader(name) {
+			masked := make([]string, len(values))
+			for i := range masked {
+				masked[i] = Mask
+			}
+			result[name] = masked
+			continue
+		}
+		result[name] = append([]string(nil), values...)
+	}
+	return result
+}
+
+// URL masks the values of sensitive query parameters in a path or URL, e.g. `/login?token=abc` becomes
+// `/login?token=******`. The user info password, if any, is masked too.
+func (r *Redactor) URL(s string) string {
+	base, query, hasQuery := strings.Cut(s, "?")
+	if scheme := strings.Index(base, "://"); scheme >= 0 {
+		start := scheme + len("://")
+		end := len(base)
+		if slash := strings.IndexByte(base[start:], '/'); slash >= 0 {
+			end = start + slash
+		}
+		if at := strings.LastIndex(base[start:end], "@"); at >= 0 {
+			if colon := strings.IndexByte(base[start:start+at], ':'); colon >= 0 {
+				base = base[:start+colon+1] + Mask + base[start+at:]
+			}
+		}
+	}
+	if !hasQuery {
+		return base
+	}
+	fragment := ""
+	if i := strings.IndexByte(query, '#'); i >= 0 {
+		query, fragment = query[:i], query[i:]
+	}
+	pairs := strings.Split(query, "&")
+	for i, pair := range pairs {
+		key, _, hasValue := strings.Cut(pair, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		if hasValue && r.IsSensitiveKey(name) {
+			pairs[i] = key + "=" + Mask
+		}
+	}
+	return base + "?" + strings.Join(pairs, "&") + fragment
+}
+
+// DSN masks the password of a `user:password@protocol(address)/db` data source name.
+func DSN(dsn string) string {
+	at := strings.LastIndex(dsn, "@")
+	if at < 0 {
+		return dsn
+	}
+	colon := strings.IndexByte(dsn[:at], ':')
+	if colon < 0 {
+		return dsn
+	}
+	return dsn[:colon+1] + Mask + dsn[at:]
+}
+
+// Attr masks the attribute if its key is sensitive, or if its value is a struct with fields tagged `log:"secret"`.
+// It can be used as slog.HandlerOptions.ReplaceAttr.
+func (r *Redactor) Attr(groups []string, a slog.Attr) slog.Attr {
+	if r.IsSensitiveKey(a.Key) {
+		return slog.String(a.Key, Mask)
+	}
+	if a.Value.Kind() == slog.KindAny {
+		if v := a.Value.Any(); HasSecrets(v) {
+			return slog.Attr{Key: a.Key, Value: Value(v)}
+		}
+	}
+	return a
+}
+
+var secretTypes sync.Map // map[reflect.Type]bool
+
+// HasSecrets reports whether v is a struct, or a pointer to one, with fields tagged `log:"secret"`, directly or in
+// nested structs.
+func HasSecrets(v any) bool {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return false
+	}
+	result, _ := typeHasSecrets(t, make(map[reflect.Type]bool))
+	return result
+}
+
+// typeHasSecrets walks the struct types of the fields of t, skipping the types being walked, and caches the results
+// once complete: a type of a cycle may have secrets through the types still being walked, unless it is the root.
+func typeHasSecrets(t reflect.Type, walking map[reflect.Type]bool) (result bool, complete bool) {
+	if cached, ok := secretTypes.Load(t); ok {
+		return cached.(bool), true
+	}
+	if walking[t] {
+		return false, false
+	}
+	walking[t] = true
+	defer delete(walking, t)
+	complete = true
+	for i := 0; i < t.NumField() && !result; i++ {
+		field := t.Field(i)
+		if IsSecret(field) {
+			result = true
+			break
+		}
+		ft := field.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct {
+			found, explored := typeHasSecrets(ft, walking)
+			result, complete = found, complete && explored
+		}
+	}
+	if result || complete || len(walking) == 1 {
+		secretTypes.Store(t, result)
+		return result, true
+	}
+	return false, false
+}
+
+// Value returns a slog group value of the exported fields of the struct v, with the fields tagged `log:"secret"`
+// masked. Non-empty secrets are replaced by Mask, so that the presence of a value can still be told.
+func Value(v any) slog.Value {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return slog.AnyValue(nil)
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return slog.AnyValue(v)
+	}
+	t := rv.Type()
+	attrs := make([]slog.Attr, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := rv.Field(i)
+		switch {
+		case IsSecret(field):
+			attrs = append(attrs, slog.String(field.Name, maskValue(fv)))
+		case HasSecrets(fv.Interface()):
+			attrs = append(attrs, slog.Attr{Key: field.Name, Value: Value(fv.Interface())})
+		default:
+			attrs = append(attrs, slog.Any(field.Name, fv.Interface()))
+		}
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// String formats the struct v like fmt's %+v verb, with the fields tagged `log:"secret"` masked.
+// It is meant to implement fmt.Stringer on configuration structs.
+func String(v any) string {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return "<nil>"
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Sprintf("%+v", v)
+	}
+	t := rv.Type()
+	b := strings.Builder{}
+	b.WriteByte('{')
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if b.Len() > 1 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(field.Name)
+		b.WriteByte(':')
+		fv := rv.Field(i)
+		switch {
+		case IsSecret(field):
+			b.WriteString(maskValue(fv))
+		case HasSecrets(fv.Interface()):
+			b.WriteString(String(fv.Interface()))
+		default:
+			fmt.Fprintf(&b, "%+v", fv.Interface())
+		}
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func maskValue(v reflect.Value) string {
+	if v.IsZero() {
+		return ""
+	}
+	return Mask
+}
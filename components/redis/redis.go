@@ -4,19 +4,60 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net"
+	"reflect"
+	"slices"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/redis/go-redis/v9"
+	"github.com/rhosocial/go-rush-common/components/config"
+	"github.com/rhosocial/go-rush-common/components/redact"
 )
 
+// DefaultRetireDelay is the time the clients removed by ClientPool.Reconfigure stay open.
+const DefaultRetireDelay = 30 * time.Second
+
 type ClientPool struct {
-	clients *[]*redis.Client
+	// RetireDelay is the time the clients removed by Reconfigure stay open before being closed, for the requests
+	// already using them. Zero means DefaultRetireDelay.
+	RetireDelay time.Duration
+	// set is replaced as a whole, so that readers never see the clients of one configuration with the turns of another.
+	set     atomic.Pointer[clientSet]
+	mu      sync.Mutex
+	hooks   []redis.Hook
+	retired []*retiredClient
+}
+
+// retiredClient is a client removed by Reconfigure, closed by its timer unless a later configuration reuses it.
+type retiredClient struct {
+	candidate
+	timer *time.Timer
+}
+
+// candidate is a client which a new configuration may reuse.
+type candidate struct {
+	server EnvRedisServer
+	client *redis.Client
+}
+
+// clientSet is the clients of a configuration.
+type clientSet struct {
+	clients []*redis.Client
+	servers []EnvRedisServer
 	turnMap []uint8
 }
 
+func (c *ClientPool) load() *clientSet {
+	if c == nil {
+		return nil
+	}
+	return c.set.Load()
+}
+
 var ServerTurn atomic.Uint32
 
 var ErrRedisClientNil = errors.New("redis client nil")
@@ -25,32 +66,38 @@ var ErrRedisClientsNotAvailable = errors.New("redis client(s) not available")
 // GetCurrentTurn 获得当前活动 redis 客户端顺序。如果没有活动客户端，则报 ErrRedisClientNil 错误。
 // 注意！如果某个 Redis 服务器的权重大于1，则意味着该服务器将被询问多次。
 func (c *ClientPool) GetCurrentTurn() *uint8 {
-	if c == nil || c.clients == nil || len(*c.clients) == 0 {
+	set := c.load()
+	if set == nil || len(set.clients) == 0 {
 		panic(ErrRedisClientNil)
 	}
-	now := ServerTurn.Load() % uint32(len(c.turnMap))
-	next := ServerTurn.Add(1) % uint32(len(c.turnMap))
-	status := *c.GetRedisServerStatus(context.Background(), c.turnMap[next])
+	return set.currentTurn()
+}
+
+func (s *clientSet) currentTurn() *uint8 {
+	now := ServerTurn.Load() % uint32(len(s.turnMap))
+	next := ServerTurn.Add(1) % uint32(len(s.turnMap))
+	status := *s.status(context.Background(), s.turnMap[next])
 	for {
 		if now == next && !status.Valid {
 			panic(ErrRedisClientsNotAvailable)
 		}
 		if now != next && !status.Valid {
-			next = ServerTurn.Add(1) % uint32(len(c.turnMap))
-			status = *c.GetRedisServerStatus(context.Background(), c.turnMap[next])
+			next = ServerTurn.Add(1) % uint32(len(s.turnMap))
+			status = *s.status(context.Background(), s.turnMap[next])
 			continue
 		}
 		break
 	}
-	return &c.turnMap[next]
+	return &s.turnMap[next]
 }
 
 // GetCurrentClient 获得当前活动 redis 客户端指针。如果没有活动客户端，则报 ErrRedisClientNil 错误。
 func (c *ClientPool) GetCurrentClient() *redis.Client {
-	if c.GetCurrentTurn() == nil {
-		return nil
+	set := c.load()
+	if set == nil || len(set.clients) == 0 {
+		panic(ErrRedisClientNil)
 	}
-	return (*c.clients)[*c.GetCurrentTurn()]
+	return set.clients[*set.currentTurn()]
 }
 
 type EnvRedisServerDialer struct {
@@ -63,8 +110,10 @@ func (e *EnvRedisServerDialer) Validate() error {
 	return validate.Struct(e)
 }
 
+// GetDialerDefault returns the dialer configuration made of the `default` tags.
 func (e *EnvRedisServer) GetDialerDefault() *EnvRedisServerDialer {
-	d := EnvRedisServerDialer{KeepAlive: 5, Timeout: 1}
+	d := EnvRedisServerDialer{}
+	_ = config.ApplyDefaults(&d)
 	return &d
 }
 
@@ -77,20 +126,33 @@ func (e *EnvRedisServerWorker) Validate() error {
 	return validate.Struct(e)
 }
 
+// GetWorkerDefault returns the worker configuration made of the `default` tags.
 func (e *EnvRedisServer) GetWorkerDefault() *EnvRedisServerWorker {
-	d := EnvRedisServerWorker{Interval: 1000}
+	d := EnvRedisServerWorker{}
+	_ = config.ApplyDefaults(&d)
 	return &d
 }
 
 type EnvRedisServer struct {
-	Host     string                `yaml:"Host,omitempty" default:"localhost"`
-	Port     uint16                `yaml:"Port,omitempty" default:"6379"`
-	Username string                `yaml:"Username,omitempty" default:""`
-	Password string                `yaml:"Password,omitempty" default:""`
+	Host     string `yaml:"Host,omitempty" default:"localhost"`
+	Port     uint16 `yaml:"Port,omitempty" default:"6379"`
+	Username string `yaml:"Username,omitempty" default:""`
+	// Password may be empty in production, for a server without authentication.
+	Password string                `yaml:"Password,omitempty" default:"" log:"secret,allowempty"`
 	DB       int                   `yaml:"DB,omitempty" default:"0" validate:"min=0,max=15"`
 	Weight   uint8                 `yaml:"Weight,omitempty" default:"1" validate:"min=1,max=10"`
-	Dialer   *EnvRedisServerDialer `yaml:"Dialer,omitempty"`
-	Worker   *EnvRedisServerWorker `yaml:"Worker,omitempty"`
+	Dialer   *EnvRedisServerDialer `yaml:"Dialer,omitempty" default:"{}"`
+	Worker   *EnvRedisServerWorker `yaml:"Worker,omitempty" default:"{}"`
+}
+
+// String hides the password.
+func (e EnvRedisServer) String() string {
+	return redact.String(e)
+}
+
+// LogValue hides the password.
+func (e EnvRedisServer) LogValue() slog.Value {
+	return redact.Value(e)
 }
 
 func (e *EnvRedisServer) Validate() error {
@@ -121,6 +183,8 @@ func (e *EnvRedisServer) GetRedisOptions() *redis.Options {
 		Username: e.Username,
 		Password: e.Password,
 		DB:       e.DB,
+		// The deadlines of the contexts bound the commands, e.g. the pings of a metrics scrape, within the timeouts.
+		ContextTimeoutEnabled: true,
 		Dialer: func(ctx context.Context, network, address string) (net.Conn, error) {
 			config := e.Dialer
 			if config == nil {
@@ -130,23 +194,134 @@ func (e *EnvRedisServer) GetRedisOptions() *redis.Options {
 				Timeout:   time.Duration(config.Timeout) * time.Second,
 				KeepAlive: time.Duration(config.KeepAlive) * time.Minute,
 			}
-			return netDialer.Dial(network, address)
+			return netDialer.DialContext(ctx, network, address)
 		},
 	}
 	return &options
 }
 
 func (c *ClientPool) InitRedisClientPool(servers *[]EnvRedisServer) {
-	redisClients := make([]*redis.Client, len(*servers))
-	turnMap := make([]uint8, 0)
-	for i, v := range *servers {
-		redisClients[i] = redis.NewClient(v.GetRedisOptions())
-		for j := 0; j < int(v.Weight); j++ {
-			turnMap = append(turnMap, uint8(i))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Cloned as by Reconfigure, so that the pool does not share the slice of the configuration.
+	set, _ := c.newClientSet(slices.Clone(*servers), nil)
+	c.set.Store(set)
+}
+
+// Reconfigure replaces the servers of the pool at once, e.g. on configuration reload. The clients of the servers whose
+// connection settings are unchanged are kept, along with their connections, whatever their weight. The clients of the
+// removed servers are closed after RetireDelay, unless a later call reuses them, e.g. to revert the configuration. If
+// a server is invalid, the pool is left untouched.
+func (c *ClientPool) Reconfigure(servers []EnvRedisServer) error {
+	servers = slices.Clone(servers)
+	for i := range servers {
+		if err := servers[i].Validate(); err != nil {
+			return fmt.Errorf("redis server %d: %w", i, err)
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous := c.set.Load()
+	candidates := make([]candidate, 0, len(c.retired))
+	for i := 0; previous != nil && i < len(previous.clients); i++ {
+		candidates = append(candidates, candidate{server: previous.servers[i], client: previous.clients[i]})
+	}
+	for _, r := range c.retired {
+		candidates = append(candidates, r.candidate)
+	}
+	set, reused := c.newClientSet(servers, candidates)
+	c.set.Store(set)
+	// A timer already fired waits for mu, then no longer finds its client.
+	c.retired = slices.DeleteFunc(c.retired, func(r *retiredClient) bool {
+		if reused[r.client] {
+			r.timer.Stop()
+			return true
+		}
+		return false
+	})
+	for i := 0; previous != nil && i < len(previous.clients); i++ {
+		if !reused[previous.clients[i]] {
+			c.retire(candidate{server: previous.servers[i], client: previous.clients[i]})
+		}
+	}
+	return nil
+}
+
+// retire closes the client after RetireDelay, unless it is reused meanwhile. It must be called with mu held.
+func (c *ClientPool) retire(removed candidate) {
+	delay := c.RetireDelay
+	if delay <= 0 {
+		delay = DefaultRetireDelay
+	}
+	r := &retiredClient{candidate: removed}
+	r.timer = time.AfterFunc(delay, func() {
+		c.mu.Lock()
+		i := slices.Index(c.retired, r)
+		if i >= 0 {
+			c.retired = slices.Delete(c.retired, i, i+1)
+		}
+		c.mu.Unlock()
+		if i >= 0 {
+			_ = r.client.Close()
+		}
+	})
+	c.retired = append(c.retired, r)
+}
+
+// newClientSet creates the clients of the servers, reusing the clients of the candidates with the same connection
+// settings, and returns the clients reused.
+func (c *ClientPool) newClientSet(servers []EnvRedisServer, candidates []candidate) (*clientSet, map[*redis.Client]bool) {
+	set := clientSet{
+		clients: make([]*redis.Client, len(servers)),
+		servers: servers,
+		turnMap: make([]uint8, 0),
+	}
+	reused := make(map[*redis.Client]bool)
+	for i := range servers {
+		for _, candidate := range candidates {
+			if !reused[candidate.client] && sameConnection(candidate.server, servers[i]) {
+				set.clients[i] = candidate.client
+				reused[candidate.client] = true
+				break
+			}
+		}
+		if set.clients[i] == nil {
+			set.clients[i] = redis.NewClient(servers[i].GetRedisOptions())
+			for _, hook := range c.hooks {
+				set.clients[i].AddHook(hook)
+			}
+		}
+		for j := 0; j < int(servers[i].Weight); j++ {
+			set.turnMap = append(set.turnMap, uint8(i))
+		}
+	}
+	return &set, reused
+}
+
+// sameConnection reports whether the servers differ at most by their weight and worker settings.
+func sameConnection(a EnvRedisServer, b EnvRedisServer) bool {
+	a.Weight, b.Weight = 0, 0
+	a.Worker, b.Worker = nil, nil
+	if a.Dialer == nil {
+		a.Dialer = a.GetDialerDefault()
+	}
+	if b.Dialer == nil {
+		b.Dialer = b.GetDialerDefault()
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// AddHook adds the hook, e.g. tracing.RedisHook, to every client of the pool, including the clients created by later
+// calls to InitRedisClientPool and Reconfigure.
+func (c *ClientPool) AddHook(hook redis.Hook) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hooks = append(c.hooks, hook)
+	if set := c.set.Load(); set != nil {
+		for _, client := range set.clients {
+			client.AddHook(hook)
 		}
 	}
-	c.clients = &redisClients
-	c.turnMap = turnMap
 }
 
 type ServerStatus struct {
@@ -155,24 +330,34 @@ type ServerStatus struct {
 }
 
 func (c *ClientPool) GetClient(idx *uint8) *redis.Client {
-	if c == nil || c.clients == nil {
+	set := c.load()
+	if set == nil {
 		panic(ErrRedisClientNil)
 	}
 	index := uint8(0)
 	if idx != nil {
 		index = *idx
 	}
-	if int(index) >= len(*c.clients) || (*c.clients)[index] == nil {
+	return set.client(index)
+}
+
+func (s *clientSet) client(index uint8) *redis.Client {
+	if int(index) >= len(s.clients) || s.clients[index] == nil {
 		panic(ErrRedisClientNil)
 	}
-	return (*c.clients)[index]
+	return s.clients[index]
 }
 
 func (c *ClientPool) GetRedisServerStatus(ctx context.Context, idx uint8) *ServerStatus {
-	client := c.GetClient(&idx)
-	if client == nil {
+	set := c.load()
+	if set == nil {
 		panic(ErrRedisClientNil)
 	}
+	return set.status(ctx, idx)
+}
+
+func (s *clientSet) status(ctx context.Context, idx uint8) *ServerStatus {
+	client := s.client(idx)
 	poolStats := client.PoolStats()
 	status := ServerStatus{
 		Valid: false,
@@ -187,15 +372,78 @@ func (c *ClientPool) GetRedisServerStatus(ctx context.Context, idx uint8) *Serve
 	return &status
 }
 
+// Len returns the number of clients in the pool.
+func (c *ClientPool) Len() int {
+	set := c.load()
+	if set == nil {
+		return 0
+	}
+	return len(set.clients)
+}
+
+// GetRedisServerPoolStats returns the connection pool statistics of the client at idx.
+func (c *ClientPool) GetRedisServerPoolStats(idx uint8) *redis.PoolStats {
+	return c.GetClient(&idx).PoolStats()
+}
+
 func (c *ClientPool) GetRedisServersStatus(ctx context.Context) map[uint8]ServerStatus {
 	result := make(map[uint8]ServerStatus)
-	for i := 0; c != nil && c.clients != nil && i < len(*c.clients); i++ {
-		status := c.GetRedisServerStatus(ctx, uint8(i))
+	set := c.load()
+	for i := 0; set != nil && i < len(set.clients); i++ {
+		status := set.status(ctx, uint8(i))
 		result[uint8(i)] = *status
 	}
 	return result
 }
 
+// ServerSnapshot is the state of a server of the pool, see ClientPool.Snapshot.
+type ServerSnapshot struct {
+	Addr  string
+	Stats *redis.PoolStats
+	ServerStatus
+}
+
+// Snapshot pings every server of the pool and returns their addresses, pool statistics and statuses in order. They all
+// come from the same configuration, even if the pool is reconfigured meanwhile.
+func (c *ClientPool) Snapshot(ctx context.Context) []ServerSnapshot {
+	set := c.load()
+	if set == nil {
+		return nil
+	}
+	result := make([]ServerSnapshot, len(set.clients))
+	for i, client := range set.clients {
+		result[i] = ServerSnapshot{
+			Addr:         client.Options().Addr,
+			Stats:        client.PoolStats(),
+			ServerStatus: *set.status(ctx, uint8(i)),
+		}
+	}
+	return result
+}
+
+// Close closes every client of the pool, the clients retired by Reconfigure included.
+func (c *ClientPool) Close() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	retired := c.retired
+	c.retired = nil
+	c.mu.Unlock()
+	errs := make([]error, 0, len(retired))
+	for _, r := range retired {
+		r.timer.Stop()
+		errs = append(errs, r.client.Close())
+	}
+	set := c.load()
+	for i := 0; set != nil && i < len(set.clients); i++ {
+		if set.clients[i] != nil {
+			errs = append(errs, set.clients[i].Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func (c *ClientPool) FunctionLoadReplace(ctx context.Context, idx *uint8, code string) *redis.StringCmd {
 	client := c.GetClient(idx)
 	return client.FunctionLoadReplace(ctx, code)
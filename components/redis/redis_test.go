@@ -2,7 +2,12 @@ package redis
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -15,3 +20,84 @@ func TestRedisClientPool_GetRedisServersStatus(t *testing.T) {
 		assert.Len(t, result, 0)
 	})
 }
+
+func TestRedisClientPool_Len(t *testing.T) {
+	t.Run("nil ClientPool", func(t *testing.T) {
+		var pool *ClientPool
+		assert.Equal(t, 0, pool.Len())
+	})
+	t.Run("initialized ClientPool", func(t *testing.T) {
+		pool := ClientPool{}
+		pool.InitRedisClientPool(&[]EnvRedisServer{{Host: "127.0.0.1", Port: 1, Weight: 1}, {Host: "127.0.0.1", Port: 2, Weight: 2}})
+		assert.Equal(t, 2, pool.Len())
+		assert.NotNil(t, pool.GetRedisServerPoolStats(1))
+	})
+}
+
+func TestClientPool_Snapshot(t *testing.T) {
+	var empty *ClientPool
+	assert.Empty(t, empty.Snapshot(context.Background()))
+	pool := ClientPool{}
+	pool.InitRedisClientPool(&[]EnvRedisServer{{Host: "127.0.0.1", Port: 1, Weight: 1}, {Host: "127.0.0.1", Port: 2, Weight: 2}})
+	snapshot := pool.Snapshot(context.Background())
+	if assert.Len(t, snapshot, 2) {
+		assert.Equal(t, "127.0.0.1:2", snapshot[1].Addr)
+		assert.NotNil(t, snapshot[1].Stats)
+		assert.False(t, snapshot[1].Valid)
+		assert.NotEmpty(t, snapshot[1].Message)
+	}
+}
+
+func TestEnvRedisServer_String(t *testing.T) {
+	e := EnvRedisServer{Host: "localhost", Port: 6379, Password: "secret-password", Weight: 1}
+	assert.NotContains(t, e.String(), "secret-password")
+	assert.NotContains(t, fmt.Sprintf("%+v", &e), "secret-password")
+	assert.Contains(t, e.String(), "Host:localhost")
+}
+
+func TestClientPool_Close(t *testing.T) {
+	var empty *ClientPool
+	assert.NoError(t, empty.Close())
+	pool := ClientPool{}
+	pool.InitRedisClientPool(&[]EnvRedisServer{{Host: "127.0.0.1", Port: 1, Weight: 1}})
+	assert.NoError(t, pool.Close())
+	assert.Error(t, pool.GetClient(nil).Ping(context.Background()).Err())
+}
+
+func TestClientPool_Reconfigure(t *testing.T) {
+	pool := ClientPool{}
+	pool.InitRedisClientPool(&[]EnvRedisServer{{Host: "127.0.0.1", Port: 1, Weight: 1}, {Host: "127.0.0.1", Port: 2, Weight: 1}})
+	kept := pool.load().clients[0]
+	removed := pool.load().clients[1]
+
+	t.Run("invalid server", func(t *testing.T) {
+		assert.Error(t, pool.Reconfigure([]EnvRedisServer{{Host: "127.0.0.1", Port: 1, Weight: 0}}))
+		assert.Equal(t, 2, pool.Len())
+	})
+	t.Run("replace servers", func(t *testing.T) {
+		assert.NoError(t, pool.Reconfigure([]EnvRedisServer{{Host: "127.0.0.1", Port: 3, Weight: 1}, {Host: "127.0.0.1", Port: 1, Weight: 3}}))
+		assert.Equal(t, 2, pool.Len())
+		assert.Same(t, kept, pool.load().clients[1])
+		assert.NotSame(t, removed, pool.load().clients[0])
+		assert.Equal(t, []uint8{0, 1, 1, 1}, pool.load().turnMap)
+		// The removed client stays open for the requests using it.
+		assert.NotErrorIs(t, removed.Ping(context.Background()).Err(), redis.ErrClosed)
+	})
+	t.Run("revert", func(t *testing.T) {
+		assert.NoError(t, pool.Reconfigure([]EnvRedisServer{{Host: "127.0.0.1", Port: 1, Weight: 1}, {Host: "127.0.0.1", Port: 2, Weight: 1}}))
+		assert.Same(t, kept, pool.load().clients[0])
+		assert.Same(t, removed, pool.load().clients[1])
+		assert.Len(t, pool.retired, 1)
+	})
+	t.Run("retire delay", func(t *testing.T) {
+		pool.RetireDelay = 10 * time.Millisecond
+		assert.NoError(t, pool.Reconfigure([]EnvRedisServer{{Host: "127.0.0.1", Port: 1, Weight: 1}}))
+		assert.Eventually(t, func() bool {
+			return errors.Is(removed.Ping(context.Background()).Err(), redis.ErrClosed)
+		}, time.Second, 10*time.Millisecond)
+	})
+	retired := pool.retired[0].client
+	assert.NoError(t, pool.Close())
+	assert.ErrorIs(t, retired.Ping(context.Background()).Err(), redis.ErrClosed)
+	assert.Empty(t, pool.retired)
+}
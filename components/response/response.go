@@ -9,10 +9,28 @@ import (
 	"github.com/rhosocial/go-rush-common/components/logger"
 )
 
+// ContextCode is the key under which NewBase and NewGeneric store the code of the response, e.g. for audit logs.
+const ContextCode = "ResponseCode"
+
+// GetCode returns the code of the response built for the context, if any.
+func GetCode(c *gin.Context) (uint32, bool) {
+	if c == nil {
+		return 0, false
+	}
+	code, ok := c.Value(ContextCode).(uint32)
+	return code, ok
+}
+
+func setCode(c *gin.Context, code uint32) {
+	if c != nil {
+		c.Set(ContextCode, code)
+	}
+}
+
 type Base struct {
-	RequestID uint32 `json:"request_id"`
-	Code      uint32 `json:"code"`
-	Message   string `json:"message"`
+	RequestID logger.RequestID `json:"request_id"`
+	Code      uint32           `json:"code"`
+	Message   string           `json:"message"`
 }
 
 type DataAndExtension[T1 interface{}, T2 interface{}] struct {
@@ -56,8 +74,9 @@ func UnmarshalResponseBodyBaseWithDataAndExtension[T1 interface{}, T2 interface{
 }
 
 func NewBase(c *gin.Context, code uint32, message string) *Base {
+	setCode(c, code)
 	r := Base{
-		RequestID: c.Value(logger.ContextRequestID).(uint32),
+		RequestID: logger.GetRequestID(c),
 		Code:      code,
 		Message:   message,
 	}
@@ -65,9 +84,10 @@ func NewBase(c *gin.Context, code uint32, message string) *Base {
 }
 
 func NewGeneric[T1 interface{}, T2 interface{}](c *gin.Context, code uint32, message string, data T1, extension T2) *Generic[T1, T2] {
+	setCode(c, code)
 	r := Generic[T1, T2]{
 		Base{
-			RequestID: c.Value(logger.ContextRequestID).(uint32),
+			RequestID: logger.GetRequestID(c),
 			Code:      code,
 			Message:   message,
 		},
@@ -9,6 +9,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gin-gonic/gin"
+	"github.com/rhosocial/go-rush-common/components/logger"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -24,8 +26,8 @@ func teardownResponseNewServer(t *testing.T) {
 
 func TestUnmarshalResponseBody(t *testing.T) {
 	t.Run("Normal Case", func(t *testing.T) {
-		requestID := uint32(time.Now().Unix())
-		code := requestID + 1
+		requestID := logger.NewRequestID()
+		code := uint32(time.Now().Unix())
 		message := fmt.Sprintf("message_%d", code+1)
 		setupResponseNewServer(t, func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
@@ -72,8 +74,8 @@ func TestUnmarshalResponseBody(t *testing.T) {
 		assert.Equal(t, message, body.Message)
 	})
 	t.Run("Bad Case", func(t *testing.T) {
-		requestID := uint32(time.Now().Unix())
-		code := requestID + 1
+		requestID := logger.NewRequestID()
+		code := uint32(time.Now().Unix())
 		message := fmt.Sprintf("message_%d", code+1)
 		setupResponseNewServer(t, func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
@@ -127,8 +129,8 @@ type NestedStructWithScalarAndStruct struct {
 
 func TestUnmarshalResponseDataExtensionBody(t *testing.T) {
 	t.Run("Normal Case 1: Data with scalar and Extension with any nil", func(t *testing.T) {
-		requestID := uint32(time.Now().Unix())
-		code := requestID + 1
+		requestID := logger.NewRequestID()
+		code := uint32(time.Now().Unix())
 		message := fmt.Sprintf("message_%d", code+1)
 		data := code + 2
 		setupResponseNewServer(t, func(w http.ResponseWriter, r *http.Request) {
@@ -184,8 +186,8 @@ func TestUnmarshalResponseDataExtensionBody(t *testing.T) {
 		assert.Nil(t, body.Extension)
 	})
 	t.Run("Normal Case 2: Data with struct and Extension with any nil", func(t *testing.T) {
-		requestID := uint32(time.Now().Unix())
-		code := requestID + 1
+		requestID := logger.NewRequestID()
+		code := uint32(time.Now().Unix())
 		message := fmt.Sprintf("message_%d", code+1)
 		data := StructWithScalar{
 			1, "2", true,
@@ -243,8 +245,8 @@ func TestUnmarshalResponseDataExtensionBody(t *testing.T) {
 		assert.Nil(t, body.Extension)
 	})
 	t.Run("Normal Case 3: Data with nested struct and Extension with any nil", func(t *testing.T) {
-		requestID := uint32(time.Now().Unix())
-		code := requestID + 1
+		requestID := logger.NewRequestID()
+		code := uint32(time.Now().Unix())
 		message := fmt.Sprintf("message_%d", code+1)
 		data := NestedStructWithScalarAndStruct{
 			StructWithScalar{
@@ -304,3 +306,22 @@ func TestUnmarshalResponseDataExtensionBody(t *testing.T) {
 		assert.Nil(t, body.Extension)
 	})
 }
+
+func TestGetCode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	_, ok := GetCode(c)
+	assert.False(t, ok)
+
+	NewBase(c, 1001, "failed")
+	code, ok := GetCode(c)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(1001), code)
+
+	NewGeneric[any, any](c, 0, "ok", nil, nil)
+	code, _ = GetCode(c)
+	assert.Equal(t, uint32(0), code)
+
+	_, ok = GetCode(nil)
+	assert.False(t, ok)
+}
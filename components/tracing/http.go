@@ -0,0 +1,66 @@
+package tracing
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Transport is an http.RoundTripper that creates a client span around each outbound request and propagates the
+// trace context in its headers.
+type Transport struct {
+	// Base performs the requests. Nil means http.DefaultTransport.
+	Base http.RoundTripper
+	// TracerProvider creates the spans. Nil means the global provider.
+	TracerProvider trace.TracerProvider
+	// Propagator injects the trace context. Nil means the global propagator.
+	Propagator propagation.TextMapPropagator
+}
+
+// NewTransport wraps base with tracing.
+func NewTransport(base http.RoundTripper) *Transport {
+	return &Transport{Base: base}
+}
+
+// NewHTTPClient returns an http.Client whose requests are traced. Pass ContextOf(c) as the request context.
+func NewHTTPClient() *http.Client {
+	return &http.Client{Transport: NewTransport(nil)}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	propagator := t.Propagator
+	if propagator == nil {
+		propagator = otel.GetTextMapPropagator()
+	}
+	ctx, span := tracer(t.TracerProvider).Start(ContextOf(req.Context()), req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLFull(req.URL.Redacted()),
+			semconv.ServerAddress(req.URL.Hostname()),
+		),
+	)
+	// RoundTrippers must not modify the request, so the headers are injected into a clone.
+	req = req.Clone(ctx)
+	propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		End(span, err)
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+	span.End()
+	return resp, nil
+}
@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rhosocial/go-rush-common/components/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AttrRequestID is the span attribute carrying the request ID set by logger.AppendRequestID.
+const AttrRequestID = attribute.Key("request.id")
+
+// Config defines the behavior of the tracing middleware.
+type Config struct {
+	// TracerProvider creates the server spans. Nil means the global provider.
+	TracerProvider trace.TracerProvider
+	// Propagator extracts the incoming trace context. Nil means the global propagator.
+	Propagator propagation.TextMapPropagator
+	// Skip excludes requests, e.g. health checks, from tracing.
+	Skip func(c *gin.Context) bool
+}
+
+// Middleware returns a tracing middleware using the global provider and propagator.
+func Middleware() gin.HandlerFunc {
+	return MiddlewareWithConfig(Config{})
+}
+
+// MiddlewareWithConfig returns a middleware that continues the trace described by the traceparent/tracestate headers
+// (or starts a new one) with a server span named after the route template, e.g. `GET /users/:id`.
+//
+// The span is stored in the context of c.Request; use ContextOf(c) to get a context carrying it.
+func MiddlewareWithConfig(config Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if config.Skip != nil && config.Skip(c) {
+			c.Next()
+			return
+		}
+		propagator := config.Propagator
+		if propagator == nil {
+			propagator = otel.GetTextMapPropagator()
+		}
+		ctx := propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		route := c.FullPath()
+		name := c.Request.Method
+		if route != "" {
+			name += " " + route
+		}
+		attrs := []attribute.KeyValue{
+			semconv.HTTPRequestMethodKey.String(c.Request.Method),
+			semconv.URLPath(c.Request.URL.Path),
+			semconv.ClientAddress(c.ClientIP()),
+			semconv.UserAgentOriginal(c.Request.UserAgent()),
+		}
+		if route != "" {
+			attrs = append(attrs, semconv.HTTPRoute(route))
+		}
+		if id := logger.GetRequestID(c); id != "" {
+			attrs = append(attrs, AttrRequestID.String(id.String()))
+		}
+		ctx, span := tracer(config.TracerProvider).Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attrs...),
+		)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		for _, err := range c.Errors {
+			span.RecordError(err.Err)
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
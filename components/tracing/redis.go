@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AttrRedisPipelineLength is the span attribute carrying the number of commands in a pipeline.
+const AttrRedisPipelineLength = attribute.Key("db.redis.pipeline_length")
+
+// RedisHook creates a client span around every Redis command, pipeline and dial.
+// Add it to a pool with redis.ClientPool.AddHook.
+type RedisHook struct {
+	// TracerProvider creates the spans. Nil means the global provider.
+	TracerProvider trace.TracerProvider
+}
+
+var _ redis.Hook = (*RedisHook)(nil)
+
+// NewRedisHook returns a RedisHook using the global provider.
+func NewRedisHook() *RedisHook {
+	return &RedisHook{}
+}
+
+func (h *RedisHook) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, semconv.DBSystemNameRedis)
+	return tracer(h.TracerProvider).Start(ContextOf(ctx), name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+func (h *RedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, span := h.start(ctx, "redis.dial", semconv.ServerAddress(addr))
+		conn, err := next(ctx, network, addr)
+		End(span, err)
+		return conn, err
+	}
+}
+
+func (h *RedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		operation := strings.ToUpper(cmd.Name())
+		ctx, span := h.start(ctx, operation, semconv.DBOperationName(operation))
+		err := next(ctx, cmd)
+		End(span, ignoreNil(err))
+		return err
+	}
+}
+
+func (h *RedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, span := h.start(ctx, "PIPELINE",
+			semconv.DBOperationName("PIPELINE"),
+			AttrRedisPipelineLength.Int(len(cmds)),
+		)
+		err := next(ctx, cmds)
+		End(span, ignoreNil(err))
+		return err
+	}
+}
+
+// ignoreNil does not report a missing key as a failure.
+func ignoreNil(err error) error {
+	if err == redis.Nil {
+		return nil
+	}
+	return err
+}
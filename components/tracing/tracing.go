@@ -0,0 +1,157 @@
+// Package tracing provides distributed tracing with W3C Trace Context propagation, built on OpenTelemetry.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the instrumentation scope of the spans created by this package.
+const TracerName = "github.com/rhosocial/go-rush-common/components/tracing"
+
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterNone   = "none"
+)
+
+// EnvTracing defines the tracer provider.
+type EnvTracing struct {
+	ServiceName string `yaml:"ServiceName" default:"go-rush" validate:"required"`
+	Exporter    string `yaml:"Exporter,omitempty" default:"otlp" validate:"omitempty,oneof=otlp stdout none"`
+	// Endpoint is the host:port of the OTLP/HTTP collector. Empty means the OTEL_EXPORTER_OTLP_* environment variables.
+	Endpoint string `yaml:"Endpoint,omitempty" default:""`
+	Insecure bool   `yaml:"Insecure,omitempty" default:"false"`
+	// SampleRatio is the fraction of root traces sampled. Zero means 1, i.e. all of them.
+	SampleRatio float64 `yaml:"SampleRatio,omitempty" default:"1" validate:"min=0,max=1"`
+}
+
+func (e *EnvTracing) Validate() error {
+	validate := validator.New()
+	return validate.Struct(e)
+}
+
+// NewTracerProvider creates a tracer provider exporting spans as configured.
+// The provider must be shut down to flush pending spans.
+func NewTracerProvider(ctx context.Context, e *EnvTracing) (*sdktrace.TracerProvider, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	options := make([]sdktrace.TracerProviderOption, 0, 3)
+	switch e.Exporter {
+	case "", ExporterOTLP:
+		clientOptions := make([]otlptracehttp.Option, 0, 2)
+		if e.Endpoint != "" {
+			clientOptions = append(clientOptions, otlptracehttp.WithEndpoint(e.Endpoint))
+		}
+		if e.Insecure {
+			clientOptions = append(clientOptions, otlptracehttp.WithInsecure())
+		}
+		exporter, err := otlptracehttp.New(ctx, clientOptions...)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, sdktrace.WithBatcher(exporter))
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, sdktrace.WithSyncer(exporter))
+	case ExporterNone:
+	default:
+		return nil, fmt.Errorf("invalid trace exporter %q", e.Exporter)
+	}
+	ratio := e.SampleRatio
+	if ratio == 0 {
+		ratio = 1
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(e.ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+	options = append(options,
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(res),
+	)
+	return sdktrace.NewTracerProvider(options...), nil
+}
+
+// Setup creates the tracer provider and installs it, along with the W3C Trace Context and Baggage propagators, as
+// the global OpenTelemetry provider. The returned function shuts the provider down.
+func Setup(ctx context.Context, e *EnvTracing) (func(context.Context) error, error) {
+	provider, err := NewTracerProvider(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(NewPropagator())
+	return provider.Shutdown, nil
+}
+
+// NewPropagator returns the W3C Trace Context (traceparent/tracestate) and Baggage propagator.
+func NewPropagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+}
+
+// NewInMemoryTracerProvider creates a tracer provider recording spans in memory, for tests without a collector.
+func NewInMemoryTracerProvider() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter), sdktrace.WithSampler(sdktrace.AlwaysSample()))
+	return provider, exporter
+}
+
+func tracer(provider trace.TracerProvider) trace.Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return provider.Tracer(TracerName)
+}
+
+// ContextOf returns the context carrying the current span.
+//
+// The server span is stored in the context of the *http.Request, which a *gin.Context does not look up unless
+// gin.Engine.ContextWithFallback is enabled, so a *gin.Context (or a context derived from it) is resolved to its
+// request context.
+func ContextOf(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	if trace.SpanFromContext(ctx).SpanContext().IsValid() {
+		return ctx
+	}
+	if c, ok := ctx.Value(gin.ContextKey).(*gin.Context); ok && c != nil && c.Request != nil {
+		return c.Request.Context()
+	}
+	return ctx
+}
+
+// StartClientSpan starts a client span, e.g. around a database call, as a child of the span carried by ctx.
+// The returned span must be finished with End.
+func StartClientSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer(nil).Start(ContextOf(ctx), name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// End records err, if any, on the span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,153 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rhosocial/go-rush-common/components/logger"
+	"github.com/rhosocial/go-rush-common/components/redis"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const incomingTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func setupTracing(t *testing.T) *tracetest.InMemoryExporter {
+	provider, exporter := NewInMemoryTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(NewPropagator())
+	t.Cleanup(func() {
+		_ = provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+func findSpan(spans tracetest.SpanStubs, name string) *tracetest.SpanStub {
+	for i := range spans {
+		if spans[i].Name == name {
+			return &spans[i]
+		}
+	}
+	return nil
+}
+
+func hasAttribute(span *tracetest.SpanStub, kv attribute.KeyValue) bool {
+	for _, attr := range span.Attributes {
+		if attr == kv {
+			return true
+		}
+	}
+	return false
+}
+
+func TestMiddleware(t *testing.T) {
+	exporter := setupTracing(t)
+	gin.SetMode(gin.TestMode)
+
+	var outbound http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outbound = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	r := gin.New()
+	r.Use(logger.AppendRequestID(), Middleware())
+	r.GET("/users/:id", func(c *gin.Context) {
+		req, _ := http.NewRequestWithContext(ContextOf(c), http.MethodGet, upstream.URL, nil)
+		resp, err := NewHTTPClient().Do(req)
+		assert.NoError(t, err)
+		_ = resp.Body.Close()
+		c.Status(http.StatusOK)
+	})
+	r.GET("/fail", func(c *gin.Context) {
+		c.Status(http.StatusInternalServerError)
+	})
+
+	t.Run("continues the incoming trace and propagates it downstream", func(t *testing.T) {
+		exporter.Reset()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/users/1", nil)
+		req.Header.Set("traceparent", incomingTraceparent)
+		r.ServeHTTP(w, req)
+
+		spans := exporter.GetSpans()
+		server := findSpan(spans, "GET /users/:id")
+		if assert.NotNil(t, server) {
+			assert.Equal(t, trace.SpanKindServer, server.SpanKind)
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID().String())
+			assert.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID().String())
+			assert.True(t, hasAttribute(server, attribute.String("http.route", "/users/:id")))
+			assert.True(t, hasAttribute(server, AttrRequestID.String(w.Header().Get(logger.HeaderXRequestID))))
+		}
+		client := findSpan(spans, http.MethodGet)
+		if assert.NotNil(t, client) && assert.NotNil(t, server) {
+			assert.Equal(t, trace.SpanKindClient, client.SpanKind)
+			assert.Equal(t, server.SpanContext.SpanID(), client.Parent.SpanID())
+			assert.Contains(t, outbound.Get("traceparent"), client.SpanContext.SpanID().String())
+		}
+	})
+
+	t.Run("server errors mark the span", func(t *testing.T) {
+		exporter.Reset()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/fail", nil)
+		r.ServeHTTP(w, req)
+
+		server := findSpan(exporter.GetSpans(), "GET /fail")
+		if assert.NotNil(t, server) {
+			assert.Equal(t, codes.Error, server.Status.Code)
+			// A new trace is started without an incoming traceparent.
+			assert.False(t, server.Parent.IsValid())
+		}
+	})
+}
+
+func TestRedisHook(t *testing.T) {
+	exporter := setupTracing(t)
+
+	pool := redis.ClientPool{}
+	pool.AddHook(NewRedisHook())
+	pool.InitRedisClientPool(&[]redis.EnvRedisServer{{Host: "127.0.0.1", Port: 1, Weight: 1}})
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	err := pool.GetClient(nil).Get(ctx, "key").Err()
+	parent.End()
+	assert.Error(t, err)
+
+	spans := exporter.GetSpans()
+	get := findSpan(spans, "GET")
+	if assert.NotNil(t, get) {
+		assert.Equal(t, trace.SpanKindClient, get.SpanKind)
+		assert.Equal(t, codes.Error, get.Status.Code)
+		assert.Equal(t, parent.SpanContext().SpanID(), get.Parent.SpanID())
+		assert.True(t, hasAttribute(get, attribute.String("db.system.name", "redis")))
+	}
+	assert.NotNil(t, findSpan(spans, "redis.dial"))
+}
+
+func TestNewTracerProvider(t *testing.T) {
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewTracerProvider(context.Background(), &EnvTracing{})
+		assert.Error(t, err)
+		_, err = NewTracerProvider(context.Background(), &EnvTracing{ServiceName: "test", Exporter: "jaeger"})
+		assert.Error(t, err)
+	})
+
+	for _, exporter := range []string{ExporterOTLP, ExporterStdout, ExporterNone} {
+		t.Run(exporter, func(t *testing.T) {
+			provider, err := NewTracerProvider(context.Background(), &EnvTracing{ServiceName: "test", Exporter: exporter})
+			assert.NoError(t, err)
+			assert.IsType(t, &sdktrace.TracerProvider{}, provider)
+			assert.NoError(t, provider.Shutdown(context.Background()))
+		})
+	}
+}
@@ -93,3 +93,15 @@ func (p *Pool) GetActivity(id uint64) (*Activity, error) {
 	}
 	return nil, ErrActivityNonExists
 }
+
+// Len returns the number of activities in the pool.
+func (p *Pool) Len() uint32 {
+	p.activitiesRWMutex.RLock()
+	defer p.activitiesRWMutex.RUnlock()
+	return uint32(len(p.activities))
+}
+
+// Limit returns the upper limit of the capacity.
+func (p *Pool) Limit() uint32 {
+	return p.activitiesLimit
+}
@@ -166,3 +166,19 @@ func TestPool_NewActivity2(t *testing.T) {
 		assert.Len(t, poolDefault.activities, 1)
 	})
 }
+
+func TestPool_Len(t *testing.T) {
+	setupPool(t, 2)
+	defer teardownPool(t)
+
+	assert.Equal(t, uint32(0), poolDefault.Len())
+	assert.Equal(t, uint32(2), poolDefault.Limit())
+	poolDefault.activitiesIDGenerator = func() uint64 {
+		return 1
+	}
+	_, err := poolDefault.NewActivity("test-activity-8", false)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1), poolDefault.Len())
+	assert.Nil(t, poolDefault.RemoveActivity(1))
+	assert.Equal(t, uint32(0), poolDefault.Len())
+}